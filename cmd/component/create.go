@@ -288,4 +288,6 @@ func main() {
 	}
 	fmt.Printf("   )\n")
 	fmt.Printf("   ```\n")
+	fmt.Printf("\nOr generate the registration and route code with:\n")
+	fmt.Printf("   go run ./cmd/route scaffold %s\n", config.Directory)
 }
@@ -0,0 +1,53 @@
+//go:build webrender_demos
+
+package main
+
+import (
+	"html/template"
+	"log"
+
+	"github.com/magooney-loon/webrender/pkg"
+	"github.com/magooney-loon/webrender/pkg/components/example"
+	"github.com/magooney-loon/webrender/pkg/components/testcomponent"
+)
+
+// registerDemos registers the example components and their routes
+func registerDemos(webRender *pkg.WebRender) {
+	// Register the counter component manually
+	counter := example.NewCounter("counter-1")
+	if err := webRender.RegisterComponent(counter); err != nil {
+		log.Printf("Error registering counter component: %v", err)
+	} else {
+		log.Println("Counter component registered successfully with ID:", counter.ID)
+	}
+
+	// Home page with counter example; the component brings its own assets
+	webRender.ComponentRouteAuto("/", "WebRender Example", "counter-1",
+		map[string]interface{}{"title": "Click Counter"},
+	)
+
+	// Alternative using RouteWithTemplate for more control
+	webRender.RouteWithTemplate("/alt", "Alternative Example", func() (template.HTML, error) {
+		// Render the counter component
+		counterHTML, err := webRender.RenderComponent("counter-1", map[string]interface{}{"title": "Custom Counter"})
+		if err != nil {
+			return "", err
+		}
+		return template.HTML(counterHTML), nil
+	},
+		func() template.CSS { return template.CSS(example.GetStyles()) },
+		func() template.JS { return template.JS(example.GetScripts()) })
+
+	// Create and register the component first
+	testcomponentComp := testcomponent.NewTestComponent("testcomponent-id")
+	if err := webRender.RegisterComponent(testcomponentComp); err != nil {
+		log.Printf("Error registering testcomponent component: %v", err)
+	}
+
+	webRender.ComponentRouteAuto("/testcomponent", "TestComponent Example", "testcomponent-id",
+		map[string]interface{}{
+			"title":       "TestComponent Component",
+			"description": "A custom TestComponent component",
+		},
+	)
+}
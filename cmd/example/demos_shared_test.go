@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/magooney-loon/webrender/pkg"
+	"github.com/magooney-loon/webrender/pkg/logger"
+	"github.com/magooney-loon/webrender/pkg/router"
+)
+
+// newTestWebRender returns a quiet instance without the admin panel or
+// component auto-registration, shut down with the test
+func newTestWebRender(t *testing.T) *pkg.WebRender {
+	t.Helper()
+
+	wr, err := pkg.New(pkg.Config{
+		StaticDir:       t.TempDir(),
+		ServeMux:        http.NewServeMux(),
+		Router:          router.New(),
+		UseBaseTemplate: true,
+		Logger:          logger.Discard(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		wr.StateManager.Close(ctx)
+	})
+	return wr
+}
+
+// demoComponentIDs are the components registerDemos adds
+var demoComponentIDs = []string{"counter-1", "testcomponent-id"}
@@ -0,0 +1,29 @@
+//go:build webrender_demos
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDemosPresentWithTag(t *testing.T) {
+	wr := newTestWebRender(t)
+	registerDemos(wr)
+
+	registry := wr.StateManager.GetComponentRegistry()
+	for _, id := range demoComponentIDs {
+		if _, exists := registry.Get(id); !exists {
+			t.Errorf("demo component %s not registered with the webrender_demos tag", id)
+		}
+	}
+
+	for _, path := range []string{"/", "/alt", "/testcomponent"} {
+		rec := httptest.NewRecorder()
+		wr.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s: status = %d, want %d", path, rec.Code, http.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !webrender_demos
+
+package main
+
+import (
+	"log"
+
+	"github.com/magooney-loon/webrender/pkg"
+)
+
+// registerDemos is a no-op without the webrender_demos build tag
+func registerDemos(webRender *pkg.WebRender) {
+	log.Println("Demo components not compiled in; build with -tags webrender_demos to include them")
+}
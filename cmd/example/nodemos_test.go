@@ -0,0 +1,27 @@
+//go:build !webrender_demos
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDemosAbsentWithoutTag(t *testing.T) {
+	wr := newTestWebRender(t)
+	registerDemos(wr)
+
+	registry := wr.StateManager.GetComponentRegistry()
+	for _, id := range demoComponentIDs {
+		if _, exists := registry.Get(id); exists {
+			t.Errorf("demo component %s registered without the webrender_demos tag", id)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	wr.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/testcomponent", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /testcomponent: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
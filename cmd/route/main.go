@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/magooney-loon/webrender/pkg/component"
+)
+
+// PackageInfo describes a component package found on disk
+type PackageInfo struct {
+	ImportPath string
+	Name       string
+	HasStyles  bool // Exports GetStyles() string
+	HasScripts bool // Exports GetScripts() string
+	Routes     []RouteInfo
+}
+
+// RouteInfo describes the route generated for one component constructor
+type RouteInfo struct {
+	Constructor string // e.g. NewCounter
+	Var         string // e.g. counterComp
+	ID          string // e.g. example-counter
+	Path        string // e.g. /counter
+	Title       string // e.g. Counter
+	Pos         token.Position
+}
+
+const routeTemplate = `// Import the package
+import "{{.ImportPath}}"
+{{range .Routes}}
+// {{.Title}} ({{.Constructor}})
+{{.Var}} := {{$.Name}}.{{.Constructor}}("{{.ID}}")
+if err := webRender.RegisterComponent({{.Var}}); err != nil {
+	log.Printf("Error registering {{.ID}} component: %v", err)
+}
+
+webRender.ComponentRoute("{{.Path}}", "{{.Title}}", {{.Var}}.ID, nil,
+	{{if $.HasStyles}}func() template.CSS { return template.CSS({{$.Name}}.GetStyles()) }{{else}}nil{{end}},
+	{{if $.HasScripts}}func() template.JS { return template.JS({{$.Name}}.GetScripts()) }{{else}}nil{{end}},
+)
+{{end}}`
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: go run ./cmd/route <command> [flags] <component package dir>")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  list      list the component constructors and style/script funcs")
+	fmt.Fprintln(os.Stderr, "  scaffold  print RegisterComponent + ComponentRoute code for each constructor")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Flags:")
+	fmt.Fprintln(os.Stderr, "  -tags     comma-separated build tags, e.g. webrender_demos")
+	fmt.Fprintln(os.Stderr, "  -prefix   route path prefix (scaffold only)")
+	fmt.Fprintln(os.Stderr, "  -o        append the scaffold to a file instead of printing it")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	command := os.Args[1]
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	flags.Usage = usage
+	tags := flags.String("tags", "", "comma-separated build tags")
+	prefix := flags.String("prefix", "", "route path prefix")
+	output := flags.String("o", "", "append the scaffold to this file")
+	flags.Parse(os.Args[2:])
+
+	if flags.NArg() != 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	// FindInitializers matches files against the default build context
+	if *tags != "" {
+		build.Default.BuildTags = strings.Split(*tags, ",")
+	}
+
+	info, err := inspectPackage(flags.Arg(0), *prefix)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch command {
+	case "list":
+		printList(os.Stdout, info)
+	case "scaffold":
+		if err := writeScaffold(info, *output); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+// inspectPackage finds the constructors and asset funcs of a component package
+func inspectPackage(dir, prefix string) (*PackageInfo, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	importPath, err := importPathFor(absDir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(absDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	info := &PackageInfo{ImportPath: importPath}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+
+		match, err := build.Default.MatchFile(absDir, name)
+		if err != nil || !match {
+			continue
+		}
+
+		path := filepath.Join(absDir, name)
+		if err := inspectFile(path, info); err != nil {
+			return nil, err
+		}
+
+		inits, err := component.FindInitializers(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, initializer := range inits {
+			info.Name = initializer.Package
+			info.Routes = append(info.Routes, newRouteInfo(initializer, prefix))
+		}
+	}
+
+	if len(info.Routes) == 0 {
+		return nil, fmt.Errorf("no component constructors found in %s (missing -tags?)", dir)
+	}
+
+	sort.Slice(info.Routes, func(i, j int) bool {
+		return info.Routes[i].Constructor < info.Routes[j].Constructor
+	})
+
+	return info, nil
+}
+
+// inspectFile records whether a file exports GetStyles or GetScripts
+func inspectFile(path string, info *PackageInfo) error {
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || !returnsString(fn.Type) {
+			continue
+		}
+
+		switch fn.Name.Name {
+		case "GetStyles":
+			info.HasStyles = true
+		case "GetScripts":
+			info.HasScripts = true
+		}
+	}
+
+	return nil
+}
+
+// returnsString reports whether a function type is func() string
+func returnsString(fnType *ast.FuncType) bool {
+	if len(fnType.Params.List) != 0 || fnType.Results == nil || len(fnType.Results.List) != 1 {
+		return false
+	}
+	ident, ok := fnType.Results.List[0].Type.(*ast.Ident)
+	return ok && ident.Name == "string"
+}
+
+// newRouteInfo derives names for a constructor, e.g. NewUserCard gives
+// the ID {package}-usercard at {prefix}/usercard
+func newRouteInfo(initializer component.Initializer, prefix string) RouteInfo {
+	title := strings.TrimPrefix(initializer.Name, "New")
+	if title == "" {
+		title = initializer.Package
+	}
+	lower := strings.ToLower(title)
+
+	return RouteInfo{
+		Constructor: initializer.Name,
+		Var:         strings.ToLower(title[:1]) + title[1:] + "Comp",
+		ID:          component.SanitizeID(initializer.Package + "-" + lower),
+		Path:        strings.TrimSuffix(prefix, "/") + "/" + lower,
+		Title:       title,
+		Pos:         initializer.Pos,
+	}
+}
+
+// importPathFor works out a directory's import path from the nearest go.mod
+func importPathFor(dir string) (string, error) {
+	for root := dir; ; root = filepath.Dir(root) {
+		modulePath, err := readModulePath(filepath.Join(root, "go.mod"))
+		if err == nil {
+			rel, err := filepath.Rel(root, dir)
+			if err != nil {
+				return "", fmt.Errorf("failed to resolve import path: %w", err)
+			}
+			if rel == "." {
+				return modulePath, nil
+			}
+			return modulePath + "/" + filepath.ToSlash(rel), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		if filepath.Dir(root) == root {
+			return "", fmt.Errorf("no go.mod found above %s", dir)
+		}
+	}
+}
+
+// readModulePath returns the module path declared in a go.mod file
+func readModulePath(goMod string) (string, error) {
+	file, err := os.Open(goMod)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if rest, ok := strings.CutPrefix(line, "module "); ok {
+			return strings.Trim(strings.TrimSpace(rest), `"`), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", goMod, err)
+	}
+
+	return "", fmt.Errorf("no module directive in %s", goMod)
+}
+
+// printList prints the constructors and asset funcs of a package
+func printList(w io.Writer, info *PackageInfo) {
+	fmt.Fprintf(w, "Package %s (%s)\n", info.Name, info.ImportPath)
+	fmt.Fprintf(w, "  GetStyles:  %t\n", info.HasStyles)
+	fmt.Fprintf(w, "  GetScripts: %t\n", info.HasScripts)
+	fmt.Fprintln(w, "Constructors:")
+	for _, route := range info.Routes {
+		fmt.Fprintf(w, "  %-24s %-24s %s\n", route.Constructor, route.Path, route.Pos)
+	}
+}
+
+// writeScaffold prints the route code, or appends it to output if set
+func writeScaffold(info *PackageInfo, output string) error {
+	tmpl, err := template.New("route").Parse(routeTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse route template: %w", err)
+	}
+
+	if output == "" {
+		return tmpl.Execute(os.Stdout, info)
+	}
+
+	file, err := os.OpenFile(output, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", output, err)
+	}
+	defer file.Close()
+
+	if err := tmpl.Execute(file, info); err != nil {
+		return fmt.Errorf("failed to write scaffold: %w", err)
+	}
+
+	fmt.Printf("Appended routes for %s to %s\n", info.Name, output)
+	return nil
+}
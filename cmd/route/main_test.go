@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeModule creates a module with a component package and returns the
+// package directory
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	root := t.TempDir()
+	files["go.mod"] = "module example.com/shop\n\ngo 1.21\n"
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(root, "components", "cart")
+}
+
+const cartSource = `package cart
+
+import "github.com/magooney-loon/webrender/pkg/component"
+
+func NewCart(id string) *component.Component {
+	return component.New(id, "cart", "<div></div>")
+}
+
+func NewMiniCart(id string) *component.Component {
+	return component.New(id, "minicart", "<div></div>")
+}
+`
+
+func TestScaffoldReferencesPackageAndFuncs(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"components/cart/cart.go":   cartSource,
+		"components/cart/assets.go": "package cart\n\nfunc GetStyles() string { return \"\" }\n",
+	})
+
+	info, err := inspectPackage(dir, "/shop")
+	if err != nil {
+		t.Fatalf("inspectPackage: %v", err)
+	}
+
+	output := filepath.Join(t.TempDir(), "routes.go.txt")
+	if err := writeScaffold(info, output); err != nil {
+		t.Fatalf("writeScaffold: %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snippet := string(data)
+
+	for _, want := range []string{
+		`import "example.com/shop/components/cart"`,
+		`cartComp := cart.NewCart("cart-cart")`,
+		`miniCartComp := cart.NewMiniCart("cart-minicart")`,
+		`webRender.ComponentRoute("/shop/cart", "Cart", cartComp.ID, nil,`,
+		`webRender.ComponentRoute("/shop/minicart", "MiniCart", miniCartComp.ID, nil,`,
+		`func() template.CSS { return template.CSS(cart.GetStyles()) },`,
+	} {
+		if !strings.Contains(snippet, want) {
+			t.Errorf("scaffold is missing %q:\n%s", want, snippet)
+		}
+	}
+
+	// The package has no GetScripts, so the scripts func is nil
+	if strings.Contains(snippet, "GetScripts") {
+		t.Errorf("scaffold references GetScripts, which the package doesn't export:\n%s", snippet)
+	}
+}
+
+func TestInspectPackageWithoutConstructors(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"components/cart/assets.go": "package cart\n\nfunc GetStyles() string { return \"\" }\n",
+	})
+
+	if _, err := inspectPackage(dir, ""); err == nil {
+		t.Error("inspectPackage accepted a package without constructors")
+	}
+}
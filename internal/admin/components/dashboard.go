@@ -1,6 +1,7 @@
 package components
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"strconv"
@@ -607,7 +608,8 @@ func generateRandomEvent() map[string]string {
 }
 
 // StartContinuousUpdates begins sending continuous updates to the component
-func (tp *TrafficPattern) StartContinuousUpdates(dashboard *component.Component) {
+// Updates stop when ctx is cancelled or StopUpdates is called.
+func (tp *TrafficPattern) StartContinuousUpdates(ctx context.Context, dashboard *component.Component) {
 	// Initial update
 	data := tp.GenerateTrafficData()
 	for key, value := range data {
@@ -637,8 +639,9 @@ func (tp *TrafficPattern) StartContinuousUpdates(dashboard *component.Component)
 		// Use two timers:
 		// 1. A fast timer for micro-updates (small changes to simulate real-time movement)
 		// 2. A slower timer for occasional larger changes and event generation
-		fastTicker := time.NewTicker(200 * time.Millisecond)
-		slowTicker := time.NewTicker(3 * time.Second)
+		// Jitter keeps multiple dashboards from broadcasting in lockstep
+		fastTicker := component.NewJitteredTicker(200*time.Millisecond, 0.2)
+		slowTicker := component.NewJitteredTicker(3*time.Second, 0.1)
 		defer fastTicker.Stop()
 		defer slowTicker.Stop()
 
@@ -651,6 +654,8 @@ func (tp *TrafficPattern) StartContinuousUpdates(dashboard *component.Component)
 
 		for {
 			select {
+			case <-ctx.Done():
+				return
 			case <-tp.stopChan:
 				return
 			case <-fastTicker.C:
@@ -763,19 +768,21 @@ func (tp *TrafficPattern) StartContinuousUpdates(dashboard *component.Component)
 					trafficData[len(trafficData)-1] = spikeHeight
 				}
 
-				// Update data for the UI
-				dashboard.State.Set("users", newUsers)
-				dashboard.State.Set("userTrend", userTrend)
-				dashboard.State.Set("userTrendColor", userTrendColor)
-				dashboard.State.Set("userTrendIcon", userTrendIcon)
-				dashboard.State.Set("sessions", newSessions)
-				dashboard.State.Set("sessionTrend", sessionTrend)
-				dashboard.State.Set("sessionTrendColor", sessionTrendColor)
-				dashboard.State.Set("sessionTrendIcon", sessionTrendIcon)
-				dashboard.State.Set("loadPercentage", loadChange)
-				dashboard.State.Set("loadTrend", loadTrend)
-				dashboard.State.Set("loadTrendColor", loadTrendColor)
-				dashboard.State.Set("loadTrendIcon", loadTrendIcon)
+				// Update data for the UI in a single broadcast
+				dashboard.State.SetBatch(map[string]interface{}{
+					"users":             newUsers,
+					"userTrend":         userTrend,
+					"userTrendColor":    userTrendColor,
+					"userTrendIcon":     userTrendIcon,
+					"sessions":          newSessions,
+					"sessionTrend":      sessionTrend,
+					"sessionTrendColor": sessionTrendColor,
+					"sessionTrendIcon":  sessionTrendIcon,
+					"loadPercentage":    loadChange,
+					"loadTrend":         loadTrend,
+					"loadTrendColor":    loadTrendColor,
+					"loadTrendIcon":     loadTrendIcon,
+				})
 
 				// Update our tracking variables for next time
 				currentUsers = newUsers
@@ -916,8 +923,14 @@ func NewAdminDashboard(id string) *component.Component {
 	// Notification message (initially empty)
 	dashboard.State.Set("notification", "")
 
-	// Start continuous updates instead of timed updates
-	trafficPattern.StartContinuousUpdates(dashboard)
+	// Start continuous updates instead of timed updates, tied to the
+	// component's lifetime
+	ctx, cancel := context.WithCancel(context.Background())
+	trafficPattern.StartContinuousUpdates(ctx, dashboard)
+	dashboard.Lifecycle.OnDestroy = func(c *component.Component) error {
+		cancel()
+		return nil
+	}
 
 	// Add method for refreshing stats (will be called via WebSocket)
 	dashboard.Methods["refreshStats"] = func(params map[string]interface{}) error {
@@ -1083,6 +1096,15 @@ func NewAdminDashboard(id string) *component.Component {
 		return nil
 	}
 
+	// Clearing the cache is destructive; reject replayed requests
+	dashboard.RequireNonce("clearCache")
+
+	// clearCache toggles the status; don't let two clients interleave it
+	dashboard.SerializeActions(true)
+
+	// The stats tickers write several times a second; render from one snapshot
+	dashboard.RenderFromSnapshot(true)
+
 	return dashboard
 }
 
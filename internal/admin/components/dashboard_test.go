@@ -0,0 +1,53 @@
+package components
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/magooney-loon/webrender/pkg/component"
+)
+
+// waitForGoroutines polls until at most n goroutines are running
+func waitForGoroutines(t *testing.T, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines still running, want at most %d", runtime.NumGoroutine(), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestContinuousUpdatesStopOnCancel(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dashboard := component.New("dashboard-1", "dashboard", `<div></div>`)
+	NewTrafficPattern().StartContinuousUpdates(ctx, dashboard)
+
+	if runtime.NumGoroutine() <= baseline {
+		t.Fatal("no update goroutine started")
+	}
+
+	cancel()
+	waitForGoroutines(t, baseline)
+}
+
+func TestDashboardUpdatesStopOnDestroy(t *testing.T) {
+	registry := component.NewRegistry(nil)
+	baseline := runtime.NumGoroutine()
+
+	dashboard := NewAdminDashboard("admin-dashboard")
+	if err := registry.Register(dashboard); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := registry.Remove(dashboard.ID); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	waitForGoroutines(t, baseline)
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/magooney-loon/webrender/internal/admin/middleware"
+	"github.com/magooney-loon/webrender/pkg/component"
+	"github.com/magooney-loon/webrender/pkg/state"
+)
+
+// ComponentEnabledHandler toggles a component on or off at runtime
+// Expects a POST with an "enabled" form value ("true" or "false")
+func ComponentEnabledHandler(registry *component.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		if _, exists := registry.Get(id); !exists {
+			http.Error(w, "Component not found", http.StatusNotFound)
+			return
+		}
+
+		enabled, err := strconv.ParseBool(r.FormValue("enabled"))
+		if err != nil {
+			http.Error(w, "Invalid value for enabled", http.StatusBadRequest)
+			return
+		}
+
+		registry.SetEnabled(id, enabled)
+		registry.Logger().Infof("Admin %s set component %s enabled=%t", middleware.GetUserFromContext(r), id, enabled)
+
+		writeJSON(w, r, map[string]interface{}{
+			"id":      id,
+			"enabled": enabled,
+		}, registry.Debug())
+	}
+}
+
+// ComponentStateHandler shows or edits a component's live state
+// GET returns all state values. POST sets one key from the "key" and
+// "value" form values, where value is JSON (e.g. 42, "text", {"a":1});
+// the change is broadcast to clients like any other state update.
+func ComponentStateHandler(registry *component.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		comp, exists := registry.Get(id)
+		if !exists {
+			http.Error(w, "Component not found", http.StatusNotFound)
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			key := r.FormValue("key")
+			if key == "" {
+				http.Error(w, "Missing state key", http.StatusBadRequest)
+				return
+			}
+
+			var value interface{}
+			if err := json.Unmarshal([]byte(r.FormValue("value")), &value); err != nil {
+				http.Error(w, "Value must be valid JSON", http.StatusBadRequest)
+				return
+			}
+
+			// Audit manual edits with the previous value
+			oldValue := comp.State.Get(key)
+			comp.State.Set(key, value)
+			registry.Logger().Infof("Admin %s edited component %s state %q: %v -> %v",
+				middleware.GetUserFromContext(r), id, key, oldValue, value)
+		}
+
+		writeJSON(w, r, map[string]interface{}{
+			"id":    id,
+			"state": comp.State.GetAll(),
+		}, registry.Debug())
+	}
+}
+
+// MethodsHandler lists the methods (client actions) of every component
+func MethodsHandler(registry *component.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		methods := make(map[string][]string)
+		for _, comp := range registry.GetAll() {
+			methods[comp.ID] = comp.MethodNames()
+		}
+
+		writeJSON(w, r, methods, registry.Debug())
+	}
+}
+
+// ComponentMethodHandler lists a component's methods on GET and, in debug
+// mode only, invokes one on POST for testing
+// POST reads the "method" form value and an optional "params" form value
+// holding a JSON object.
+func ComponentMethodHandler(registry *component.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		comp, exists := registry.Get(id)
+		if !exists {
+			http.Error(w, "Component not found", http.StatusNotFound)
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			writeJSON(w, r, map[string]interface{}{
+				"id":      id,
+				"methods": comp.MethodNames(),
+			}, registry.Debug())
+			return
+		}
+
+		// Triggering methods by hand is a development tool
+		if !registry.Debug() {
+			http.Error(w, "Method triggering is only available in debug mode", http.StatusForbidden)
+			return
+		}
+
+		name := r.FormValue("method")
+		if name == "" {
+			http.Error(w, "Missing method name", http.StatusBadRequest)
+			return
+		}
+
+		params := map[string]interface{}{}
+		if raw := r.FormValue("params"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &params); err != nil {
+				http.Error(w, "Params must be a JSON object", http.StatusBadRequest)
+				return
+			}
+		}
+
+		registry.Logger().Infof("Admin %s triggered component %s method %q with %v",
+			middleware.GetUserFromContext(r), id, name, params)
+
+		result := map[string]interface{}{
+			"id":     id,
+			"method": name,
+		}
+		if err := comp.CallMethod(name, params); err != nil {
+			result["error"] = err.Error()
+		}
+
+		writeJSON(w, r, result, registry.Debug())
+	}
+}
+
+// MetricsHandler reports component lifecycle and render counters and
+// WebSocket connection health as JSON
+func MetricsHandler(sm *state.StateManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		registry := sm.GetComponentRegistry()
+		writeJSON(w, r, map[string]interface{}{
+			"lifecycle": registry.LifecycleStats(),
+			"renders":   registry.RenderStats(),
+			"websocket": sm.GetWebSocketManager().Stats(),
+		}, registry.Debug())
+	}
+}
+
+// writeJSON writes a JSON response body
+// When allowPretty is set (debug mode), ?pretty=1 indents the output for
+// reading with curl.
+func writeJSON(w http.ResponseWriter, r *http.Request, data interface{}, allowPretty bool) {
+	w.Header().Set("Content-Type", "application/json")
+
+	encoder := json.NewEncoder(w)
+	if allowPretty && r.URL.Query().Get("pretty") == "1" {
+		encoder.SetIndent("", "  ")
+	}
+
+	if err := encoder.Encode(data); err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+	}
+}
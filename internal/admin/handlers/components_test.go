@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/magooney-loon/webrender/internal/admin/middleware"
+	"github.com/magooney-loon/webrender/internal/admin/session"
+	"github.com/magooney-loon/webrender/pkg/component"
+	"github.com/magooney-loon/webrender/pkg/logger"
+)
+
+// recordingBroadcaster records the keys of broadcast state updates
+type recordingBroadcaster struct {
+	mutex sync.Mutex
+	keys  []string
+}
+
+func (b *recordingBroadcaster) BroadcastStateUpdate(componentID, key string, value interface{}, updateType string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.keys = append(b.keys, componentID+"."+key)
+	return nil
+}
+
+func (b *recordingBroadcaster) broadcast() []string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return append([]string(nil), b.keys...)
+}
+
+// adminServer serves a handler on an admin route behind the real auth and
+// CSRF middleware, with an in-memory session store. GET /_/token returns
+// a CSRF token for the request's session.
+func adminServer(t *testing.T, route string, handler http.HandlerFunc) http.Handler {
+	t.Helper()
+
+	previous := session.Store
+	session.Store = sessions.NewCookieStore(securecookie.GenerateRandomKey(64), securecookie.GenerateRandomKey(32))
+	session.Store.Options = &sessions.Options{Path: "/", MaxAge: session.MaxAge, HttpOnly: true}
+	t.Cleanup(func() { session.Store = previous })
+
+	if err := middleware.InitCSRF(); err != nil {
+		t.Fatalf("InitCSRF: %v", err)
+	}
+
+	r := mux.NewRouter()
+	admin := r.PathPrefix("/_").Subrouter()
+	admin.Use(middleware.RequireAdminAuth)
+	admin.Use(middleware.CSRFMiddleware)
+	admin.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, middleware.CSRFToken(r))
+	})
+	admin.HandleFunc(route, handler).Methods("GET", "POST")
+	return r
+}
+
+// adminClient sends requests with the cookies it has been given
+type adminClient struct {
+	t       *testing.T
+	handler http.Handler
+	cookies map[string]*http.Cookie
+}
+
+// loginAs returns a client with a session for the given role
+func loginAs(t *testing.T, handler http.Handler, role string) *adminClient {
+	t.Helper()
+
+	c := &adminClient{t: t, handler: handler, cookies: map[string]*http.Cookie{}}
+	rec := httptest.NewRecorder()
+	if err := session.CreateUserSession(rec, httptest.NewRequest(http.MethodGet, "/_/login", nil), "tester", role); err != nil {
+		t.Fatalf("CreateUserSession: %v", err)
+	}
+	c.keep(rec)
+	return c
+}
+
+// keep stores the cookies set by a response
+func (c *adminClient) keep(rec *httptest.ResponseRecorder) {
+	for _, cookie := range rec.Result().Cookies() {
+		c.cookies[cookie.Name] = cookie
+	}
+}
+
+// do serves a request with the client's cookies
+func (c *adminClient) do(req *http.Request) *httptest.ResponseRecorder {
+	for _, cookie := range c.cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	c.handler.ServeHTTP(rec, req)
+	c.keep(rec)
+	return rec
+}
+
+// csrfToken fetches a CSRF token for the client's session
+func (c *adminClient) csrfToken() string {
+	c.t.Helper()
+
+	rec := c.do(httptest.NewRequest(http.MethodGet, "/_/token", nil))
+	if rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+		c.t.Fatalf("fetching CSRF token: status %d", rec.Code)
+	}
+	return rec.Body.String()
+}
+
+// postForm builds a form POST, with a CSRF token when one is given
+func postForm(path string, values url.Values, token string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if token != "" {
+		req.Header.Set("X-CSRF-Token", token)
+	}
+	return req
+}
+
+// newStateRegistry registers a counter component with a recorded broadcaster
+func newStateRegistry(t *testing.T) (*component.Registry, *recordingBroadcaster) {
+	t.Helper()
+
+	b := &recordingBroadcaster{}
+	registry := component.NewRegistry(b)
+	registry.SetLogger(logger.Discard())
+
+	c := component.New("counter-1", "counter", `<div></div>`)
+	c.State.Set("count", 1)
+	if err := registry.Register(c); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return registry, b
+}
+
+func TestComponentStateHandlerReadsState(t *testing.T) {
+	registry, _ := newStateRegistry(t)
+	server := adminServer(t, "/components/{id}/state", ComponentStateHandler(registry))
+	client := loginAs(t, server, "admin")
+
+	rec := client.do(httptest.NewRequest(http.MethodGet, "/_/components/counter-1/state", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var body struct {
+		ID    string                 `json:"id"`
+		State map[string]interface{} `json:"state"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if body.ID != "counter-1" || body.State["count"] != float64(1) {
+		t.Errorf("got %+v, want counter-1 with count 1", body)
+	}
+
+	rec = client.do(httptest.NewRequest(http.MethodGet, "/_/components/missing/state", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown component: status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestComponentStateHandlerSetsKey(t *testing.T) {
+	registry, broadcaster := newStateRegistry(t)
+	server := adminServer(t, "/components/{id}/state", ComponentStateHandler(registry))
+	client := loginAs(t, server, "admin")
+
+	form := url.Values{"key": {"count"}, "value": {"42"}}
+	rec := client.do(postForm("/_/components/counter-1/state", form, client.csrfToken()))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	comp, _ := registry.Get("counter-1")
+	if got := comp.State.Get("count"); got != float64(42) {
+		t.Errorf("count = %v, want 42", got)
+	}
+	if got := broadcaster.broadcast(); len(got) != 1 || got[0] != "counter-1.count" {
+		t.Errorf("broadcast %v, want the count update", got)
+	}
+
+	form = url.Values{"key": {"count"}, "value": {"not json"}}
+	rec = client.do(postForm("/_/components/counter-1/state", form, client.csrfToken()))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid value: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestComponentStateHandlerRejectsUnauthorized(t *testing.T) {
+	registry, broadcaster := newStateRegistry(t)
+	server := adminServer(t, "/components/{id}/state", ComponentStateHandler(registry))
+	form := url.Values{"key": {"count"}, "value": {"42"}}
+
+	// No session
+	anonymous := &adminClient{t: t, handler: server, cookies: map[string]*http.Cookie{}}
+	rec := anonymous.do(httptest.NewRequest(http.MethodGet, "/_/components/counter-1/state", nil))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/_/login" {
+		t.Errorf("anonymous GET: status %d to %q, want a redirect to the login page", rec.Code, rec.Header().Get("Location"))
+	}
+
+	// A session without an admin role
+	viewer := loginAs(t, server, "viewer")
+	rec = viewer.do(httptest.NewRequest(http.MethodGet, "/_/components/counter-1/state", nil))
+	if rec.Code != http.StatusFound {
+		t.Errorf("viewer GET: status = %d, want a redirect", rec.Code)
+	}
+
+	// An admin session without a CSRF token
+	admin := loginAs(t, server, "admin")
+	rec = admin.do(postForm("/_/components/counter-1/state", form, ""))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("POST without CSRF token: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	comp, _ := registry.Get("counter-1")
+	if got := comp.State.Get("count"); got != 1 {
+		t.Errorf("count = %v after rejected requests, want 1", got)
+	}
+	if got := broadcaster.broadcast(); len(got) != 0 {
+		t.Errorf("rejected requests broadcast %v", got)
+	}
+}
+
+// auditLogger records the info lines written to it
+type auditLogger struct {
+	mutex sync.Mutex
+	lines []string
+}
+
+func (l *auditLogger) Debugf(format string, args ...interface{}) {}
+func (l *auditLogger) Warnf(format string, args ...interface{})  {}
+func (l *auditLogger) Errorf(format string, args ...interface{}) {}
+
+func (l *auditLogger) Infof(format string, args ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func TestComponentStateEditsAreAuditedToTheRegistryLogger(t *testing.T) {
+	registry, _ := newStateRegistry(t)
+	audit := &auditLogger{}
+	registry.SetLogger(audit)
+	server := adminServer(t, "/components/{id}/state", ComponentStateHandler(registry))
+	client := loginAs(t, server, "admin")
+
+	form := url.Values{"key": {"count"}, "value": {"42"}}
+	if rec := client.do(postForm("/_/components/counter-1/state", form, client.csrfToken())); rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	audit.mutex.Lock()
+	defer audit.mutex.Unlock()
+	want := `Admin tester edited component counter-1 state "count": 1 -> 42`
+	if len(audit.lines) != 1 || audit.lines[0] != want {
+		t.Errorf("audit lines = %q, want [%q]", audit.lines, want)
+	}
+}
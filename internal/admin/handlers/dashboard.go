@@ -4,22 +4,32 @@ import (
 	"html/template"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
 
 	"github.com/gorilla/mux"
 	"github.com/magooney-loon/webrender/internal/admin/components"
 	"github.com/magooney-loon/webrender/internal/admin/middleware"
 	"github.com/magooney-loon/webrender/internal/admin/session"
+	"github.com/magooney-loon/webrender/pkg/router"
 	"github.com/magooney-loon/webrender/pkg/state"
 	tmpl "github.com/magooney-loon/webrender/pkg/template"
+	"github.com/magooney-loon/webrender/pkg/websocket"
 )
 
 // RegisterAdminRoutes registers all admin dashboard routes
-func RegisterAdminRoutes(r *mux.Router, sm *state.StateManager) {
+func RegisterAdminRoutes(r *mux.Router, sm *state.StateManager, maintenance *router.Maintenance, wsPath, ssePath string, assets tmpl.Assets) {
 	// Initialize session management
 	session.Initialize()
 
+	// Let admins through while maintenance mode is on
+	maintenance.SetBypass(middleware.IsAdmin)
+
+	// Tag admin WebSocket clients so they can be targeted as a group
+	sm.GetWebSocketManager().OnClient(func(c *websocket.Client, r *http.Request) {
+		if middleware.IsAdmin(r) {
+			c.SetMetadata("role", session.GetUserRole(r))
+		}
+	})
+
 	// Initialize CSRF protection
 	if err := middleware.InitCSRF(); err != nil {
 		log.Fatalf("Failed to initialize CSRF protection: %v", err)
@@ -32,7 +42,7 @@ func RegisterAdminRoutes(r *mux.Router, sm *state.StateManager) {
 	publicAdminRouter.Use(middleware.CSRFMiddleware)
 
 	// Login page route
-	publicAdminRouter.HandleFunc("/login", AdminLoginPageHandler).Methods("GET")
+	publicAdminRouter.HandleFunc("/login", AdminLoginPageHandler(assets)).Methods("GET")
 	publicAdminRouter.HandleFunc("/login", AdminLoginHandler).Methods("POST")
 
 	// Logout route (doesn't need auth)
@@ -41,6 +51,7 @@ func RegisterAdminRoutes(r *mux.Router, sm *state.StateManager) {
 	// Create a subrouter for protected admin routes
 	adminRouter := r.PathPrefix("/_").Subrouter()
 	adminRouter.Use(middleware.RequireAdminAuth)
+	adminRouter.Use(middleware.CSRFMiddleware)
 
 	// Register components
 	dashboard := components.NewAdminDashboard("admin-dashboard")
@@ -57,11 +68,10 @@ func RegisterAdminRoutes(r *mux.Router, sm *state.StateManager) {
 			return
 		}
 
-		// Read client JS content
-		clientJSPath := filepath.Join("pkg", "websocket", "client.js")
-		clientJSContent, err := os.ReadFile(clientJSPath)
-		if err != nil {
-			http.Error(w, "Failed to load WebSocket client: "+err.Error(), http.StatusInternalServerError)
+		// Client JS is embedded in the websocket package
+		clientJSContent := websocket.ClientJS()
+		if clientJSContent == "" {
+			http.Error(w, "Failed to load WebSocket client: script is empty", http.StatusInternalServerError)
 			return
 		}
 
@@ -72,6 +82,11 @@ func RegisterAdminRoutes(r *mux.Router, sm *state.StateManager) {
 			Styles:   template.CSS(components.GetDashboardStyles()),
 			Scripts:  template.JS(components.GetDashboardScripts()),
 			ClientJS: template.JS(clientJSContent),
+			Nonce:    router.CSPNonce(r),
+
+			WebSocketPath: wsPath,
+			SSEPath:       ssePath,
+			Assets:        assets,
 		}
 
 		// Render the page using base template
@@ -88,10 +103,34 @@ func RegisterAdminRoutes(r *mux.Router, sm *state.StateManager) {
 
 	// Analytics page
 	adminRouter.HandleFunc("/analytics", AdminAnalyticsHandler).Methods("GET")
+
+	// Maintenance mode toggle
+	registry := sm.GetComponentRegistry()
+	adminRouter.HandleFunc("/maintenance", MaintenanceHandler(maintenance, registry.Logger(), registry.Debug)).Methods("POST")
+
+	// Component feature flags
+	adminRouter.HandleFunc("/components/{id}/enabled", ComponentEnabledHandler(sm.GetComponentRegistry())).Methods("POST")
+
+	// Component state inspector
+	adminRouter.HandleFunc("/components/{id}/state", ComponentStateHandler(sm.GetComponentRegistry())).Methods("GET", "POST")
+
+	// Component method introspection and test triggers
+	adminRouter.HandleFunc("/methods", MethodsHandler(sm.GetComponentRegistry())).Methods("GET")
+	adminRouter.HandleFunc("/components/{id}/methods", ComponentMethodHandler(sm.GetComponentRegistry())).Methods("GET", "POST")
+
+	// Component metrics
+	adminRouter.HandleFunc("/metrics", MetricsHandler(sm)).Methods("GET")
 }
 
-// AdminLoginPageHandler serves the login page
-func AdminLoginPageHandler(w http.ResponseWriter, r *http.Request) {
+// AdminLoginPageHandler serves the login page, styled with assets
+func AdminLoginPageHandler(assets tmpl.Assets) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		adminLoginPage(w, r, assets)
+	}
+}
+
+// adminLoginPage writes the login page
+func adminLoginPage(w http.ResponseWriter, r *http.Request, assets tmpl.Assets) {
 	// If user is already authenticated, redirect to dashboard
 	if session.IsAuthenticated(r) {
 		http.Redirect(w, r, "/_/", http.StatusFound)
@@ -114,6 +153,21 @@ func AdminLoginPageHandler(w http.ResponseWriter, r *http.Request) {
 	// Get CSRF token field
 	csrfField := middleware.CSRFField(r)
 
+	// Allow the inline styles under a nonce-based CSP
+	nonceAttr := ""
+	if nonce := router.CSPNonce(r); nonce != "" {
+		nonceAttr = ` nonce="` + nonce + `"`
+	}
+
+	// Load Tailwind from the CDN unless assets are self-hosted
+	stylesheets := `<link href="https://cdn.jsdelivr.net/npm/tailwindcss@2.2.19/dist/tailwind.min.css" rel="stylesheet"` + nonceAttr + `>`
+	if assets.SelfHosted {
+		stylesheets = ""
+	}
+	for _, href := range assets.Stylesheets {
+		stylesheets += `<link href="` + template.HTMLEscapeString(href) + `" rel="stylesheet"` + nonceAttr + `>`
+	}
+
 	loginHTML := `
 	<!DOCTYPE html>
 	<html lang="en">
@@ -122,8 +176,8 @@ func AdminLoginPageHandler(w http.ResponseWriter, r *http.Request) {
 		<meta name="viewport" content="width=device-width, initial-scale=1.0">
 		<link rel="icon" href="/static/logo.svg" type="image/svg+xml">
 		<title>Admin Login</title>
-		<link href="https://cdn.jsdelivr.net/npm/tailwindcss@2.2.19/dist/tailwind.min.css" rel="stylesheet">
-		<style>
+		` + stylesheets + `
+		<style` + nonceAttr + `>
 			/* Vercel dark theme styles */
 			.vercel-card {
 				background: rgba(32, 32, 36, 0.5);
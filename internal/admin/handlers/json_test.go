@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteJSONPrettyPrinting(t *testing.T) {
+	data := map[string]interface{}{"id": "counter-1", "state": map[string]int{"count": 3}}
+	const compact = `{"id":"counter-1","state":{"count":3}}` + "\n"
+	const indented = "{\n  \"id\": \"counter-1\",\n  \"state\": {\n    \"count\": 3\n  }\n}\n"
+
+	tests := []struct {
+		name  string
+		debug bool
+		path  string
+		want  string
+	}{
+		{"debug with pretty", true, "/_/metrics?pretty=1", indented},
+		{"debug without pretty", true, "/_/metrics", compact},
+		{"production with pretty", false, "/_/metrics?pretty=1", compact},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeJSON(rec, httptest.NewRequest(http.MethodGet, tt.path, nil), data, tt.debug)
+
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("body = %q, want %q", got, tt.want)
+			}
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/magooney-loon/webrender/internal/admin/middleware"
+	"github.com/magooney-loon/webrender/pkg/logger"
+	"github.com/magooney-loon/webrender/pkg/router"
+)
+
+// MaintenanceHandler turns maintenance mode on or off
+// Expects a POST with an "enabled" form value ("true" or "false"). Changes
+// are audited to log; debug reports whether ?pretty=1 may indent responses.
+func MaintenanceHandler(maintenance *router.Maintenance, log logger.Logger, debug func() bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		enabled, err := strconv.ParseBool(r.FormValue("enabled"))
+		if err != nil {
+			http.Error(w, "Invalid value for enabled", http.StatusBadRequest)
+			return
+		}
+
+		maintenance.SetEnabled(enabled)
+		log.Infof("Admin %s set maintenance mode enabled=%t", middleware.GetUserFromContext(r), enabled)
+
+		writeJSON(w, r, map[string]interface{}{
+			"maintenance": enabled,
+		}, debug())
+	}
+}
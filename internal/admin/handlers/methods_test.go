@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/magooney-loon/webrender/pkg/component"
+	"github.com/magooney-loon/webrender/pkg/logger"
+)
+
+// newMethodRegistry registers a counter whose methods record their params
+func newMethodRegistry(t *testing.T) (*component.Registry, *[]map[string]interface{}) {
+	t.Helper()
+
+	registry := component.NewRegistry(nil)
+	registry.SetLogger(logger.Discard())
+
+	var calls []map[string]interface{}
+	c := component.New("counter-1", "counter", `<div></div>`)
+	for _, name := range []string{"increment", "decrement", "reset"} {
+		c.AddTypedMethod(name, func(params map[string]interface{}) error {
+			calls = append(calls, params)
+			return nil
+		})
+	}
+	if err := registry.Register(c); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	return registry, &calls
+}
+
+// triggerMethod posts a method trigger for counter-1
+func triggerMethod(registry *component.Registry, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/_/components/counter-1/methods", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = mux.SetURLVars(req, map[string]string{"id": "counter-1"})
+
+	rec := httptest.NewRecorder()
+	ComponentMethodHandler(registry)(rec, req)
+	return rec
+}
+
+func TestMethodsHandlerListsMethods(t *testing.T) {
+	registry, _ := newMethodRegistry(t)
+
+	rec := httptest.NewRecorder()
+	MethodsHandler(registry)(rec, httptest.NewRequest(http.MethodGet, "/_/methods", nil))
+
+	var methods map[string][]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &methods); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	want := map[string][]string{"counter-1": {"decrement", "increment", "reset"}}
+	if !reflect.DeepEqual(methods, want) {
+		t.Errorf("methods = %v, want %v", methods, want)
+	}
+}
+
+func TestComponentMethodHandlerTriggersInDebugMode(t *testing.T) {
+	registry, calls := newMethodRegistry(t)
+	registry.SetDebug(true)
+
+	rec := triggerMethod(registry, url.Values{"method": {"increment"}, "params": {`{"step":2}`}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if len(*calls) != 1 || (*calls)[0]["step"] != float64(2) {
+		t.Errorf("calls = %v, want one increment with step 2", *calls)
+	}
+
+	// Unknown methods report the error in the response
+	rec = triggerMethod(registry, url.Values{"method": {"explode"}})
+	var result map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &result)
+	if result["error"] == nil {
+		t.Errorf("unknown method: response %v has no error", result)
+	}
+
+	if rec := triggerMethod(registry, url.Values{"method": {"increment"}, "params": {"[1]"}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("non-object params: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestComponentMethodHandlerRefusesTriggersInProduction(t *testing.T) {
+	registry, calls := newMethodRegistry(t)
+
+	rec := triggerMethod(registry, url.Values{"method": {"increment"}})
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if len(*calls) != 0 {
+		t.Errorf("method ran %d times outside debug mode", len(*calls))
+	}
+}
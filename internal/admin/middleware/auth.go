@@ -61,6 +61,16 @@ func RequireAdminAuth(next http.Handler) http.Handler {
 	})
 }
 
+// IsAdmin reports whether the request carries an authenticated admin session
+func IsAdmin(r *http.Request) bool {
+	if !session.IsAuthenticated(r) {
+		return false
+	}
+
+	role := session.GetUserRole(r)
+	return role == "admin" || role == "super-admin"
+}
+
 // GetUserFromContext retrieves the username from the request context
 func GetUserFromContext(r *http.Request) string {
 	if user, ok := r.Context().Value(UserKey).(string); ok {
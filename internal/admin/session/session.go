@@ -2,6 +2,7 @@ package session
 
 import (
 	"net/http"
+	"sync"
 
 	"github.com/gorilla/sessions"
 )
@@ -23,8 +24,64 @@ const (
 var (
 	// Store is the session store used for admin sessions
 	Store *sessions.CookieStore
+
+	// Cookie attributes applied to the store and every saved session
+	cookieOptions    = DefaultCookieOptions()
+	cookieOptionsMux sync.RWMutex
 )
 
+// CookieOptions configures the admin session cookie
+type CookieOptions struct {
+	Path     string
+	Domain   string // Empty for a host-only cookie; set e.g. "example.com" to share across subdomains
+	MaxAge   int
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+}
+
+// DefaultCookieOptions returns the default session cookie attributes
+// SameSite is Lax so following a link into the admin panel from another
+// site keeps the session; cross-site subrequests and POSTs still don't
+// carry it, and state-changing requests also need a CSRF token. Strict
+// drops it on those navigations too; opt in with SetCookieOptions.
+func DefaultCookieOptions() CookieOptions {
+	return CookieOptions{
+		Path:     "/",
+		MaxAge:   MaxAge,
+		Secure:   true, // Requires HTTPS
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// SetCookieOptions sets the session cookie attributes
+// It may be called before or after Init.
+func SetCookieOptions(opts CookieOptions) {
+	cookieOptionsMux.Lock()
+	cookieOptions = opts
+	cookieOptionsMux.Unlock()
+
+	if Store != nil {
+		Store.Options = sessionOptions()
+	}
+}
+
+// sessionOptions converts the cookie attributes into store options
+func sessionOptions() *sessions.Options {
+	cookieOptionsMux.RLock()
+	defer cookieOptionsMux.RUnlock()
+
+	return &sessions.Options{
+		Path:     cookieOptions.Path,
+		Domain:   cookieOptions.Domain,
+		MaxAge:   cookieOptions.MaxAge,
+		Secure:   cookieOptions.Secure,
+		HttpOnly: cookieOptions.HttpOnly,
+		SameSite: cookieOptions.SameSite,
+	}
+}
+
 // Init initializes the session store with secure keys
 func Init() error {
 	// Load or generate secure keys
@@ -36,13 +93,7 @@ func Init() error {
 	Store = sessions.NewCookieStore(hashKey, blockKey)
 
 	// Configure the session store
-	Store.Options = &sessions.Options{
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   true, // Requires HTTPS
-		SameSite: http.SameSiteStrictMode,
-		MaxAge:   MaxAge,
-	}
+	Store.Options = sessionOptions()
 
 	return nil
 }
@@ -75,8 +126,8 @@ func CreateUserSession(w http.ResponseWriter, r *http.Request, username string,
 	}
 	session.ID = sessionID
 
-	// Set session expiry
-	session.Options.MaxAge = MaxAge
+	// Apply the configured cookie attributes
+	session.Options = sessionOptions()
 
 	// Save the session
 	return Save(r, w, session)
@@ -92,7 +143,8 @@ func ClearSession(w http.ResponseWriter, r *http.Request) error {
 	// Clear session values
 	session.Values = make(map[interface{}]interface{})
 
-	// Expire the cookie
+	// Expire the cookie, matching the attributes it was set with
+	session.Options = sessionOptions()
 	session.Options.MaxAge = -1
 
 	// Save the session (which will delete it)
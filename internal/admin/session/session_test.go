@@ -0,0 +1,87 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// useTestStore installs an in-memory cookie store and cookie options,
+// restoring the previous ones after the test
+func useTestStore(t *testing.T, opts CookieOptions) {
+	t.Helper()
+
+	previousStore := Store
+	cookieOptionsMux.RLock()
+	previousOptions := cookieOptions
+	cookieOptionsMux.RUnlock()
+	t.Cleanup(func() {
+		Store = previousStore
+		SetCookieOptions(previousOptions)
+	})
+
+	Store = sessions.NewCookieStore(securecookie.GenerateRandomKey(64), securecookie.GenerateRandomKey(32))
+	SetCookieOptions(opts)
+}
+
+// sessionCookie returns the session cookie set by a response
+func sessionCookie(t *testing.T, rec *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == SessionName {
+			return cookie
+		}
+	}
+	t.Fatal("no session cookie set")
+	return nil
+}
+
+func TestDefaultCookieOptionsAreLax(t *testing.T) {
+	opts := DefaultCookieOptions()
+	if opts.SameSite != http.SameSiteLaxMode || !opts.Secure || !opts.HttpOnly || opts.Domain != "" {
+		t.Errorf("got %+v, want a Secure, HttpOnly, host-only SameSite=Lax cookie", opts)
+	}
+}
+
+func TestSessionCookieHonorsSameSiteAndDomain(t *testing.T) {
+	opts := DefaultCookieOptions()
+	opts.SameSite = http.SameSiteStrictMode
+	opts.Domain = "example.com"
+	useTestStore(t, opts)
+
+	rec := httptest.NewRecorder()
+	if err := CreateUserSession(rec, httptest.NewRequest(http.MethodGet, "/_/login", nil), "admin", "admin"); err != nil {
+		t.Fatalf("CreateUserSession: %v", err)
+	}
+
+	cookie := sessionCookie(t, rec)
+	if cookie.SameSite != http.SameSiteStrictMode {
+		t.Errorf("SameSite = %v, want Strict", cookie.SameSite)
+	}
+	if cookie.Domain != "example.com" {
+		t.Errorf("Domain = %q, want example.com", cookie.Domain)
+	}
+	if !cookie.Secure || !cookie.HttpOnly {
+		t.Errorf("Secure = %v, HttpOnly = %v, want both set", cookie.Secure, cookie.HttpOnly)
+	}
+
+	// Clearing the session expires the same cookie
+	req := httptest.NewRequest(http.MethodGet, "/_/logout", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	if err := ClearSession(rec, req); err != nil {
+		t.Fatalf("ClearSession: %v", err)
+	}
+
+	cleared := sessionCookie(t, rec)
+	if cleared.MaxAge >= 0 {
+		t.Errorf("MaxAge = %d, want the cookie expired", cleared.MaxAge)
+	}
+	if cleared.Domain != "example.com" || cleared.SameSite != http.SameSiteStrictMode {
+		t.Errorf("cleared cookie has Domain %q, SameSite %v; want the attributes it was set with", cleared.Domain, cleared.SameSite)
+	}
+}
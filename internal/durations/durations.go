@@ -0,0 +1,88 @@
+// Package durations summarizes latency and duration distributions for the
+// stats the component registry and WebSocket manager report
+package durations
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultMaxSamples bounds the samples a Recorder keeps when MaxSamples is 0
+const DefaultMaxSamples = 256
+
+// Summary describes a distribution of durations
+// Percentiles are computed over the most recent samples; the count,
+// minimum, maximum, and mean cover everything recorded.
+type Summary struct {
+	Count int64
+	Min   time.Duration
+	Max   time.Duration
+	Mean  time.Duration
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+}
+
+// Recorder accumulates durations in a bounded ring of samples
+// The zero value is ready to use. A Recorder is not safe for concurrent
+// use; callers guard it with their own lock.
+type Recorder struct {
+	// Most recent samples kept for percentiles; DefaultMaxSamples if 0
+	MaxSamples int
+
+	count   int64
+	total   time.Duration
+	min     time.Duration
+	max     time.Duration
+	samples []time.Duration
+	next    int
+}
+
+// Record adds a duration to the distribution
+func (r *Recorder) Record(v time.Duration) {
+	if r.count == 0 || v < r.min {
+		r.min = v
+	}
+	if v > r.max {
+		r.max = v
+	}
+	r.count++
+	r.total += v
+
+	limit := r.MaxSamples
+	if limit <= 0 {
+		limit = DefaultMaxSamples
+	}
+	if len(r.samples) < limit {
+		r.samples = append(r.samples, v)
+		return
+	}
+	r.samples[r.next] = v
+	r.next = (r.next + 1) % len(r.samples)
+}
+
+// Summary returns the count, extremes, mean, and percentiles recorded
+func (r *Recorder) Summary() Summary {
+	if r.count == 0 {
+		return Summary{}
+	}
+
+	sorted := make([]time.Duration, len(r.samples))
+	copy(sorted, r.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return Summary{
+		Count: r.count,
+		Min:   r.min,
+		Max:   r.max,
+		Mean:  r.total / time.Duration(r.count),
+		P50:   percentile(sorted, 0.50),
+		P95:   percentile(sorted, 0.95),
+		P99:   percentile(sorted, 0.99),
+	}
+}
+
+// percentile returns the p-th percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	return sorted[int(float64(len(sorted)-1)*p)]
+}
@@ -0,0 +1,59 @@
+package durations
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummary(t *testing.T) {
+	var r Recorder
+	if got := r.Summary(); got != (Summary{}) {
+		t.Errorf("empty recorder summary = %+v, want zero", got)
+	}
+
+	for i := 100; i >= 1; i-- {
+		r.Record(time.Duration(i) * time.Millisecond)
+	}
+	want := Summary{
+		Count: 100,
+		Min:   time.Millisecond,
+		Max:   100 * time.Millisecond,
+		Mean:  50500 * time.Microsecond,
+		P50:   50 * time.Millisecond,
+		P95:   95 * time.Millisecond,
+		P99:   99 * time.Millisecond,
+	}
+	if got := r.Summary(); got != want {
+		t.Errorf("summary = %+v, want %+v", got, want)
+	}
+}
+
+func TestSamplesBounded(t *testing.T) {
+	r := Recorder{MaxSamples: 10}
+
+	// An early slow sample is overwritten by later fast ones, but the
+	// count and maximum cover everything recorded
+	r.Record(time.Second)
+	for i := 0; i < r.MaxSamples; i++ {
+		r.Record(time.Millisecond)
+	}
+
+	summary := r.Summary()
+	if len(r.samples) != r.MaxSamples {
+		t.Errorf("kept %d samples, want %d", len(r.samples), r.MaxSamples)
+	}
+	if summary.Count != 11 || summary.Max != time.Second {
+		t.Errorf("count=%d max=%v, want 11 and 1s", summary.Count, summary.Max)
+	}
+	if summary.P99 != time.Millisecond {
+		t.Errorf("p99 = %v, want the slow sample to have been evicted", summary.P99)
+	}
+
+	var unset Recorder
+	for i := 0; i < DefaultMaxSamples+5; i++ {
+		unset.Record(time.Millisecond)
+	}
+	if len(unset.samples) != DefaultMaxSamples {
+		t.Errorf("zero recorder kept %d samples, want %d", len(unset.samples), DefaultMaxSamples)
+	}
+}
@@ -0,0 +1,129 @@
+package pkg
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/magooney-loon/webrender/pkg/component"
+	tmpl "github.com/magooney-loon/webrender/pkg/template"
+)
+
+// renderPage serves a base-template page and returns its HTML
+func renderPage(t *testing.T, wr *WebRender) string {
+	t.Helper()
+
+	wr.RouteWithOptions("/page", "Page", func(*http.Request) (template.HTML, RenderOptions, error) {
+		return "<p>content</p>", RenderOptions{}, nil
+	}, nil, nil)
+
+	rec := get(wr, "/page")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	return rec.Body.String()
+}
+
+var cdnURLs = []string{"https://cdn.tailwindcss.com", "https://fonts.googleapis.com"}
+
+func TestBaseTemplateUsesCDNsByDefault(t *testing.T) {
+	page := renderPage(t, newTestWebRender(t))
+
+	for _, url := range cdnURLs {
+		if !strings.Contains(page, url) {
+			t.Errorf("default page does not load %s", url)
+		}
+	}
+}
+
+func TestBaseTemplateSelfHostedAssets(t *testing.T) {
+	wr := newTestWebRender(t, func(c *Config) {
+		c.Assets = tmpl.Assets{
+			SelfHosted:  true,
+			Stylesheets: []string{"/static/tailwind.css", "/static/fonts.css"},
+			Scripts:     []string{"/static/vendor.js"},
+		}
+	})
+	page := renderPage(t, wr)
+
+	for _, url := range cdnURLs {
+		if strings.Contains(page, url) {
+			t.Errorf("self-hosted page still loads %s", url)
+		}
+	}
+	for _, tag := range []string{
+		`<link rel="stylesheet" href="/static/tailwind.css"`,
+		`<link rel="stylesheet" href="/static/fonts.css"`,
+		`<script src="/static/vendor.js"`,
+	} {
+		if !strings.Contains(page, tag) {
+			t.Errorf("self-hosted page is missing %s", tag)
+		}
+	}
+}
+
+func TestConfigRejectsEmptyAssetURLs(t *testing.T) {
+	config := DefaultConfig()
+	config.Assets.Stylesheets = []string{" "}
+	if err := config.Validate(); err == nil {
+		t.Error("empty stylesheet URL accepted")
+	}
+}
+
+func TestPageIncludesComponentAssetsOnce(t *testing.T) {
+	wr := newTestWebRender(t)
+	for _, c := range []*component.Component{
+		component.New("counter-1", "counter", `<div id="counter-1"></div>`).WithAssets(".counter-css{}", "window.counterJS = 1;"),
+		component.New("counter-2", "counter", `<div id="counter-2"></div>`).WithAssets(".counter-css{}", "window.counterJS = 1;"),
+		component.New("card-1", "card", `<div id="card-1"></div>`).WithAssets(".card-css{}", ""),
+	} {
+		if err := wr.RegisterComponent(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wr.RouteWithOptions("/dashboard", "Dashboard", func(r *http.Request) (template.HTML, RenderOptions, error) {
+		var page strings.Builder
+		for _, id := range []string{"counter-1", "counter-2", "card-1"} {
+			html, err := wr.RenderComponentContext(r.Context(), id, nil)
+			if err != nil {
+				return "", RenderOptions{}, err
+			}
+			page.WriteString(html)
+		}
+		return template.HTML(page.String()), RenderOptions{}, nil
+	}, func() template.CSS { return ".route-css{}" }, nil)
+
+	rec := get(wr, "/dashboard")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	page := rec.Body.String()
+	for snippet, want := range map[string]int{
+		".counter-css{}":        1,
+		"window.counterJS = 1;": 1,
+		".card-css{}":           1,
+		".route-css{}":          1,
+	} {
+		if got := strings.Count(page, snippet); got != want {
+			t.Errorf("%s appears %d times, want %d", snippet, got, want)
+		}
+	}
+}
+
+func TestComponentRouteAutoIncludesAssets(t *testing.T) {
+	wr := newTestWebRender(t)
+	c := component.New("card-1", "card", `<div id="card-1"></div>`).WithAssets(".card-css{}", "window.cardJS = 1;")
+	if err := wr.RegisterComponent(c); err != nil {
+		t.Fatal(err)
+	}
+	wr.ComponentRouteAuto("/card", "Card", "card-1", nil)
+
+	page := get(wr, "/card").Body.String()
+	for _, snippet := range []string{".card-css{}", "window.cardJS = 1;"} {
+		if !strings.Contains(page, snippet) {
+			t.Errorf("page is missing %s", snippet)
+		}
+	}
+}
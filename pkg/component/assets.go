@@ -0,0 +1,54 @@
+package component
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// WithAssets sets the CSS and JavaScript pages include when they render
+// the component, and returns the component for chaining
+func (c *Component) WithAssets(css, js string) *Component {
+	c.Styles = css
+	c.Scripts = js
+	return c
+}
+
+// RenderedAssets concatenates the styles and scripts of the components
+// rendered with RenderComponentContext under ctx
+// Assets are included once per component Name, so several instances of a
+// component on one page share them. Both are empty without a render cache
+// in ctx.
+func (r *Registry) RenderedAssets(ctx context.Context) (styles, scripts string) {
+	cache, ok := ctx.Value(renderCacheKey{}).(*renderCache)
+	if !ok {
+		return "", ""
+	}
+
+	cache.mutex.Lock()
+	ids := make([]string, 0, len(cache.rendered))
+	for id := range cache.rendered {
+		ids = append(ids, id)
+	}
+	cache.mutex.Unlock()
+	sort.Strings(ids)
+
+	var css, js []string
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		comp, exists := r.Get(id)
+		if !exists || seen[comp.Name] {
+			continue
+		}
+		seen[comp.Name] = true
+
+		if comp.Styles != "" {
+			css = append(css, comp.Styles)
+		}
+		if comp.Scripts != "" {
+			js = append(js, comp.Scripts)
+		}
+	}
+
+	return strings.Join(css, "\n"), strings.Join(js, "\n")
+}
@@ -1,11 +1,18 @@
 package component
 
 import (
+	"errors"
 	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"plugin"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -29,13 +36,51 @@ func NewAutoRegistration(registry *Registry, idPrefix string) *AutoRegistration
 	}
 }
 
+// RegistrationReport describes the outcome of an auto-registration run
+type RegistrationReport struct {
+	// Components that registered
+	Registered []RegisteredComponent
+
+	// Files or plugins that couldn't be inspected
+	Skipped []SkippedFile
+
+	// Components that were found but failed to register
+	Errors []error
+}
+
+// SkippedFile names a file left out of auto-registration and why
+type SkippedFile struct {
+	Path   string
+	Reason string
+}
+
+// Err returns the registration errors joined, or nil
+func (r *RegistrationReport) Err() error {
+	return errors.Join(r.Errors...)
+}
+
+// String summarizes the report, e.g. "2 registered, 1 skipped, 0 failed"
+func (r *RegistrationReport) String() string {
+	return fmt.Sprintf("%d registered, %d skipped, %d failed", len(r.Registered), len(r.Skipped), len(r.Errors))
+}
+
+// skip records a file left out of registration
+func (r *RegistrationReport) skip(path string, err error) {
+	r.Skipped = append(r.Skipped, SkippedFile{Path: path, Reason: err.Error()})
+}
+
 // RegisterDirectory registers all components found in a directory
-// It looks for component initialization functions in Go files
-func (a *AutoRegistration) RegisterDirectory(dirPath string) error {
+// It looks for component initialization functions in Go files. Source
+// can't be executed, so each registers a placeholder component; see
+// FindInitializers. The error is non-nil if the directory can't be walked
+// or holds no components; per-component failures are in the report.
+func (a *AutoRegistration) RegisterDirectory(dirPath string) (*RegistrationReport, error) {
+	report := &RegistrationReport{}
+
 	// Get absolute path
 	absPath, err := filepath.Abs(dirPath)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute path: %w", err)
+		return report, fmt.Errorf("failed to get absolute path: %w", err)
 	}
 
 	// Walk through the directory
@@ -54,47 +99,96 @@ func (a *AutoRegistration) RegisterDirectory(dirPath string) error {
 
 			// Find component initializer functions and register them
 			pkg := filepath.Base(filepath.Dir(path))
-			if inits, err := a.findInitializersInFile(path); err == nil {
-				for name, initFn := range inits {
-					id := fmt.Sprintf("%s-%s-%d", a.idPrefix, pkg, componentCount)
-					componentCount++
-
-					comp := initFn(id)
-					if err := a.registry.Register(comp); err != nil {
-						fmt.Printf("Warning: Failed to register component '%s': %v\n", name, err)
-						continue
-					}
-
-					fmt.Printf("Auto-registered component '%s' with ID '%s'\n", name, id)
+			inits, err := FindInitializers(path)
+			if err != nil {
+				report.skip(path, err)
+				return nil
+			}
+
+			for _, initializer := range inits {
+				id := fmt.Sprintf("%s-%s-%d", a.idPrefix, pkg, componentCount)
+				componentCount++
+
+				comp := placeholderInitializer(initializer.Name)(id)
+				if err := a.registry.Register(comp); err != nil {
+					report.Errors = append(report.Errors, fmt.Errorf("failed to register component %s (%s): %w", initializer.Name, initializer.Pos, err))
+					continue
 				}
+
+				a.registry.Logger().Debugf("Auto-registered component '%s' (%s) with ID '%s'", initializer.Name, initializer.Pos, id)
+				report.Registered = append(report.Registered, RegisteredComponent{Name: initializer.Name, ID: id})
 			}
 		}
 		return nil
 	})
 
 	if err != nil {
-		return fmt.Errorf("error walking directory: %w", err)
+		return report, fmt.Errorf("error walking directory: %w", err)
 	}
 
 	if componentCount == 0 {
-		return fmt.Errorf("no components found in directory: %s", dirPath)
+		return report, fmt.Errorf("no components found in directory: %s", dirPath)
 	}
 
-	return nil
+	return report, nil
+}
+
+// RegisteredComponent names a component created by auto-registration
+type RegisteredComponent struct {
+	Name string
+	ID   string
+}
+
+// RegisterInitializers creates and registers a component for each named
+// initializer, with IDs of the form {prefix}-{name}
+// Initializers run in name order. The returned pairs cover the components
+// that registered, e.g. for building routes; failures are joined into the
+// error.
+func (a *AutoRegistration) RegisterInitializers(initializers map[string]ComponentInitializer) ([]RegisteredComponent, error) {
+	names := make([]string, 0, len(initializers))
+	for name := range initializers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	registered := make([]RegisteredComponent, 0, len(names))
+	var errs []error
+	for _, name := range names {
+		id := fmt.Sprintf("%s-%s", a.idPrefix, SanitizeID(strings.ToLower(name)))
+
+		comp := initializers[name](id)
+		if comp == nil {
+			errs = append(errs, fmt.Errorf("initializer %s returned no component", name))
+			continue
+		}
+
+		if err := a.registry.Register(comp); err != nil {
+			errs = append(errs, fmt.Errorf("failed to register component %s: %w", name, err))
+			continue
+		}
+
+		a.registry.Logger().Infof("Registered component '%s' with ID '%s'", name, comp.ID)
+		registered = append(registered, RegisteredComponent{Name: name, ID: comp.ID})
+	}
+
+	return registered, errors.Join(errs...)
 }
 
 // RegisterPlugins registers components from Go plugins in a directory
-func (a *AutoRegistration) RegisterPlugins(dirPath string) error {
+// Errors are reported as for RegisterDirectory.
+func (a *AutoRegistration) RegisterPlugins(dirPath string) (*RegistrationReport, error) {
+	report := &RegistrationReport{}
+
 	// Get absolute path
 	absPath, err := filepath.Abs(dirPath)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute path: %w", err)
+		return report, fmt.Errorf("failed to get absolute path: %w", err)
 	}
 
 	// Find plugin files
 	entries, err := os.ReadDir(absPath)
 	if err != nil {
-		return fmt.Errorf("failed to read directory: %w", err)
+		return report, fmt.Errorf("failed to read directory: %w", err)
 	}
 
 	// Process each plugin
@@ -105,20 +199,20 @@ func (a *AutoRegistration) RegisterPlugins(dirPath string) error {
 		}
 
 		pluginPath := filepath.Join(absPath, entry.Name())
-		if err := a.registerPlugin(pluginPath, &componentCount); err != nil {
-			fmt.Printf("Warning: Failed to register plugin '%s': %v\n", entry.Name(), err)
+		if err := a.registerPlugin(pluginPath, &componentCount, report); err != nil {
+			report.skip(pluginPath, err)
 		}
 	}
 
 	if componentCount == 0 {
-		return fmt.Errorf("no components found in plugins: %s", dirPath)
+		return report, fmt.Errorf("no components found in plugins: %s", dirPath)
 	}
 
-	return nil
+	return report, nil
 }
 
 // registerPlugin registers components from a single plugin
-func (a *AutoRegistration) registerPlugin(pluginPath string, count *int) error {
+func (a *AutoRegistration) registerPlugin(pluginPath string, count *int, report *RegistrationReport) error {
 	// Open the plugin
 	p, err := plugin.Open(pluginPath)
 	if err != nil {
@@ -162,68 +256,149 @@ func (a *AutoRegistration) registerPlugin(pluginPath string, count *int) error {
 
 		comp := initFn(id)
 		if err := a.registry.Register(comp); err != nil {
-			fmt.Printf("Warning: Failed to register component '%s' from plugin: %v\n", name, err)
+			report.Errors = append(report.Errors, fmt.Errorf("failed to register component %s from plugin %s: %w", name, filepath.Base(pluginPath), err))
 			continue
 		}
 
-		fmt.Printf("Auto-registered component '%s' with ID '%s' from plugin\n", name, id)
+		a.registry.Logger().Debugf("Auto-registered component '%s' with ID '%s' from plugin", name, id)
+		report.Registered = append(report.Registered, RegisteredComponent{Name: name, ID: id})
 	}
 
 	return nil
 }
 
-// findInitializersInFile uses reflection to find component initializer functions
-// This is a simple implementation that looks for specific package imports and function patterns
-func (a *AutoRegistration) findInitializersInFile(filePath string) (map[string]ComponentInitializer, error) {
-	// In a real implementation, this would use the go/parser package to analyze the file
-	// For this example, we'll use a simpler approach based on common patterns
+// componentImportPath is the import path of this package
+const componentImportPath = "github.com/magooney-loon/webrender/pkg/component"
+
+// Initializer describes a component initializer function found in source
+type Initializer struct {
+	// Function name, e.g. NewCounter
+	Name string
 
-	// Read the file content
-	content, err := os.ReadFile(filePath)
+	// Package name declared by the file
+	Package string
+
+	// Position of the function declaration
+	Pos token.Position
+}
+
+// FindInitializers parses a Go file and returns the exported functions with
+// the signature func(string) *component.Component
+// Files excluded by build constraints for the current platform yield no
+// initializers. The functions are only located, not loaded; calling them
+// requires compiling them into the program or a plugin.
+func FindInitializers(filePath string) ([]Initializer, error) {
+	// Honor //go:build lines and _GOOS/_GOARCH file names
+	match, err := build.Default.MatchFile(filepath.Dir(filePath), filepath.Base(filePath))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
+		return nil, fmt.Errorf("failed to check build constraints: %w", err)
+	}
+	if !match {
+		return nil, nil
 	}
 
-	// Convert to string
-	fileContent := string(content)
-
-	// Check if it imports the component package
-	if !strings.Contains(fileContent, "\"github.com/magooney-loon/webrender/pkg/component\"") {
-		return nil, fmt.Errorf("file does not import the component package")
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filePath, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file: %w", err)
 	}
 
-	// Look for component initializers (functions starting with "New" that return *component.Component)
-	initializers := make(map[string]ComponentInitializer)
+	// Work out how the file refers to this package
+	qualifier, ok := componentQualifier(file)
+	if !ok {
+		return nil, nil
+	}
 
-	// Simple regex-like pattern matching (in a real implementation, use proper parsing)
-	lines := strings.Split(fileContent, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		// Look for function declarations like "func NewXxx(id string) *component.Component {"
-		if strings.HasPrefix(line, "func New") && strings.Contains(line, "(id string)") && strings.Contains(line, "*component.Component") {
-			// Extract function name
-			parts := strings.Split(line, " ")
-			if len(parts) >= 2 {
-				funcName := strings.TrimPrefix(parts[1], "func ")
-				funcName = strings.Split(funcName, "(")[0]
-
-				// Create a simple initializer that calls itself by name
-				// This is a placeholder - in a real implementation, you'd use the go/parser
-				initFn := func(id string) *Component {
-					// In a real implementation, you'd dynamically call the function
-					// For now, return a placeholder component
-					return New(id, "auto-"+funcName, "<div>Auto-generated component</div>")
-				}
+	var initializers []Initializer
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv != nil || fn.Type.TypeParams != nil || !fn.Name.IsExported() {
+			continue
+		}
 
-				initializers[funcName] = initFn
-			}
+		if !isInitializerSignature(fn.Type, qualifier) {
+			continue
 		}
+
+		initializers = append(initializers, Initializer{
+			Name:    fn.Name.Name,
+			Package: file.Name.Name,
+			Pos:     fset.Position(fn.Pos()),
+		})
 	}
 
 	return initializers, nil
 }
 
+// componentQualifier returns the name a file uses for this package, or ""
+// when the file is part of it
+func componentQualifier(file *ast.File) (string, bool) {
+	if file.Name.Name == "component" {
+		return "", true
+	}
+
+	for _, imp := range file.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil || path != componentImportPath {
+			continue
+		}
+
+		switch {
+		case imp.Name == nil:
+			return "component", true
+		case imp.Name.Name == "_":
+			// Blank imports can't name the type
+			return "", false
+		default:
+			return imp.Name.Name, true
+		}
+	}
+
+	return "", false
+}
+
+// isInitializerSignature reports whether a function type is
+// func(string) *Component, qualified as the file refers to this package
+func isInitializerSignature(fnType *ast.FuncType, qualifier string) bool {
+	params := fnType.Params.List
+	if len(params) != 1 || len(params[0].Names) > 1 {
+		return false
+	}
+	if ident, ok := params[0].Type.(*ast.Ident); !ok || ident.Name != "string" {
+		return false
+	}
+
+	if fnType.Results == nil || len(fnType.Results.List) != 1 || len(fnType.Results.List[0].Names) > 1 {
+		return false
+	}
+	star, ok := fnType.Results.List[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+
+	// Unqualified inside this package or with a dot import
+	if qualifier == "" || qualifier == "." {
+		ident, ok := star.X.(*ast.Ident)
+		return ok && ident.Name == "Component"
+	}
+
+	sel, ok := star.X.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "Component" {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == qualifier
+}
+
+// placeholderInitializer stands in for an initializer found in source
+// Source files can't be invoked without compiling them, so the component
+// only records which function it came from.
+func placeholderInitializer(funcName string) ComponentInitializer {
+	return func(id string) *Component {
+		return New(id, "auto-"+funcName, "<div>Auto-generated component</div>")
+	}
+}
+
 // getPluginSymbols returns a list of all exported symbols in a plugin
 // This is a helper for plugin inspection
 func getPluginSymbols(p *plugin.Plugin) ([]string, error) {
@@ -237,7 +412,7 @@ func getPluginSymbols(p *plugin.Plugin) ([]string, error) {
 // RegisterComponent is a convenience function that creates an auto-registration
 // instance and registers a component
 func AutoRegisterComponent(registry *Registry, initFn ComponentInitializer, name string) error {
-	id := fmt.Sprintf("auto-%s", strings.ToLower(name))
+	id := fmt.Sprintf("auto-%s", SanitizeID(strings.ToLower(name)))
 	component := initFn(id)
 	return registry.Register(component)
 }
@@ -0,0 +1,188 @@
+package component
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+const fixtureDir = "testdata/initializers"
+
+// initializerNames returns the names and lines of the initializers in a
+// fixture file
+func initializerNames(t *testing.T, file string) map[string]int {
+	t.Helper()
+
+	inits, err := FindInitializers(filepath.Join(fixtureDir, file))
+	if err != nil {
+		t.Fatalf("FindInitializers(%s): %v", file, err)
+	}
+	found := make(map[string]int, len(inits))
+	for _, initializer := range inits {
+		if initializer.Package != "widgets" {
+			t.Errorf("%s: package = %q, want widgets", initializer.Name, initializer.Package)
+		}
+		found[initializer.Name] = initializer.Pos.Line
+	}
+	return found
+}
+
+func TestFindInitializers(t *testing.T) {
+	tests := []struct {
+		file string
+		want map[string]int
+	}{
+		// Only exported, non-generic functions of func(string) *component.Component,
+		// including multi-line signatures
+		{"widgets.go", map[string]int{"NewCard": 9, "NewTable": 13}},
+		{"aliased.go", map[string]int{"NewAliased": 5}},
+		// Excluded by its build constraint
+		{"excluded.go", map[string]int{}},
+		// Returns a different Component type
+		{"unrelated.go", map[string]int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			if got := initializerNames(t, tt.file); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("found %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindInitializersReportsParseErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.go")
+	if err := os.WriteFile(path, []byte("package widgets\n\nfunc NewBroken(id string {"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FindInitializers(path); err == nil {
+		t.Error("FindInitializers parsed a broken file")
+	}
+}
+
+func TestRegisterDirectoryUsesFixtures(t *testing.T) {
+	r := newTestRegistry(nil)
+	report, err := NewAutoRegistration(r, "fx").RegisterDirectory(fixtureDir)
+	if err != nil {
+		t.Fatalf("RegisterDirectory: %v", err)
+	}
+
+	var names []string
+	for _, registered := range report.Registered {
+		names = append(names, registered.Name)
+		if _, ok := r.Get(registered.ID); !ok {
+			t.Errorf("%s reported as %s but not registered", registered.Name, registered.ID)
+		}
+	}
+	sort.Strings(names)
+	if want := []string{"NewAliased", "NewCard", "NewTable"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("registered %v, want %v", names, want)
+	}
+	if len(report.Skipped) != 0 || len(report.Errors) != 0 {
+		t.Errorf("report = %s, want nothing skipped or failed", report)
+	}
+}
+
+func TestRegisterInitializers(t *testing.T) {
+	r := newTestRegistry(nil)
+	initializer := func(name string) ComponentInitializer {
+		return func(id string) *Component {
+			return New(id, name, "<div></div>")
+		}
+	}
+
+	registered, err := NewAutoRegistration(r, "app").RegisterInitializers(map[string]ComponentInitializer{
+		"Counter":   initializer("counter"),
+		"Todo List": initializer("todo"),
+		"Clock":     initializer("clock"),
+	})
+	if err != nil {
+		t.Fatalf("RegisterInitializers: %v", err)
+	}
+
+	want := []RegisteredComponent{
+		{Name: "Clock", ID: "app-clock"},
+		{Name: "Counter", ID: "app-counter"},
+		{Name: "Todo List", ID: "app-" + SanitizeID("todo list")},
+	}
+	if !reflect.DeepEqual(registered, want) {
+		t.Fatalf("registered %+v, want %+v", registered, want)
+	}
+
+	seen := make(map[string]bool)
+	for _, rc := range registered {
+		if seen[rc.ID] {
+			t.Errorf("duplicate ID %s", rc.ID)
+		}
+		seen[rc.ID] = true
+		if _, ok := r.Get(rc.ID); !ok {
+			t.Errorf("%s (%s) is not in the registry", rc.Name, rc.ID)
+		}
+	}
+}
+
+func TestRegisterInitializersReportsFailures(t *testing.T) {
+	r := newTestRegistry(nil)
+	registered, err := NewAutoRegistration(r, "app").RegisterInitializers(map[string]ComponentInitializer{
+		"Good":  func(id string) *Component { return New(id, "good", "<div></div>") },
+		"Nil":   func(id string) *Component { return nil },
+		"Empty": func(id string) *Component { return New(id, "empty", "") },
+	})
+	if err == nil {
+		t.Fatal("RegisterInitializers returned no error for failing initializers")
+	}
+	if len(registered) != 1 || registered[0].Name != "Good" {
+		t.Errorf("registered %+v, want only Good", registered)
+	}
+}
+
+func TestRegisterDirectoryReportsMixedResults(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "widgets")
+	files := map[string]string{
+		"a_card.go":   "package widgets\n\nimport \"github.com/magooney-loon/webrender/pkg/component\"\n\nfunc NewCard(id string) *component.Component { return nil }\n",
+		"b_broken.go": "package widgets\n\nfunc NewBroken(id string {",
+		"c_table.go":  "package widgets\n\nimport \"github.com/magooney-loon/webrender/pkg/component\"\n\nfunc NewTable(id string) *component.Component { return nil }\n",
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Take the ID NewTable would get so its registration fails
+	r := newTestRegistry(nil)
+	mustRegister(t, r, New("mix-widgets-1", "taken", "<div></div>"))
+
+	report, err := NewAutoRegistration(r, "mix").RegisterDirectory(dir)
+	if err != nil {
+		t.Fatalf("RegisterDirectory: %v", err)
+	}
+
+	if want := []RegisteredComponent{{Name: "NewCard", ID: "mix-widgets-0"}}; !reflect.DeepEqual(report.Registered, want) {
+		t.Errorf("registered %+v, want %+v", report.Registered, want)
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0].Path != filepath.Join(dir, "b_broken.go") || report.Skipped[0].Reason == "" {
+		t.Errorf("skipped %+v, want b_broken.go with a reason", report.Skipped)
+	}
+	if len(report.Errors) != 1 || report.Err() == nil {
+		t.Errorf("errors %v, want the failed NewTable registration", report.Errors)
+	}
+	if got, want := report.String(), "1 registered, 1 skipped, 1 failed"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+}
+
+func TestRegisterDirectoryWithoutComponents(t *testing.T) {
+	report, err := NewAutoRegistration(newTestRegistry(nil), "").RegisterDirectory(t.TempDir())
+	if err == nil {
+		t.Error("RegisterDirectory found components in an empty directory")
+	}
+	if report == nil || len(report.Registered) != 0 {
+		t.Errorf("report = %+v, want an empty report", report)
+	}
+}
@@ -2,10 +2,21 @@ package component
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/magooney-loon/webrender/pkg/logger"
 )
 
 // Manager interface defines methods for component management
@@ -13,6 +24,8 @@ type Manager interface {
 	RegisterComponent(*Component) error
 	RenderComponent(name string, props map[string]interface{}) (string, error)
 	BroadcastStateUpdate(componentID, key string, value interface{}, updateType string) error
+	BroadcastStateBatch(componentID string, changes map[string]interface{}) error
+	BroadcastStateDeletes(componentID string, keys []string) error
 }
 
 // Component represents a reusable UI component with isolated state
@@ -22,6 +35,15 @@ type Component struct {
 	Name     string
 	Template string
 
+	// CSS and JavaScript added to pages that render the component; see
+	// WithAssets
+	Styles  string
+	Scripts string
+
+	// Props the component requires and their kinds, checked before each
+	// render; reflect.Interface accepts any value (nil skips the check)
+	PropSchema map[string]reflect.Kind
+
 	// Internal state and methods
 	State   *State
 	Methods map[string]interface{}
@@ -32,6 +54,53 @@ type Component struct {
 	// Internal references
 	CompiledTmpl *template.Template
 	manager      Manager
+
+	// Optional durable storage for state
+	persistence *statePersistence
+
+	// Optional transform applied to state values sent to clients
+	broadcastTransform func(key string, v interface{}) interface{}
+
+	// Layout for time.Time state values sent to clients (RFC 3339 if empty)
+	timeFormat string
+
+	// Optional paged data source for large lists
+	windowedSource WindowedSource
+
+	// Optional group sharing state keys with other components, swapped
+	// atomically so State.set can read it without holding a lock
+	group atomic.Pointer[StateGroup]
+
+	// Actions that must carry a single-use nonce
+	nonceActions map[string]bool
+
+	// State keys clients may not write with state updates
+	clientReadOnly map[string]bool
+
+	// Run methods one at a time when set
+	serializeActions bool
+	actionMux        sync.Mutex
+
+	// Render from one state snapshot instead of locking per Get
+	snapshotRender bool
+
+	// CSP sources pages need to add when showing the component
+	cspSources map[string][]string
+
+	// Register accepts an empty template when set
+	allowEmptyTemplate bool
+
+	// Templates compiled per catalog locale
+	localized    map[string]*template.Template
+	localizedMux sync.Mutex
+
+	// Reuse the output of renders with the same props and unchanged state;
+	// see renderMemo
+	CacheRenders bool
+	memo         renderMemo
+
+	// Props of the last successful render, reused by BroadcastRender
+	lastProps atomic.Pointer[map[string]interface{}]
 }
 
 // State manages component state with reactivity
@@ -46,6 +115,9 @@ type State struct {
 	// Thread safety
 	mutex sync.RWMutex
 
+	// Incremented on every change to values or computed properties
+	version uint64
+
 	// Reference to parent component
 	component *Component
 }
@@ -61,6 +133,9 @@ type Lifecycle struct {
 	OnDestroy func(c *Component) error
 
 	// State change hooks
+	// OnStateChange runs when a value is set or deleted (newVal is nil),
+	// before the change is applied. Returning an error rejects it; a hook
+	// clamping a value can Set the corrected one first.
 	OnStateChange func(c *Component, key string, oldVal, newVal interface{}) error
 }
 
@@ -78,28 +153,123 @@ func New(id, name, tmpl string) *Component {
 	return c
 }
 
+// log returns the logger of the registry managing the component
+func (c *Component) log() logger.Logger {
+	if r, ok := c.manager.(*Registry); ok {
+		return r.Logger()
+	}
+	return logger.Default()
+}
+
 // SetManager sets the component manager for this component
 func (c *Component) SetManager(manager Manager) {
 	c.manager = manager
 }
 
+// SetBroadcastTransform sets a function that converts state values before
+// they are sent to clients, e.g. to pre-format numbers. It also applies to
+// the state embedded in the rendered HTML (ToJSON, ScriptTag). Server-side
+// reads (Get, GetAll) still see the raw values. Set it before registering.
+func (c *Component) SetBroadcastTransform(transform func(key string, v interface{}) interface{}) {
+	c.broadcastTransform = transform
+}
+
+// SetTimeFormat sets the layout (as for time.Time.Format) used for
+// time.Time state values sent to clients, so components can store real
+// times and still control how clients see them
+// Without it times are sent in RFC 3339. Set it before registering.
+func (c *Component) SetTimeFormat(layout string) {
+	c.timeFormat = layout
+}
+
+// BroadcastValue returns a state value as it should be sent to clients
+// The broadcast transform runs first; times it returns are then formatted
+// with the time format.
+func (c *Component) BroadcastValue(key string, v interface{}) interface{} {
+	if c.broadcastTransform != nil {
+		v = c.broadcastTransform(key, v)
+	}
+
+	if c.timeFormat != "" {
+		switch t := v.(type) {
+		case time.Time:
+			return t.Format(c.timeFormat)
+		case *time.Time:
+			if t != nil {
+				return t.Format(c.timeFormat)
+			}
+		}
+	}
+	return v
+}
+
+// clientValues applies BroadcastValue to every value of a state copy
+func (c *Component) clientValues(values map[string]interface{}) map[string]interface{} {
+	for key, v := range values {
+		values[key] = c.BroadcastValue(key, v)
+	}
+	return values
+}
+
+// BroadcastState returns the component's state as clients see it, with
+// the broadcast transform applied
+func (c *Component) BroadcastState() map[string]interface{} {
+	return c.clientValues(c.State.GetAll())
+}
+
+// HashState returns a short hash identifying a state snapshot
+// Clients send it back on reconnect so an unchanged state isn't replayed.
+// It returns "" when the state can't be serialized.
+func HashState(values map[string]interface{}) string {
+	// encoding/json sorts map keys, so equal states hash equally
+	data, err := json.Marshal(values)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
 // Render renders the component with the given props
 func (c *Component) Render(props map[string]interface{}) (string, error) {
+	return c.RenderLocale(props, "")
+}
+
+// RenderLocale renders the component with the given props, translating
+// {{ t "key" }} with the registry's catalog in locale ("" returns keys)
+func (c *Component) RenderLocale(props map[string]interface{}, locale string) (string, error) {
 	if c.CompiledTmpl == nil {
 		var err error
-		c.CompiledTmpl, err = template.New(c.Name).Parse(c.Template)
+		c.CompiledTmpl, err = c.parseTemplate("")
 		if err != nil {
-			return "", fmt.Errorf("failed to parse component template: %w", err)
+			return "", err
 		}
 	}
 
+	tmpl, err := c.localizedTemplate(locale)
+	if err != nil {
+		return "", err
+	}
+
+	// Fail loudly instead of rendering blanks for bad props
+	if err := c.validateProps(props); err != nil {
+		return "", err
+	}
+
 	// Create template context
+	var state interface{} = c.State
+	if c.snapshotRender {
+		state = c.State.Snapshot()
+	}
 	data := map[string]interface{}{
 		"ID":      c.ID,
-		"State":   c.State,
+		"State":   state,
 		"props":   props,
 		"Methods": c.Methods,
 	}
+	if c.windowedSource != nil {
+		data["Window"] = c.Window
+	}
 
 	// Call lifecycle hook
 	if c.Lifecycle.BeforeRender != nil {
@@ -108,13 +278,25 @@ func (c *Component) Render(props map[string]interface{}) (string, error) {
 		}
 	}
 
-	// Render template
-	var buf bytes.Buffer
-	if err := c.CompiledTmpl.Execute(&buf, data); err != nil {
-		return "", fmt.Errorf("template execution error: %w", err)
+	// Reuse an earlier render when neither props nor state have changed
+	memoKey, version, cacheable := c.memoKey(props, locale)
+	output, cached := "", false
+	if cacheable {
+		output, cached = c.memo.get(memoKey, version)
 	}
 
-	output := buf.String()
+	// Render template
+	if !cached {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("template execution error: %w", err)
+		}
+
+		output = buf.String()
+		if cacheable {
+			c.memo.put(memoKey, version, output)
+		}
+	}
 
 	// Call lifecycle hook
 	if c.Lifecycle.AfterRender != nil {
@@ -123,14 +305,107 @@ func (c *Component) Render(props map[string]interface{}) (string, error) {
 		}
 	}
 
+	c.rememberProps(props)
 	return output, nil
 }
 
+// rememberProps keeps a copy of the props of a successful render
+func (c *Component) rememberProps(props map[string]interface{}) {
+	remembered := make(map[string]interface{}, len(props))
+	for k, v := range props {
+		remembered[k] = v
+	}
+	c.lastProps.Store(&remembered)
+}
+
+// LastProps returns a copy of the props the component was last rendered
+// with successfully, or an empty map if it hasn't been rendered
+func (c *Component) LastProps() map[string]interface{} {
+	props := make(map[string]interface{})
+	if last := c.lastProps.Load(); last != nil {
+		for k, v := range *last {
+			props[k] = v
+		}
+	}
+	return props
+}
+
+// AllowEmptyTemplate lets the component register with an empty template,
+// for placeholders that intentionally render nothing
+func (c *Component) AllowEmptyTemplate() {
+	c.allowEmptyTemplate = true
+}
+
+// RenderFromSnapshot makes Render read state from one snapshot taken up
+// front instead of locking the state on every Get in the template
+// This cuts lock contention for components updated at a high rate, and
+// every value in a render comes from the same moment. Templates can then
+// only call Get, GetAll, ToJSON, and ScriptTag on .State.
+func (c *Component) RenderFromSnapshot(enabled bool) {
+	c.snapshotRender = enabled
+}
+
 // AddMethod adds a method to the component
+// Only func(map[string]interface{}) error can be called as an action;
+// prefer AddTypedMethod, which checks the signature at compile time.
 func (c *Component) AddMethod(name string, method interface{}) {
 	c.Methods[name] = method
 }
 
+// AddTypedMethod adds a method callable as a client action
+func (c *Component) AddTypedMethod(name string, method func(params map[string]interface{}) error) {
+	c.Methods[name] = method
+}
+
+// MethodNames returns the names of the component's methods, sorted
+func (c *Component) MethodNames() []string {
+	names := make([]string, 0, len(c.Methods))
+	for name := range c.Methods {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SerializeActions makes the component's methods run one at a time, so
+// actions from several clients can't interleave their state changes
+// Methods must not call CallMethod on their own component while it is set.
+func (c *Component) SerializeActions(serialize bool) {
+	c.actionMux.Lock()
+	defer c.actionMux.Unlock()
+	c.serializeActions = serialize
+}
+
+// CallMethod invokes a method the way a client action would
+// Failures are returned as a *MethodError.
+func (c *Component) CallMethod(name string, params map[string]interface{}) error {
+	methodVal, exists := c.Methods[name]
+	if !exists {
+		return &MethodError{ComponentID: c.ID, Method: name, Err: ErrMethodNotFound}
+	}
+
+	method, ok := methodVal.(func(map[string]interface{}) error)
+	if !ok {
+		return &MethodError{
+			ComponentID: c.ID,
+			Method:      name,
+			Err:         fmt.Errorf("%w: %T", ErrInvalidMethod, methodVal),
+		}
+	}
+
+	c.actionMux.Lock()
+	if !c.serializeActions {
+		c.actionMux.Unlock()
+	} else {
+		defer c.actionMux.Unlock()
+	}
+
+	if err := method(params); err != nil {
+		return &MethodError{ComponentID: c.ID, Method: name, Err: err}
+	}
+	return nil
+}
+
 // newState creates a new State instance
 func newState(c *Component) *State {
 	return &State{
@@ -143,30 +418,79 @@ func newState(c *Component) *State {
 }
 
 // Set sets a value in the state
+// Keys shared by the component's state group are set on every member.
 func (s *State) Set(key string, value interface{}) {
-	s.mutex.Lock()
-
-	// Get old value and check if it exists
-	oldValue, exists := s.values[key]
+	s.set(key, value, true)
+}
 
-	// Skip update if value hasn't changed (deep equality check)
-	if exists && fmt.Sprintf("%v", oldValue) == fmt.Sprintf("%v", value) {
-		s.mutex.Unlock()
+// set sets a value, optionally propagating it to the component's group
+func (s *State) set(key string, value interface{}, propagate bool) {
+	changes := s.change(map[string]interface{}{key: value}, nil)
+	if len(changes) == 0 {
 		return
 	}
-
-	// Set new value
-	s.values[key] = value
-	s.mutex.Unlock()
+	oldValue := changes[0].oldValue
 
 	// Notify watchers
 	s.notifyWatchers(key, oldValue, value)
 
+	// Persist the change if the component is durable
+	if s.component != nil {
+		s.component.schedulePersist()
+	}
+
 	// Broadcast state change if component has a manager
 	if s.component != nil && s.component.manager != nil {
-		err := s.component.manager.BroadcastStateUpdate(s.component.ID, key, value, "update")
+		err := s.component.manager.BroadcastStateUpdate(s.component.ID, key, s.component.BroadcastValue(key, value), "update")
 		if err != nil {
-			fmt.Printf("Error broadcasting state update: %v\n", err)
+			s.component.log().Errorf("Error broadcasting state update: %v", err)
+		}
+	}
+
+	// Share the change with the rest of the group
+	if propagate && s.component != nil {
+		if group := s.component.group.Load(); group != nil {
+			group.propagate(s.component, key, value)
+		}
+	}
+}
+
+// SetBatch sets several values and broadcasts them as one update
+// Unchanged values are skipped; watchers still fire once per changed key.
+func (s *State) SetBatch(values map[string]interface{}) {
+	// Rejected keys are left out individually; the rest still apply
+	changes := s.change(values, nil)
+	if len(changes) == 0 {
+		return
+	}
+
+	// Notify watchers
+	for _, c := range changes {
+		s.notifyWatchers(c.key, c.oldValue, c.newValue)
+	}
+
+	if s.component == nil {
+		return
+	}
+
+	// Persist the changes if the component is durable
+	s.component.schedulePersist()
+
+	// Broadcast all changes in a single message
+	if s.component.manager != nil {
+		broadcast := make(map[string]interface{}, len(changes))
+		for _, c := range changes {
+			broadcast[c.key] = s.component.BroadcastValue(c.key, c.newValue)
+		}
+		if err := s.component.manager.BroadcastStateBatch(s.component.ID, broadcast); err != nil {
+			s.component.log().Errorf("Error broadcasting state batch: %v", err)
+		}
+	}
+
+	// Share the changes with the rest of the group
+	if group := s.component.group.Load(); group != nil {
+		for _, c := range changes {
+			group.propagate(s.component, c.key, c.newValue)
 		}
 	}
 }
@@ -189,6 +513,139 @@ func (s *State) Get(key string) interface{} {
 	return nil
 }
 
+// GetString returns a state value as a string
+// Strings, byte slices, and fmt.Stringers are accepted.
+func (s *State) GetString(key string) (string, bool) {
+	switch v := s.Get(key).(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	case fmt.Stringer:
+		return v.String(), true
+	}
+	return "", false
+}
+
+// GetInt returns a state value as an int
+// Integer types, whole floats (as decoded from JSON), and numeric strings
+// are accepted; values that would overflow are not.
+func (s *State) GetInt(key string) (int, bool) {
+	switch v := s.Get(key).(type) {
+	case int:
+		return v, true
+	case int8:
+		return int(v), true
+	case int16:
+		return int(v), true
+	case int32:
+		return int(v), true
+	case int64:
+		return int64ToInt(v)
+	case uint:
+		return uint64ToInt(uint64(v))
+	case uint8:
+		return int(v), true
+	case uint16:
+		return int(v), true
+	case uint32:
+		return uint64ToInt(uint64(v))
+	case uint64:
+		return uint64ToInt(v)
+	case float32:
+		return floatToInt(float64(v))
+	case float64:
+		return floatToInt(v)
+	case json.Number:
+		return parseInt(string(v))
+	case string:
+		return parseInt(v)
+	}
+	return 0, false
+}
+
+// GetFloat returns a state value as a float64
+// Numeric types and numeric strings are accepted.
+func (s *State) GetFloat(key string) (float64, bool) {
+	switch v := s.Get(key).(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// GetBool returns a state value as a bool
+// Bools and strings accepted by strconv.ParseBool ("true", "1", ...) are
+// accepted.
+func (s *State) GetBool(key string) (bool, bool) {
+	switch v := s.Get(key).(type) {
+	case bool:
+		return v, true
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		return b, err == nil
+	}
+	return false, false
+}
+
+// parseInt parses a decimal integer string into an int
+func parseInt(s string) (int, bool) {
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, strconv.IntSize)
+	return int(n), err == nil
+}
+
+// int64ToInt converts an int64, failing if it doesn't fit in an int
+func int64ToInt(v int64) (int, bool) {
+	if v < math.MinInt || v > math.MaxInt {
+		return 0, false
+	}
+	return int(v), true
+}
+
+// uint64ToInt converts a uint64, failing if it doesn't fit in an int
+func uint64ToInt(v uint64) (int, bool) {
+	if v > math.MaxInt {
+		return 0, false
+	}
+	return int(v), true
+}
+
+// floatToInt converts a whole float, failing on fractions and overflow
+func floatToInt(v float64) (int, bool) {
+	if v != math.Trunc(v) || v < math.MinInt || v >= math.MaxInt {
+		return 0, false
+	}
+	return int(v), true
+}
+
 // GetAll returns a map of all state values
 func (s *State) GetAll() map[string]interface{} {
 	s.mutex.RLock()
@@ -208,18 +665,147 @@ func (s *State) GetAll() map[string]interface{} {
 	return result
 }
 
-// Delete removes a state key
-func (s *State) Delete(key string) {
+// stateChange is one key's proposed or applied change
+type stateChange struct {
+	key      string
+	oldValue interface{}
+	newValue interface{}
+	existed  bool
+	deleted  bool
+}
+
+// change sets values and deletes keys once the component's OnStateChange
+// hook accepts them, returning the changes applied
+// The hook sees each proposed value before any reader can, and rejected
+// changes are never applied. A change is dropped if its key moved on while
+// the hook ran, because the hook set it itself or another writer got there
+// first; the newer value stands.
+func (s *State) change(values map[string]interface{}, deletes []string) []stateChange {
+	var hook func(*Component, string, interface{}, interface{}) error
+	if s.component != nil {
+		hook = s.component.Lifecycle.OnStateChange
+	}
+
 	s.mutex.Lock()
-	oldVal, exists := s.values[key]
-	if exists {
-		delete(s.values, key)
+	changes := s.proposeLocked(values, deletes)
+	if hook == nil || len(changes) == 0 {
+		defer s.mutex.Unlock()
+		return s.applyLocked(changes)
 	}
 	s.mutex.Unlock()
 
-	if exists {
+	accepted := changes[:0]
+	for _, c := range changes {
+		if err := hook(s.component, c.key, c.oldValue, c.newValue); err != nil {
+			s.component.log().Warnf("State change of %s on component %s rejected: %v", c.key, s.component.ID, err)
+			continue
+		}
+		accepted = append(accepted, c)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.applyLocked(accepted)
+}
+
+// proposeLocked lists the changes setting values and deleting keys would
+// make, skipping unchanged values and keys that aren't set; the caller
+// holds mutex
+func (s *State) proposeLocked(values map[string]interface{}, deletes []string) []stateChange {
+	changes := make([]stateChange, 0, len(values)+len(deletes))
+	for key, value := range values {
+		oldValue, exists := s.values[key]
+		if exists && fmt.Sprintf("%v", oldValue) == fmt.Sprintf("%v", value) {
+			continue
+		}
+		changes = append(changes, stateChange{key: key, oldValue: oldValue, newValue: value, existed: exists})
+	}
+
+	seen := make(map[string]bool, len(deletes))
+	for _, key := range deletes {
+		oldValue, exists := s.values[key]
+		if !exists || seen[key] {
+			continue
+		}
+		seen[key] = true
+		changes = append(changes, stateChange{key: key, oldValue: oldValue, existed: true, deleted: true})
+	}
+	return changes
+}
+
+// applyLocked applies the changes whose keys still hold the value they
+// were proposed against, returning those applied; the caller holds mutex
+func (s *State) applyLocked(changes []stateChange) []stateChange {
+	applied := changes[:0]
+	for _, c := range changes {
+		current, exists := s.values[c.key]
+		if exists != c.existed || (exists && fmt.Sprintf("%v", current) != fmt.Sprintf("%v", c.oldValue)) {
+			continue
+		}
+		if c.deleted {
+			delete(s.values, c.key)
+		} else {
+			s.values[c.key] = c.newValue
+		}
+		applied = append(applied, c)
+	}
+	if len(applied) > 0 {
+		s.version++
+	}
+	return applied
+}
+
+// Keys returns the names of the state values and computed properties,
+// sorted, without evaluating the computed ones
+func (s *State) Keys() []string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	keys := make([]string, 0, len(s.values)+len(s.computed))
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+	for k := range s.computed {
+		if _, exists := s.values[k]; !exists {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Restore sets state values without notifying watchers or broadcasting
+// Keys not present in values keep their current value.
+func (s *State) Restore(values map[string]interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for k, v := range values {
+		s.values[k] = v
+	}
+	s.version++
+}
+
+// snapshot returns a copy of the stored values, excluding computed properties
+func (s *State) snapshot() map[string]interface{} {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := make(map[string]interface{}, len(s.values))
+	for k, v := range s.values {
+		result[k] = v
+	}
+	return result
+}
+
+// Delete removes a state key
+func (s *State) Delete(key string) {
+	if changes := s.change(nil, []string{key}); len(changes) > 0 {
 		// Notify watchers
-		s.notifyWatchers(key, oldVal, nil)
+		s.notifyWatchers(key, changes[0].oldValue, nil)
+
+		// Persist the change if the component is durable
+		s.component.schedulePersist()
 
 		// Broadcast state change if component is managed
 		if s.component.manager != nil {
@@ -228,6 +814,35 @@ func (s *State) Delete(key string) {
 	}
 }
 
+// DeleteBatch removes several state keys at once
+// Watchers run for each removed key, and clients get a single message
+// instead of one per key. Keys that aren't set are ignored.
+func (s *State) DeleteBatch(keys ...string) {
+	removed := s.change(nil, keys)
+	if len(removed) == 0 {
+		return
+	}
+
+	// Notify watchers
+	for _, r := range removed {
+		s.notifyWatchers(r.key, r.oldValue, nil)
+	}
+
+	// Persist the change if the component is durable
+	s.component.schedulePersist()
+
+	// Broadcast all deletions in a single message
+	if s.component.manager != nil {
+		deleted := make([]string, len(removed))
+		for i, r := range removed {
+			deleted[i] = r.key
+		}
+		if err := s.component.manager.BroadcastStateDeletes(s.component.ID, deleted); err != nil {
+			s.component.log().Errorf("Error broadcasting state deletes: %v", err)
+		}
+	}
+}
+
 // Watch adds a watcher for state changes
 func (s *State) Watch(key string, fn func(oldVal, newVal interface{})) {
 	s.mutex.Lock()
@@ -245,6 +860,28 @@ func (s *State) Compute(key string, fn func() interface{}) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	s.computed[key] = fn
+	s.version++
+}
+
+// Recompute evaluates a computed property and broadcasts the fresh value
+// It returns false if no computed property is registered for key.
+func (s *State) Recompute(key string) (interface{}, bool) {
+	s.mutex.RLock()
+	fn, exists := s.computed[key]
+	s.mutex.RUnlock()
+
+	if !exists {
+		return nil, false
+	}
+
+	value := fn()
+
+	// Broadcast state change if component is managed
+	if s.component.manager != nil {
+		s.component.manager.BroadcastStateUpdate(s.component.ID, key, s.component.BroadcastValue(key, value), "compute")
+	}
+
+	return value, true
 }
 
 // notifyWatchers calls all watchers for a key
@@ -265,10 +902,75 @@ func (s *State) notifyWatchers(key string, oldVal, newVal interface{}) {
 
 // ToJSON returns the state as a JSON attribute
 func (s *State) ToJSON() template.HTMLAttr {
-	data := s.GetAll()
-	jsonData, err := json.Marshal(data)
+	return stateJSONAttr(s.component.BroadcastState())
+}
+
+// ScriptTag returns the state as a <script type="application/json"> block
+// with the ID "{component ID}-state". Use it in templates instead of a
+// data-state attribute to avoid attribute escaping for nested values;
+// the client reads and updates whichever of the two is present.
+func (s *State) ScriptTag() template.HTML {
+	return stateScriptTag(s.component.ID, s.component.BroadcastState())
+}
+
+// Snapshot returns a read-only copy of the state, including computed
+// properties, taken under a single lock
+func (s *State) Snapshot() *StateSnapshot {
+	return &StateSnapshot{
+		component: s.component,
+		values:    s.GetAll(),
+	}
+}
+
+// StateSnapshot is a read-only copy of a component's state
+// It offers the State methods templates use, without locking.
+type StateSnapshot struct {
+	component *Component
+	values    map[string]interface{}
+}
+
+// Get retrieves a value from the snapshot
+func (s *StateSnapshot) Get(key string) interface{} {
+	return s.values[key]
+}
+
+// GetAll returns a copy of all values in the snapshot
+func (s *StateSnapshot) GetAll() map[string]interface{} {
+	result := make(map[string]interface{}, len(s.values))
+	for k, v := range s.values {
+		result[k] = v
+	}
+	return result
+}
+
+// ToJSON returns the snapshot as JSON for a data-state attribute
+func (s *StateSnapshot) ToJSON() template.HTMLAttr {
+	return stateJSONAttr(s.component.clientValues(s.GetAll()))
+}
+
+// ScriptTag returns the snapshot as a state <script> block; see State.ScriptTag
+func (s *StateSnapshot) ScriptTag() template.HTML {
+	return stateScriptTag(s.component.ID, s.component.clientValues(s.GetAll()))
+}
+
+// stateJSONAttr encodes state values for a data-state attribute
+func stateJSONAttr(values map[string]interface{}) template.HTMLAttr {
+	jsonData, err := json.Marshal(values)
 	if err != nil {
 		return template.HTMLAttr("{}")
 	}
 	return template.HTMLAttr(string(jsonData))
 }
+
+// stateScriptTag encodes state values as a <script type="application/json">
+// block with the ID "{component ID}-state"
+func stateScriptTag(componentID string, values map[string]interface{}) template.HTML {
+	jsonData, err := json.Marshal(values)
+	if err != nil {
+		jsonData = []byte("{}")
+	}
+
+	// json.Marshal escapes <, > and & so the payload can't close the tag
+	return template.HTML(fmt.Sprintf(`<script type="application/json" id="%s-state">%s</script>`,
+		template.HTMLEscapeString(componentID), jsonData))
+}
@@ -0,0 +1,167 @@
+package component
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stateScriptPattern finds a state <script> block and its element ID
+var stateScriptPattern = regexp.MustCompile(`<script type="application/json" id="([^"]+)">(.*?)</script>`)
+
+func TestStateScriptTagHoldsParseableState(t *testing.T) {
+	r := newTestRegistry(nil)
+	c := New("feed-1", "feed", `<div id="{{.ID}}">{{.State.ScriptTag}}</div>`)
+	c.State.Set("title", `Tricky </script><b>"quoted"</b>`)
+	c.State.Set("recentEvents", []interface{}{
+		map[string]interface{}{"type": "login", "count": 2},
+	})
+	mustRegister(t, r, c)
+
+	html, err := r.RenderComponent("feed-1", nil)
+	if err != nil {
+		t.Fatalf("RenderComponent: %v", err)
+	}
+
+	matches := stateScriptPattern.FindAllStringSubmatch(html, -1)
+	if len(matches) != 1 {
+		t.Fatalf("found %d state script blocks in %s, want 1", len(matches), html)
+	}
+	if id := matches[0][1]; id != "feed-1-state" {
+		t.Errorf("state script ID = %q, want %q", id, "feed-1-state")
+	}
+	if strings.Contains(html, `data-state=`) {
+		t.Error("rendered a data-state attribute alongside the script block")
+	}
+
+	var state map[string]interface{}
+	if err := json.Unmarshal([]byte(matches[0][2]), &state); err != nil {
+		t.Fatalf("state block is not valid JSON: %v\n%s", err, matches[0][2])
+	}
+	if got := state["title"]; got != `Tricky </script><b>"quoted"</b>` {
+		t.Errorf("title = %q, want the original string", got)
+	}
+	want := []interface{}{map[string]interface{}{"type": "login", "count": float64(2)}}
+	if got := state["recentEvents"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("recentEvents = %v, want %v", got, want)
+	}
+}
+
+func TestStateToJSONRemainsDefault(t *testing.T) {
+	r := newTestRegistry(nil)
+	c := New("count-1", "count", `<div id="{{.ID}}" data-state="{{.State.ToJSON}}"></div>`)
+	c.State.Set("count", 3)
+	mustRegister(t, r, c)
+
+	html, err := r.RenderComponent("count-1", nil)
+	if err != nil {
+		t.Fatalf("RenderComponent: %v", err)
+	}
+	if !strings.Contains(html, `data-state="{&#34;count&#34;:3}"`) {
+		t.Errorf("rendered %s, want the state in data-state", html)
+	}
+}
+
+func TestCallMethodReturnsMethodErrors(t *testing.T) {
+	c := New("form-1", "form", `<div></div>`)
+	failure := errors.New("email is required")
+	c.AddTypedMethod("submit", func(map[string]interface{}) error { return failure })
+	c.AddTypedMethod("ok", func(map[string]interface{}) error { return nil })
+	c.Methods["legacy"] = func() {}
+
+	tests := []struct {
+		method string
+		want   error
+	}{
+		{"reset", ErrMethodNotFound},
+		{"legacy", ErrInvalidMethod},
+		{"submit", failure},
+	}
+	for _, tt := range tests {
+		err := c.CallMethod(tt.method, nil)
+
+		var methodErr *MethodError
+		if !errors.As(err, &methodErr) {
+			t.Errorf("CallMethod(%s) = %v, want a *MethodError", tt.method, err)
+			continue
+		}
+		if methodErr.ComponentID != "form-1" || methodErr.Method != tt.method {
+			t.Errorf("CallMethod(%s) error names %s/%s", tt.method, methodErr.ComponentID, methodErr.Method)
+		}
+		if !errors.Is(err, tt.want) {
+			t.Errorf("CallMethod(%s) = %v, want it to wrap %v", tt.method, err, tt.want)
+		}
+	}
+
+	if err := c.CallMethod("ok", nil); err != nil {
+		t.Errorf("CallMethod(ok) = %v", err)
+	}
+}
+
+func TestTimeFormatAppliesToClientState(t *testing.T) {
+	updated := time.Date(2024, 3, 9, 14, 5, 0, 0, time.UTC)
+
+	b := &recordingBroadcaster{}
+	r := newTestRegistry(b)
+	c := New("clock-1", "clock", `<div id="{{.ID}}">{{.State.ScriptTag}}</div>`)
+	c.SetTimeFormat("2006-01-02 15:04")
+	mustRegister(t, r, c)
+
+	c.State.Set("lastUpdated", updated)
+
+	// Server-side reads keep the time.Time
+	if got, ok := c.State.Get("lastUpdated").(time.Time); !ok || !got.Equal(updated) {
+		t.Errorf("Get = %v, want the original time", c.State.Get("lastUpdated"))
+	}
+
+	b.mutex.Lock()
+	var broadcast interface{}
+	for _, update := range b.updates {
+		if update.key == "lastUpdated" {
+			broadcast = update.value
+		}
+	}
+	b.mutex.Unlock()
+	if broadcast != "2024-03-09 14:05" {
+		t.Errorf("broadcast value = %v, want %q", broadcast, "2024-03-09 14:05")
+	}
+
+	html, err := r.RenderComponent("clock-1", nil)
+	if err != nil {
+		t.Fatalf("RenderComponent: %v", err)
+	}
+	matches := stateScriptPattern.FindStringSubmatch(html)
+	if matches == nil {
+		t.Fatalf("no state block in %s", html)
+	}
+	var state map[string]interface{}
+	if err := json.Unmarshal([]byte(matches[2]), &state); err != nil {
+		t.Fatal(err)
+	}
+	if got := state["lastUpdated"]; got != "2024-03-09 14:05" {
+		t.Errorf("rendered lastUpdated = %v, want %q", got, "2024-03-09 14:05")
+	}
+}
+
+func TestTimeFormatDefaultsToRFC3339(t *testing.T) {
+	updated := time.Date(2024, 3, 9, 14, 5, 0, 0, time.UTC)
+	c := New("clock-1", "clock", `<div></div>`)
+
+	data, err := json.Marshal(c.BroadcastValue("lastUpdated", updated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `"2024-03-09T14:05:00Z"`; got != want {
+		t.Errorf("default serialization = %s, want %s", got, want)
+	}
+
+	// Pointers are formatted too
+	c.SetTimeFormat(time.Kitchen)
+	if got := c.BroadcastValue("lastUpdated", &updated); got != "2:05PM" {
+		t.Errorf("*time.Time broadcast as %v, want 2:05PM", got)
+	}
+}
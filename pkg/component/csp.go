@@ -0,0 +1,113 @@
+package component
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// cspDirectivePattern matches CSP directive names such as img-src
+var cspDirectivePattern = regexp.MustCompile(`^[a-z]+(-[a-z]+)*$`)
+
+// CSPSources declares the Content-Security-Policy sources the component
+// needs, e.g. {"img-src": {"https://tiles.example.com"}}
+// Pages rendering the component add them to their CSP header. Wildcards
+// and 'unsafe-*' keywords are rejected so a component can't switch off
+// the policy. Call it while setting up the component, before it is
+// registered.
+func (c *Component) CSPSources(sources map[string][]string) error {
+	declared := make(map[string][]string, len(sources))
+	for directive, list := range sources {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		if !cspDirectivePattern.MatchString(directive) {
+			return fmt.Errorf("invalid CSP directive %q", directive)
+		}
+
+		for _, source := range list {
+			if err := validateCSPSource(source); err != nil {
+				return fmt.Errorf("invalid CSP source for %s: %w", directive, err)
+			}
+			declared[directive] = append(declared[directive], source)
+		}
+	}
+
+	c.cspSources = declared
+	return nil
+}
+
+// RequiredCSPSources returns the CSP sources declared with CSPSources
+func (c *Component) RequiredCSPSources() map[string][]string {
+	sources := make(map[string][]string, len(c.cspSources))
+	for directive, list := range c.cspSources {
+		sources[directive] = append([]string(nil), list...)
+	}
+	return sources
+}
+
+// validateCSPSource rejects sources that would weaken the policy or
+// break out of the directive
+func validateCSPSource(source string) error {
+	if source == "" || strings.ContainsAny(source, " \t\r\n;,") {
+		return fmt.Errorf("malformed source %q", source)
+	}
+
+	lower := strings.ToLower(source)
+	switch {
+	case source == "*", strings.HasPrefix(lower, "'unsafe-"):
+		return fmt.Errorf("source %s is not allowed", source)
+	case lower == "'none'":
+		return fmt.Errorf("source 'none' can't be combined with other sources")
+	}
+	return nil
+}
+
+// RenderedCSPSources merges the CSP sources of the components rendered
+// with RenderComponentContext under ctx
+// It returns nil without a render cache in ctx.
+func (r *Registry) RenderedCSPSources(ctx context.Context) map[string][]string {
+	cache, ok := ctx.Value(renderCacheKey{}).(*renderCache)
+	if !ok {
+		return nil
+	}
+
+	cache.mutex.Lock()
+	ids := make([]string, 0, len(cache.rendered))
+	for id := range cache.rendered {
+		ids = append(ids, id)
+	}
+	cache.mutex.Unlock()
+	sort.Strings(ids)
+
+	var merged map[string][]string
+	for _, id := range ids {
+		comp, exists := r.Get(id)
+		if !exists {
+			continue
+		}
+
+		for directive, list := range comp.cspSources {
+			if merged == nil {
+				merged = make(map[string][]string)
+			}
+			for _, source := range list {
+				if !containsString(merged[directive], source) {
+					merged[directive] = append(merged[directive], source)
+				}
+			}
+		}
+	}
+
+	return merged
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
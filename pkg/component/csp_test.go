@@ -0,0 +1,67 @@
+package component
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestCSPSourcesRejectsWeakeningSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources map[string][]string
+	}{
+		{"wildcard", map[string][]string{"img-src": {"*"}}},
+		{"unsafe-inline", map[string][]string{"script-src": {"'unsafe-inline'"}}},
+		{"unsafe-eval", map[string][]string{"script-src": {"'UNSAFE-EVAL'"}}},
+		{"none", map[string][]string{"img-src": {"'none'"}}},
+		{"directive injection", map[string][]string{"img-src": {"https://a.example.com; script-src *"}}},
+		{"bad directive", map[string][]string{"img src": {"https://a.example.com"}}},
+		{"empty source", map[string][]string{"img-src": {""}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New("map-1", "map", `<div></div>`)
+			if err := c.CSPSources(tt.sources); err == nil {
+				t.Errorf("CSPSources(%v) succeeded, want an error", tt.sources)
+			}
+			if len(c.RequiredCSPSources()) != 0 {
+				t.Error("rejected sources were kept")
+			}
+		})
+	}
+}
+
+func TestRenderedCSPSourcesCoverRenderedComponents(t *testing.T) {
+	r := newTestRegistry(nil)
+	for _, spec := range []struct {
+		id      string
+		sources map[string][]string
+	}{
+		{"map-1", map[string][]string{"img-src": {"https://tiles.example.com"}}},
+		{"map-2", map[string][]string{"IMG-SRC": {"https://tiles.example.com", "https://cdn.example.com"}}},
+		{"video-1", map[string][]string{"media-src": {"https://video.example.com"}}},
+	} {
+		c := New(spec.id, "embed", `<div></div>`)
+		if err := c.CSPSources(spec.sources); err != nil {
+			t.Fatalf("CSPSources(%s): %v", spec.id, err)
+		}
+		mustRegister(t, r, c)
+	}
+
+	if sources := r.RenderedCSPSources(context.Background()); sources != nil {
+		t.Errorf("sources without a render cache = %v, want nil", sources)
+	}
+
+	ctx := WithRenderCache(context.Background())
+	for _, id := range []string{"map-1", "map-2"} {
+		if _, err := r.RenderComponentContext(ctx, id, nil); err != nil {
+			t.Fatalf("rendering %s: %v", id, err)
+		}
+	}
+
+	want := map[string][]string{"img-src": {"https://tiles.example.com", "https://cdn.example.com"}}
+	if got := r.RenderedCSPSources(ctx); !reflect.DeepEqual(got, want) {
+		t.Errorf("rendered sources = %v, want %v", got, want)
+	}
+}
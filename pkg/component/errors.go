@@ -0,0 +1,45 @@
+package component
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrMethodNotFound is returned when a component has no such method
+	ErrMethodNotFound = errors.New("method not found")
+
+	// ErrInvalidMethod is returned when a method doesn't have the
+	// func(map[string]interface{}) error signature
+	ErrInvalidMethod = errors.New("invalid method type")
+
+	// ErrEmptyTemplate is returned when registering a component without a
+	// template; see Component.AllowEmptyTemplate
+	ErrEmptyTemplate = errors.New("component template is empty")
+
+	// ErrMissingProp is returned when rendering without a prop listed in
+	// the component's PropSchema
+	ErrMissingProp = errors.New("missing prop")
+
+	// ErrPropKind is returned when a prop doesn't have the kind listed in
+	// the component's PropSchema
+	ErrPropKind = errors.New("prop has wrong kind")
+)
+
+// MethodError reports a component method that couldn't be called or
+// returned an error
+type MethodError struct {
+	ComponentID string
+	Method      string
+	Err         error
+}
+
+// Error implements the error interface
+func (e *MethodError) Error() string {
+	return fmt.Sprintf("method %s on component %s: %v", e.Method, e.ComponentID, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *MethodError) Unwrap() error {
+	return e.Err
+}
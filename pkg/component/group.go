@@ -0,0 +1,106 @@
+package component
+
+import "sync"
+
+// StateGroup shares state keys between related components
+// Setting a shared key on any member (or on the group) sets it on every
+// member, and each member broadcasts the change for itself.
+type StateGroup struct {
+	members []*Component
+	shared  map[string]bool
+	values  map[string]interface{}
+	mutex   sync.RWMutex
+}
+
+// NewStateGroup creates an empty state group
+// Components join it with JoinGroup; keys are shared with Share.
+func (r *Registry) NewStateGroup() *StateGroup {
+	return &StateGroup{
+		shared: make(map[string]bool),
+		values: make(map[string]interface{}),
+	}
+}
+
+// Share marks keys as shared by all members of the group
+func (g *StateGroup) Share(keys ...string) *StateGroup {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for _, key := range keys {
+		g.shared[key] = true
+	}
+	return g
+}
+
+// Set sets a shared key on every member
+func (g *StateGroup) Set(key string, value interface{}) {
+	g.Share(key)
+	g.propagate(nil, key, value)
+}
+
+// Members returns the components in the group
+func (g *StateGroup) Members() []*Component {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	members := make([]*Component, len(g.members))
+	copy(members, g.members)
+	return members
+}
+
+// JoinGroup adds the component to a state group, leaving any previous one
+// Shared values already set on the group are copied into its state.
+func (c *Component) JoinGroup(g *StateGroup) {
+	g.mutex.Lock()
+	g.members = append(g.members, c)
+	values := make(map[string]interface{}, len(g.values))
+	for k, v := range g.values {
+		values[k] = v
+	}
+	g.mutex.Unlock()
+
+	if previous := c.group.Swap(g); previous != nil {
+		// Rejoining the same group drops the duplicate membership
+		previous.remove(c)
+	}
+	c.State.Restore(values)
+}
+
+// LeaveGroup removes the component from its state group, if any
+func (c *Component) LeaveGroup() {
+	if g := c.group.Swap(nil); g != nil {
+		g.remove(c)
+	}
+}
+
+// remove drops a component from the group's members
+func (g *StateGroup) remove(c *Component) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for i, member := range g.members {
+		if member == c {
+			g.members = append(g.members[:i], g.members[i+1:]...)
+			return
+		}
+	}
+}
+
+// propagate sets a shared key on every member except origin
+func (g *StateGroup) propagate(origin *Component, key string, value interface{}) {
+	g.mutex.Lock()
+	if !g.shared[key] {
+		g.mutex.Unlock()
+		return
+	}
+	g.values[key] = value
+	members := make([]*Component, len(g.members))
+	copy(members, g.members)
+	g.mutex.Unlock()
+
+	for _, member := range members {
+		if member != origin {
+			member.State.set(key, value, false)
+		}
+	}
+}
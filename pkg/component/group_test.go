@@ -0,0 +1,129 @@
+package component
+
+import (
+	"sort"
+	"sync"
+	"testing"
+)
+
+// broadcastsOf returns the IDs of components that broadcast key, sorted
+func broadcastsOf(b *recordingBroadcaster, key string) []string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	var ids []string
+	for _, update := range b.updates {
+		if update.key == key {
+			ids = append(ids, update.componentID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestStateGroupSharesKeys(t *testing.T) {
+	b := &recordingBroadcaster{}
+	r := newTestRegistry(b)
+
+	group := r.NewStateGroup().Share("range")
+	panels := []*Component{
+		New("panel-a", "panel", `<div></div>`),
+		New("panel-b", "panel", `<div></div>`),
+	}
+	for _, panel := range panels {
+		mustRegister(t, r, panel)
+		panel.JoinGroup(group)
+	}
+	outsider := New("panel-c", "panel", `<div></div>`)
+	mustRegister(t, r, outsider)
+
+	panels[0].State.Set("range", "7d")
+
+	for _, panel := range panels {
+		if got := panel.State.Get("range"); got != "7d" {
+			t.Errorf("%s range = %v, want 7d", panel.ID, got)
+		}
+	}
+	if got := outsider.State.Get("range"); got != nil {
+		t.Errorf("non-member range = %v, want unset", got)
+	}
+	if got := broadcastsOf(b, "range"); len(got) != 2 || got[0] != "panel-a" || got[1] != "panel-b" {
+		t.Errorf("range broadcast by %v, want once by each member", got)
+	}
+
+	// Keys that aren't shared stay with the component
+	panels[1].State.Set("zoom", 2)
+	if got := panels[0].State.Get("zoom"); got != nil {
+		t.Errorf("unshared key reached another member: %v", got)
+	}
+	if got := broadcastsOf(b, "zoom"); len(got) != 1 || got[0] != "panel-b" {
+		t.Errorf("zoom broadcast by %v, want panel-b only", got)
+	}
+}
+
+func TestStateGroupSetAndJoin(t *testing.T) {
+	b := &recordingBroadcaster{}
+	r := newTestRegistry(b)
+	group := r.NewStateGroup()
+
+	first := New("panel-a", "panel", `<div></div>`)
+	mustRegister(t, r, first)
+	first.JoinGroup(group)
+
+	// Setting on the group shares the key
+	group.Set("theme", "dark")
+	if got := first.State.Get("theme"); got != "dark" {
+		t.Errorf("member theme = %v, want dark", got)
+	}
+
+	// Late joiners pick up shared values
+	late := New("panel-b", "panel", `<div></div>`)
+	mustRegister(t, r, late)
+	late.JoinGroup(group)
+	if got := late.State.Get("theme"); got != "dark" {
+		t.Errorf("late member theme = %v, want dark", got)
+	}
+
+	// Members that left no longer receive shared values
+	late.LeaveGroup()
+	group.Set("theme", "light")
+	if got := late.State.Get("theme"); got != "dark" {
+		t.Errorf("former member theme = %v, want it left at dark", got)
+	}
+	if len(group.Members()) != 1 {
+		t.Errorf("group has %d members, want 1", len(group.Members()))
+	}
+}
+
+func TestGroupMembershipChangesDuringSets(t *testing.T) {
+	r := newTestRegistry(&recordingBroadcaster{})
+
+	group := r.NewStateGroup().Share("range")
+	panel := New("panel-a", "panel", `<div></div>`)
+	mustRegister(t, r, panel)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			panel.JoinGroup(group)
+			panel.LeaveGroup()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			panel.State.Set("range", i)
+			panel.State.SetBatch(map[string]interface{}{"range": i})
+		}
+	}()
+	wg.Wait()
+
+	// Rejoining the same group leaves a single membership
+	panel.JoinGroup(group)
+	panel.JoinGroup(group)
+	if got := len(group.Members()); got != 1 {
+		t.Errorf("group has %d members after rejoining, want 1", got)
+	}
+}
@@ -0,0 +1,236 @@
+package component
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Catalog supplies localized messages for the t template function
+type Catalog interface {
+	// Message returns the text for key in locale, if the catalog has it
+	Message(locale, key string) (string, bool)
+
+	// Locales lists the locales the catalog has messages for
+	Locales() []string
+}
+
+// MapCatalog is a Catalog backed by messages keyed by locale, then key,
+// e.g. {"en": {"greeting": "Hello"}, "fr": {"greeting": "Bonjour"}}
+type MapCatalog map[string]map[string]string
+
+// Message returns the text for key in locale
+func (c MapCatalog) Message(locale, key string) (string, bool) {
+	msg, ok := c[locale][key]
+	return msg, ok
+}
+
+// Locales returns the catalog's locales, sorted
+func (c MapCatalog) Locales() []string {
+	locales := make([]string, 0, len(c))
+	for locale := range c {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// localeKey is the context key for the preferred render locales
+type localeKey struct{}
+
+// WithLocale returns a context in which RenderComponentContext renders
+// in the first of locales the registry's catalog supports
+func WithLocale(ctx context.Context, locales ...string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locales)
+}
+
+// LocalesFromContext returns the preferred locales set with WithLocale
+func LocalesFromContext(ctx context.Context) []string {
+	locales, _ := ctx.Value(localeKey{}).([]string)
+	return locales
+}
+
+// ParseAcceptLanguage returns the languages of an Accept-Language header,
+// most preferred first, e.g. "fr-CH, fr;q=0.9, en;q=0.8" gives
+// [fr-CH fr en]. Wildcards and languages with q=0 are left out.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var langs []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+
+		langs = append(langs, weighted{tag: tag, q: q})
+	}
+
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].q > langs[j].q
+	})
+
+	tags := make([]string, len(langs))
+	for i, lang := range langs {
+		tags[i] = lang.tag
+	}
+	return tags
+}
+
+// matchLocale returns the supported locale best matching the preferences,
+// trying each exactly and then by its base language (fr-CH matches fr), or
+// "" when none match
+func matchLocale(preferred, supported []string) string {
+	for _, want := range preferred {
+		base, _, _ := strings.Cut(want, "-")
+		for _, candidate := range []string{want, base} {
+			for _, locale := range supported {
+				if strings.EqualFold(locale, candidate) {
+					return locale
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// SetCatalog sets the messages the t template function looks up; nil
+// makes t return its key
+func (r *Registry) SetCatalog(catalog Catalog) {
+	r.catalogMux.Lock()
+	defer r.catalogMux.Unlock()
+	r.catalog = catalog
+}
+
+// Catalog returns the registry's message catalog, or nil
+func (r *Registry) Catalog() Catalog {
+	r.catalogMux.RLock()
+	defer r.catalogMux.RUnlock()
+	return r.catalog
+}
+
+// resolveLocale picks the catalog locale to render in for a context
+func (r *Registry) resolveLocale(ctx context.Context) string {
+	preferred := LocalesFromContext(ctx)
+	catalog := r.Catalog()
+	if len(preferred) == 0 || catalog == nil {
+		return ""
+	}
+	return matchLocale(preferred, catalog.Locales())
+}
+
+// templateFuncs returns the template functions for rendering in locale
+// {{ t "key" }} looks the key up in the registry's catalog, falling back to
+// the key itself; extra arguments are formatted into a found message as
+// with fmt.Sprintf.
+func (c *Component) templateFuncs(locale string) template.FuncMap {
+	return template.FuncMap{
+		"t": func(key string, args ...interface{}) string {
+			msg, ok := c.translate(locale, key)
+			if !ok {
+				return key
+			}
+			if len(args) > 0 {
+				return fmt.Sprintf(msg, args...)
+			}
+			return msg
+		},
+	}
+}
+
+// translate returns the message for key in locale
+func (c *Component) translate(locale, key string) (string, bool) {
+	r, ok := c.manager.(*Registry)
+	if !ok || locale == "" {
+		return "", false
+	}
+
+	catalog := r.Catalog()
+	if catalog == nil {
+		return "", false
+	}
+	return catalog.Message(locale, key)
+}
+
+// parseTemplate compiles the component template for a locale
+func (c *Component) parseTemplate(locale string) (*template.Template, error) {
+	tmpl, err := template.New(c.Name).Funcs(c.templateFuncs(locale)).Parse(c.Template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse component template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// localizedTemplate returns the template compiled for a locale
+// Templates are compiled once per locale; the default locale and
+// components given a CompiledTmpl without source use CompiledTmpl.
+func (c *Component) localizedTemplate(locale string) (*template.Template, error) {
+	if locale == "" || c.Template == "" {
+		return c.CompiledTmpl, nil
+	}
+
+	c.localizedMux.Lock()
+	defer c.localizedMux.Unlock()
+
+	if tmpl, ok := c.localized[locale]; ok {
+		return tmpl, nil
+	}
+
+	tmpl, err := c.parseTemplate(locale)
+	if err != nil {
+		return nil, err
+	}
+	if c.localized == nil {
+		c.localized = make(map[string]*template.Template)
+	}
+	c.localized[locale] = tmpl
+	return tmpl, nil
+}
+
+// compile parses the component template for the default locale and each
+// of locales, replacing any compiled templates
+// Components given only a CompiledTmpl have nothing to compile.
+func (c *Component) compile(locales []string) error {
+	if c.Template == "" {
+		return nil
+	}
+
+	base, err := c.parseTemplate("")
+	if err != nil {
+		return err
+	}
+
+	localized := make(map[string]*template.Template, len(locales))
+	for _, locale := range locales {
+		tmpl, err := c.parseTemplate(locale)
+		if err != nil {
+			return err
+		}
+		localized[locale] = tmpl
+	}
+
+	c.CompiledTmpl = base
+	c.localizedMux.Lock()
+	c.localized = localized
+	c.localizedMux.Unlock()
+	return nil
+}
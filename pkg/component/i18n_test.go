@@ -0,0 +1,76 @@
+package component
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+var greetings = MapCatalog{
+	"en": {"greeting": "Hello", "items": "%d items"},
+	"fr": {"greeting": "Bonjour", "items": "%d articles"},
+}
+
+// newGreeter registers a component using the t template function
+func newGreeter(t *testing.T, catalog Catalog) *Registry {
+	t.Helper()
+
+	r := newTestRegistry(nil)
+	r.SetCatalog(catalog)
+	mustRegister(t, r, New("greeter-1", "greeter", `<p>{{t "greeting"}}, {{t "items" 3}}</p>`))
+	return r
+}
+
+func TestRenderUnderLocales(t *testing.T) {
+	r := newGreeter(t, greetings)
+
+	tests := []struct {
+		locales []string
+		want    string
+	}{
+		{[]string{"en"}, "<p>Hello, 3 items</p>"},
+		{[]string{"fr"}, "<p>Bonjour, 3 articles</p>"},
+		{[]string{"fr-CH", "en"}, "<p>Bonjour, 3 articles</p>"},
+		{[]string{"de", "en"}, "<p>Hello, 3 items</p>"},
+		// Unsupported locales fall back to the keys
+		{[]string{"de"}, "<p>greeting, items</p>"},
+		{nil, "<p>greeting, items</p>"},
+	}
+	for _, tt := range tests {
+		ctx := WithLocale(context.Background(), tt.locales...)
+		got, err := r.RenderComponentContext(ctx, "greeter-1", nil)
+		if err != nil {
+			t.Fatalf("render in %v: %v", tt.locales, err)
+		}
+		if got != tt.want {
+			t.Errorf("render in %v = %q, want %q", tt.locales, got, tt.want)
+		}
+	}
+}
+
+func TestRenderWithoutCatalogPassesKeysThrough(t *testing.T) {
+	r := newGreeter(t, nil)
+
+	got, err := r.RenderComponentContext(WithLocale(context.Background(), "fr"), "greeter-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<p>greeting, items</p>"; got != want {
+		t.Errorf("render = %q, want %q", got, want)
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := map[string][]string{
+		"fr-CH, fr;q=0.9, en;q=0.8, *;q=0.5": {"fr-CH", "fr", "en"},
+		"en;q=0.5, de":                       {"de", "en"},
+		"en;q=0, fr":                         {"fr"},
+		"en;q=bad, fr":                       {"fr"},
+		"":                                   {},
+	}
+	for header, want := range tests {
+		if got := ParseAcceptLanguage(header); !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseAcceptLanguage(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package component
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRegistryListDescribesComponents(t *testing.T) {
+	r := newTestRegistry(nil)
+	if r.Count() != 0 {
+		t.Fatalf("empty registry Count = %d", r.Count())
+	}
+
+	computed := false
+	cart := New("cart-1", "cart", `<div></div>`)
+	cart.State.SetBatch(map[string]interface{}{"items": 2, "currency": "EUR"})
+	cart.State.Compute("total", func() interface{} {
+		computed = true
+		return 30
+	})
+	cart.AddTypedMethod("checkout", func(map[string]interface{}) error { return nil })
+	cart.AddTypedMethod("add", func(map[string]interface{}) error { return nil })
+	mustRegister(t, r, cart)
+
+	menu := New("menu-1", "menu", `<nav></nav>`)
+	mustRegister(t, r, menu)
+
+	if r.Count() != 2 {
+		t.Errorf("Count = %d, want 2", r.Count())
+	}
+
+	want := []ComponentInfo{
+		{ID: "cart-1", Name: "cart", StateKeys: []string{"currency", "items", "total"}, MethodNames: []string{"add", "checkout"}},
+		{ID: "menu-1", Name: "menu", StateKeys: []string{}, MethodNames: []string{}},
+	}
+	if got := r.List(); !reflect.DeepEqual(got, want) {
+		t.Errorf("List = %+v, want %+v", got, want)
+	}
+	if computed {
+		t.Error("List evaluated a computed property")
+	}
+}
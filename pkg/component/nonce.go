@@ -0,0 +1,19 @@
+package component
+
+// RequireNonce marks actions as sensitive: clients must send a fresh,
+// recently timestamped nonce with each call, and a replayed or stale nonce
+// is rejected. Call it while setting up the component, before it is
+// registered.
+func (c *Component) RequireNonce(actions ...string) {
+	if c.nonceActions == nil {
+		c.nonceActions = make(map[string]bool, len(actions))
+	}
+	for _, action := range actions {
+		c.nonceActions[action] = true
+	}
+}
+
+// RequiresNonce reports whether an action must carry a single-use nonce
+func (c *Component) RequiresNonce(action string) bool {
+	return c.nonceActions[action]
+}
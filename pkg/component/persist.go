@@ -0,0 +1,127 @@
+package component
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// persistDebounce is how long state must be quiet before it is saved
+const persistDebounce = 500 * time.Millisecond
+
+// persistMaxWait bounds how long a change waits for state to go quiet, so
+// a component that changes constantly is still saved
+const persistMaxWait = 5 * time.Second
+
+// StateStore is a durable key-value store for component state
+// Load returns nil data and a nil error when the key does not exist.
+type StateStore interface {
+	Load(key string) ([]byte, error)
+	Save(key string, data []byte) error
+}
+
+// statePersistence tracks where and when a component's state is saved
+type statePersistence struct {
+	store StateStore
+	key   string
+
+	debounce time.Duration
+	maxWait  time.Duration
+
+	// Pending save, and when its oldest unsaved change was made
+	timer        *time.Timer
+	pendingSince time.Time
+	mutex        sync.Mutex
+}
+
+// PersistTo saves the component's state to store under key whenever it
+// changes (debounced, but at least every few seconds while changes keep
+// coming), and restores it when the component is registered.
+// Restored values come back as their JSON types, e.g. numbers as float64.
+func (c *Component) PersistTo(store StateStore, key string) {
+	c.persistence = &statePersistence{
+		store:    store,
+		key:      key,
+		debounce: persistDebounce,
+		maxWait:  persistMaxWait,
+	}
+}
+
+// restoreState loads persisted state into the component without broadcasting
+func (c *Component) restoreState() {
+	if c.persistence == nil {
+		return
+	}
+
+	data, err := c.persistence.store.Load(c.persistence.key)
+	if err != nil {
+		c.log().Warnf("Failed to load persisted state for component '%s': %v", c.ID, err)
+		return
+	}
+	if data == nil {
+		return
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		c.log().Warnf("Failed to decode persisted state for component '%s': %v", c.ID, err)
+		return
+	}
+
+	c.State.Restore(values)
+}
+
+// schedulePersist saves the component's state once changes settle, or
+// once the oldest unsaved change has waited maxWait
+func (c *Component) schedulePersist() {
+	p := c.persistence
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	if p.timer == nil || !p.timer.Stop() {
+		// Nothing was waiting to be saved; this change starts the wait
+		p.pendingSince = now
+	}
+
+	delay := p.debounce
+	if remaining := p.maxWait - now.Sub(p.pendingSince); remaining < delay {
+		delay = remaining
+	}
+	p.timer = time.AfterFunc(delay, c.saveState)
+}
+
+// flushPersist saves any pending state change immediately
+func (c *Component) flushPersist() {
+	p := c.persistence
+	if p == nil {
+		return
+	}
+
+	p.mutex.Lock()
+	pending := p.timer != nil && p.timer.Stop()
+	p.mutex.Unlock()
+
+	if pending {
+		c.saveState()
+	}
+}
+
+// saveState writes the component's current state to its store
+func (c *Component) saveState() {
+	p := c.persistence
+
+	data, err := json.Marshal(c.State.snapshot())
+	if err != nil {
+		c.log().Warnf("Failed to encode state for component '%s': %v", c.ID, err)
+		return
+	}
+
+	if err := p.store.Save(p.key, data); err != nil {
+		c.log().Warnf("Failed to persist state for component '%s': %v", c.ID, err)
+	}
+}
@@ -0,0 +1,128 @@
+package component
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore is a StateStore kept in memory; Load waits on gate if set
+type memoryStore struct {
+	data  map[string][]byte
+	saves int
+	gate  chan struct{}
+	mutex sync.Mutex
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{data: make(map[string][]byte)}
+}
+
+func (s *memoryStore) Load(key string) ([]byte, error) {
+	if s.gate != nil {
+		<-s.gate
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.data[key], nil
+}
+
+func (s *memoryStore) Save(key string, data []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.data[key] = data
+	s.saves++
+	return nil
+}
+
+// saved decodes the state saved under key
+func (s *memoryStore) saved(t *testing.T, key string) (map[string]interface{}, int) {
+	t.Helper()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var values map[string]interface{}
+	if data := s.data[key]; data != nil {
+		if err := json.Unmarshal(data, &values); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return values, s.saves
+}
+
+func TestPersistSavesBusyComponentsWithinMaxWait(t *testing.T) {
+	store := newMemoryStore()
+	c := New("ticker-1", "ticker", `<span></span>`)
+	c.PersistTo(store, "ticker")
+	c.persistence.debounce = 40 * time.Millisecond
+	c.persistence.maxWait = 100 * time.Millisecond
+
+	// Changes arrive faster than the debounce, so it never goes quiet
+	deadline := time.Now().Add(350 * time.Millisecond)
+	for i := 0; time.Now().Before(deadline); i++ {
+		c.State.Set("tick", i)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, saves := store.saved(t, "ticker"); saves < 2 {
+		t.Errorf("saved %d times while changes kept coming, want at least 2", saves)
+	}
+}
+
+func TestRemoveSavesChangesMadeByOnDestroy(t *testing.T) {
+	store := newMemoryStore()
+	r := newTestRegistry(&recordingBroadcaster{})
+	c := New("editor-1", "editor", `<div></div>`)
+	c.PersistTo(store, "editor")
+	c.Lifecycle.OnDestroy = func(c *Component) error {
+		c.State.Set("closed", true)
+		return nil
+	}
+	mustRegister(t, r, c)
+	c.State.Set("draft", "hello")
+
+	if err := r.Remove("editor-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	values, _ := store.saved(t, "editor")
+	if values["draft"] != "hello" || values["closed"] != true {
+		t.Errorf("saved %v, want the draft and the change OnDestroy made", values)
+	}
+}
+
+func TestRegisterRestoresStateOutsideTheRegistryLock(t *testing.T) {
+	store := newMemoryStore()
+	store.data["slow"] = []byte(`{"n":1}`)
+	store.gate = make(chan struct{})
+
+	r := newTestRegistry(&recordingBroadcaster{})
+	mustRegister(t, r, New("other-1", "other", `<div></div>`))
+
+	c := New("slow-1", "slow", `<div></div>`)
+	c.PersistTo(store, "slow")
+	registered := make(chan error, 1)
+	go func() { registered <- r.Register(c) }()
+
+	// Lookups carry on while the store is loading
+	looked := make(chan bool, 1)
+	go func() {
+		_, ok := r.Get("other-1")
+		looked <- ok
+	}()
+	select {
+	case <-looked:
+	case <-time.After(time.Second):
+		t.Fatal("registry lookup waited for the state store")
+	}
+
+	close(store.gate)
+	if err := <-registered; err != nil {
+		t.Fatal(err)
+	}
+	if got := c.State.Get("n"); got != float64(1) {
+		t.Errorf("restored n = %v, want 1", got)
+	}
+}
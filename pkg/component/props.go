@@ -0,0 +1,46 @@
+package component
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// validateProps checks props against the component's PropSchema
+// Every prop in the schema is required. A value matches when its kind is
+// the listed one, or for reflect.Interface when it is present at all, so
+// template.HTML passes as reflect.String and structs as reflect.Struct.
+// All problems are reported together, wrapping ErrMissingProp or
+// ErrPropKind.
+func (c *Component) validateProps(props map[string]interface{}) error {
+	if len(c.PropSchema) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(c.PropSchema))
+	for name := range c.PropSchema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		want := c.PropSchema[name]
+
+		value, ok := props[name]
+		if !ok || value == nil {
+			errs = append(errs, fmt.Errorf("prop %q: %w", name, ErrMissingProp))
+			continue
+		}
+
+		if got := reflect.ValueOf(value).Kind(); want != reflect.Interface && got != want {
+			errs = append(errs, fmt.Errorf("prop %q is %s (%T), want %s: %w", name, got, value, want, ErrPropKind))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("component %s: invalid props: %w", c.ID, errors.Join(errs...))
+	}
+	return nil
+}
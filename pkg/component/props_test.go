@@ -0,0 +1,80 @@
+package component
+
+import (
+	"errors"
+	"html/template"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newCard returns a component that requires a string title and int count
+func newCard() *Component {
+	c := New("card-1", "card", `<h2>{{.props.title}}</h2><p>{{.props.count}}</p>`)
+	c.PropSchema = map[string]reflect.Kind{
+		"title": reflect.String,
+		"count": reflect.Int,
+	}
+	return c
+}
+
+func TestPropSchemaRendersValidProps(t *testing.T) {
+	html, err := newCard().Render(map[string]interface{}{
+		"title": template.HTML("<em>Sale</em>"),
+		"count": 3,
+	})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if !strings.Contains(html, "<h2><em>Sale</em></h2><p>3</p>") {
+		t.Errorf("rendered %q", html)
+	}
+}
+
+func TestPropSchemaRejectsInvalidProps(t *testing.T) {
+	tests := []struct {
+		name    string
+		props   map[string]interface{}
+		wantErr error
+		wantMsg string
+	}{
+		{"missing prop", map[string]interface{}{"title": "Sale"}, ErrMissingProp, `prop "count"`},
+		{"nil prop", map[string]interface{}{"title": "Sale", "count": nil}, ErrMissingProp, `prop "count"`},
+		{"wrong kind", map[string]interface{}{"title": "Sale", "count": "3"}, ErrPropKind, `prop "count" is string`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := newCard().Render(tt.props)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Render error = %v, want %v", err, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("error %q does not mention %s", err, tt.wantMsg)
+			}
+			if html != "" {
+				t.Errorf("rendered %q despite invalid props", html)
+			}
+		})
+	}
+}
+
+func TestPropSchemaReportsEveryProblem(t *testing.T) {
+	_, err := newCard().Render(map[string]interface{}{"title": 42})
+
+	if !errors.Is(err, ErrMissingProp) || !errors.Is(err, ErrPropKind) {
+		t.Errorf("error = %v, want both a missing prop and a wrong kind", err)
+	}
+}
+
+func TestPropSchemaInterfaceAcceptsAnyValue(t *testing.T) {
+	c := New("list-1", "list", `<p>{{len .props.items}}</p>`)
+	c.PropSchema = map[string]reflect.Kind{"items": reflect.Interface}
+
+	if _, err := c.Render(map[string]interface{}{"items": []string{"a", "b"}}); err != nil {
+		t.Errorf("Render: %v", err)
+	}
+	if _, err := c.Render(nil); !errors.Is(err, ErrMissingProp) {
+		t.Errorf("Render without items: %v, want ErrMissingProp", err)
+	}
+}
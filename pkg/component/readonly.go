@@ -0,0 +1,19 @@
+package component
+
+// ClientReadOnly marks state keys only the server may change: clients'
+// state_update messages setting or deleting them are rejected, while
+// methods and server code still write them as usual. Call it while
+// setting up the component, before it is registered.
+func (c *Component) ClientReadOnly(keys ...string) {
+	if c.clientReadOnly == nil {
+		c.clientReadOnly = make(map[string]bool, len(keys))
+	}
+	for _, key := range keys {
+		c.clientReadOnly[key] = true
+	}
+}
+
+// IsClientReadOnly reports whether clients are barred from writing a key
+func (c *Component) IsClientReadOnly(key string) bool {
+	return c.clientReadOnly[key]
+}
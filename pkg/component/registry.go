@@ -1,19 +1,66 @@
 package component
 
 import (
+	"errors"
 	"fmt"
 	"html/template"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/magooney-loon/webrender/pkg/logger"
 )
 
+// validIDPattern matches IDs safe for HTML id attributes and getElementById
+var validIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// invalidIDChars matches characters not allowed in component IDs
+var invalidIDChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// ValidateID checks that a component ID only contains letters, digits,
+// dashes, and underscores
+func ValidateID(id string) error {
+	if !validIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid component ID %q: only letters, digits, '-' and '_' are allowed", id)
+	}
+	return nil
+}
+
+// SanitizeID replaces runs of characters not allowed in component IDs with a dash
+func SanitizeID(id string) string {
+	return invalidIDChars.ReplaceAllString(id, "-")
+}
+
 // Registry manages a collection of components
 type Registry struct {
 	// Component storage
 	components   map[string]*Component
 	componentMux sync.RWMutex
 
+	// Runtime feature flags (components absent from the map are enabled)
+	// Guarded separately so lifecycle hooks that broadcast don't deadlock.
+	disabled    map[string]bool
+	disabledMux sync.RWMutex
+
 	// State broadcaster interface
 	broadcaster StateBroadcaster
+
+	// Lifecycle metrics
+	stats registryStats
+
+	// Annotate rendered HTML with component boundaries (development only)
+	debug    bool
+	debugMux sync.RWMutex
+
+	// Destination for registration and component warnings
+	logger    logger.Logger
+	loggerMux sync.RWMutex
+
+	// Messages for the t template function
+	catalog    Catalog
+	catalogMux sync.RWMutex
 }
 
 // StateBroadcaster defines an interface for broadcasting state updates
@@ -21,20 +68,65 @@ type StateBroadcaster interface {
 	BroadcastStateUpdate(componentID, key string, value interface{}, updateType string) error
 }
 
+// RenderBroadcaster is implemented by broadcasters that can push a full
+// component re-render to clients
+type RenderBroadcaster interface {
+	BroadcastRender(componentID, html string) error
+}
+
+// BatchBroadcaster is implemented by broadcasters that can send several
+// state changes of one component in a single message
+type BatchBroadcaster interface {
+	BroadcastStateBatch(componentID string, changes map[string]interface{}) error
+}
+
+// BatchDeleteBroadcaster is implemented by broadcasters that can send the
+// deletion of several state keys of one component in a single message
+type BatchDeleteBroadcaster interface {
+	BroadcastStateDeletes(componentID string, keys []string) error
+}
+
+// RemovalBroadcaster is implemented by broadcasters that can tell clients
+// a component was removed
+type RemovalBroadcaster interface {
+	BroadcastComponentRemoved(componentID string) error
+}
+
 // NewRegistry creates a new component registry
 func NewRegistry(broadcaster StateBroadcaster) *Registry {
 	return &Registry{
 		components:  make(map[string]*Component),
+		disabled:    make(map[string]bool),
 		broadcaster: broadcaster,
+		logger:      logger.Default(),
 	}
 }
 
 // Register adds a component to the registry
 func (r *Registry) Register(c *Component) error {
+	// IDs end up in id attributes and client lookups
+	if err := ValidateID(c.ID); err != nil {
+		return err
+	}
+
+	// An empty template renders nothing, which is almost always a mistake
+	if c.CompiledTmpl == nil && strings.TrimSpace(c.Template) == "" && !c.allowEmptyTemplate {
+		return fmt.Errorf("component %s: %w", c.ID, ErrEmptyTemplate)
+	}
+
+	// Check for duplicate before touching the component
+	if _, exists := r.Get(c.ID); exists {
+		return fmt.Errorf("component with ID %s already registered", c.ID)
+	}
+
+	// Restore persisted state before the component goes live; the store
+	// may be slow, so this happens outside the registry lock
+	c.restoreState()
+
 	r.componentMux.Lock()
 	defer r.componentMux.Unlock()
 
-	// Check for duplicate
+	// Check again in case another registration won the race
 	if _, exists := r.components[c.ID]; exists {
 		return fmt.Errorf("component with ID %s already registered", c.ID)
 	}
@@ -45,9 +137,9 @@ func (r *Registry) Register(c *Component) error {
 	// Parse template if not already parsed
 	if c.CompiledTmpl == nil {
 		var err error
-		c.CompiledTmpl, err = template.New(c.Name).Parse(c.Template)
+		c.CompiledTmpl, err = c.parseTemplate("")
 		if err != nil {
-			return fmt.Errorf("failed to parse component template: %w", err)
+			return err
 		}
 	}
 
@@ -56,9 +148,14 @@ func (r *Registry) Register(c *Component) error {
 
 	// Call OnMount lifecycle hook if present
 	if c.Lifecycle.OnMount != nil {
-		if err := c.Lifecycle.OnMount(c); err != nil {
+		start := time.Now()
+		err := c.Lifecycle.OnMount(c)
+		r.stats.recordMount(time.Since(start), true)
+		if err != nil {
 			return fmt.Errorf("OnMount hook error: %w", err)
 		}
+	} else {
+		r.stats.recordMount(0, false)
 	}
 
 	return nil
@@ -75,22 +172,52 @@ func (r *Registry) Get(id string) (*Component, bool) {
 
 // Remove removes a component from the registry
 func (r *Registry) Remove(id string) error {
+	comp, err := r.remove(id)
+	if err != nil {
+		return err
+	}
+
+	// Save pending state last, so changes OnDestroy made are kept, and
+	// outside the registry lock since the store may be slow
+	comp.flushPersist()
+	return nil
+}
+
+// remove runs the component's OnDestroy hook and drops it from the registry
+func (r *Registry) remove(id string) (*Component, error) {
 	r.componentMux.Lock()
 	defer r.componentMux.Unlock()
 
 	comp, exists := r.components[id]
 	if !exists {
-		return fmt.Errorf("component with ID %s not found", id)
+		return nil, fmt.Errorf("component with ID %s not found", id)
 	}
 
 	// Call OnDestroy lifecycle hook if present
 	if comp.Lifecycle.OnDestroy != nil {
 		if err := comp.Lifecycle.OnDestroy(comp); err != nil {
-			return fmt.Errorf("OnDestroy hook error: %w", err)
+			return nil, fmt.Errorf("OnDestroy hook error: %w", err)
 		}
 	}
 
+	comp.LeaveGroup()
 	delete(r.components, id)
+	r.stats.recordDestroy()
+	return comp, nil
+}
+
+// Unregister removes a component at runtime and tells clients to drop it
+// OnDestroy runs before removal, as with Remove.
+func (r *Registry) Unregister(id string) error {
+	if err := r.Remove(id); err != nil {
+		return err
+	}
+
+	if b, ok := r.broadcaster.(RemovalBroadcaster); ok {
+		if err := b.BroadcastComponentRemoved(id); err != nil {
+			return fmt.Errorf("component %s removed but clients were not notified: %w", id, err)
+		}
+	}
 	return nil
 }
 
@@ -99,27 +226,197 @@ func (r *Registry) RegisterComponent(c *Component) error {
 	return r.Register(c)
 }
 
+// SetEnabled toggles a component on or off at runtime
+// Disabled components render an empty placeholder and their state updates
+// are not broadcast. Re-enabling a component pushes a fresh render to
+// clients, so they catch up on changes made while it was disabled. IDs may
+// be disabled before the component is registered.
+func (r *Registry) SetEnabled(id string, enabled bool) {
+	r.disabledMux.Lock()
+	wasDisabled := r.disabled[id]
+	if enabled {
+		delete(r.disabled, id)
+	} else {
+		r.disabled[id] = true
+	}
+	r.disabledMux.Unlock()
+
+	if !enabled || !wasDisabled {
+		return
+	}
+	if _, registered := r.Get(id); !registered {
+		return
+	}
+	if _, ok := r.broadcaster.(RenderBroadcaster); !ok {
+		return
+	}
+	if err := r.BroadcastRender(id); err != nil {
+		r.Logger().Warnf("Failed to push re-enabled component %s: %v", id, err)
+	}
+}
+
+// IsEnabled reports whether a component is enabled
+func (r *Registry) IsEnabled(id string) bool {
+	r.disabledMux.RLock()
+	defer r.disabledMux.RUnlock()
+
+	return !r.disabled[id]
+}
+
 // RenderComponent renders a component with props
 func (r *Registry) RenderComponent(id string, props map[string]interface{}) (string, error) {
+	return r.renderComponent(id, props, "")
+}
+
+// renderComponent renders a component in a catalog locale
+func (r *Registry) renderComponent(id string, props map[string]interface{}, locale string) (string, error) {
 	r.componentMux.RLock()
 	comp, exists := r.components[id]
 	r.componentMux.RUnlock()
+	disabled := !r.IsEnabled(id)
 
 	if !exists {
 		return "", fmt.Errorf("component with ID %s not found", id)
 	}
 
-	return comp.Render(props)
+	// Keep the element ID in the page so the component can be found again
+	// once it is re-enabled
+	if disabled {
+		return fmt.Sprintf(`<div id="%s" data-component-disabled="true"></div>`, template.HTMLEscapeString(id)), nil
+	}
+
+	start := time.Now()
+	html, err := comp.RenderLocale(props, locale)
+	r.stats.recordRender(id, err)
+	if err != nil || !r.Debug() {
+		return html, err
+	}
+
+	return annotateBoundaries(id, html, time.Since(start)), nil
+}
+
+// SetLogger sets where the registry, its components, and auto-registration
+// log; nil restores the default
+func (r *Registry) SetLogger(l logger.Logger) {
+	if l == nil {
+		l = logger.Default()
+	}
+
+	r.loggerMux.Lock()
+	defer r.loggerMux.Unlock()
+	r.logger = l
+}
+
+// Logger returns the registry's logger
+func (r *Registry) Logger() logger.Logger {
+	r.loggerMux.RLock()
+	defer r.loggerMux.RUnlock()
+	return r.logger
+}
+
+// SetDebug turns component boundary annotations on or off
+// In debug mode each rendered component is wrapped in HTML comments naming
+// it and its render time. Leave it off in production.
+func (r *Registry) SetDebug(debug bool) {
+	r.debugMux.Lock()
+	defer r.debugMux.Unlock()
+	r.debug = debug
+}
+
+// Debug reports whether component boundary annotations are on
+func (r *Registry) Debug() bool {
+	r.debugMux.RLock()
+	defer r.debugMux.RUnlock()
+	return r.debug
+}
+
+// annotateBoundaries wraps rendered component HTML in start/end comments
+// IDs are validated at registration, so they can't close the comment.
+func annotateBoundaries(id, html string, renderTime time.Duration) string {
+	return fmt.Sprintf("<!-- component:%s start (rendered in %s) -->\n%s\n<!-- component:%s end -->",
+		id, renderTime, html, id)
 }
 
 // BroadcastStateUpdate sends state updates to the broadcaster
 func (r *Registry) BroadcastStateUpdate(componentID, key string, value interface{}, updateType string) error {
+	// Updates from disabled components are paused
+	if !r.IsEnabled(componentID) {
+		return nil
+	}
+
 	if r.broadcaster != nil {
 		return r.broadcaster.BroadcastStateUpdate(componentID, key, value, updateType)
 	}
 	return nil
 }
 
+// BroadcastStateBatch sends several state changes of a component to the
+// broadcaster at once, falling back to one update per key when the
+// broadcaster doesn't support batches
+func (r *Registry) BroadcastStateBatch(componentID string, changes map[string]interface{}) error {
+	// Updates from disabled components are paused
+	if !r.IsEnabled(componentID) || r.broadcaster == nil {
+		return nil
+	}
+
+	if batcher, ok := r.broadcaster.(BatchBroadcaster); ok {
+		return batcher.BroadcastStateBatch(componentID, changes)
+	}
+
+	var errs []error
+	for key, value := range changes {
+		if err := r.broadcaster.BroadcastStateUpdate(componentID, key, value, "update"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// BroadcastStateDeletes sends the deletion of several state keys of a
+// component to the broadcaster at once, falling back to one delete update
+// per key when the broadcaster doesn't support batch deletes
+func (r *Registry) BroadcastStateDeletes(componentID string, keys []string) error {
+	// Updates from disabled components are paused
+	if !r.IsEnabled(componentID) || r.broadcaster == nil {
+		return nil
+	}
+
+	if batcher, ok := r.broadcaster.(BatchDeleteBroadcaster); ok {
+		return batcher.BroadcastStateDeletes(componentID, keys)
+	}
+
+	var errs []error
+	for _, key := range keys {
+		if err := r.broadcaster.BroadcastStateUpdate(componentID, key, nil, "delete"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// BroadcastRender re-renders a component with the props of its last render
+// and pushes the HTML to clients
+// Use it to recover clients whose DOM has drifted from server state, e.g.
+// after a template change during development.
+func (r *Registry) BroadcastRender(id string) error {
+	broadcaster, ok := r.broadcaster.(RenderBroadcaster)
+	if !ok {
+		return fmt.Errorf("broadcaster does not support render updates")
+	}
+
+	comp, exists := r.Get(id)
+	if !exists {
+		return fmt.Errorf("component with ID %s not found", id)
+	}
+
+	html, err := r.RenderComponent(id, comp.LastProps())
+	if err != nil {
+		return fmt.Errorf("failed to render component %s: %w", id, err)
+	}
+
+	return broadcaster.BroadcastRender(id, html)
+}
+
 // GetAll returns all registered components
 func (r *Registry) GetAll() []*Component {
 	r.componentMux.RLock()
@@ -132,3 +429,67 @@ func (r *Registry) GetAll() []*Component {
 
 	return components
 }
+
+// ComponentInfo describes a registered component for admin tooling
+type ComponentInfo struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	StateKeys   []string `json:"state_keys"`
+	MethodNames []string `json:"method_names"`
+}
+
+// Count returns the number of registered components
+func (r *Registry) Count() int {
+	r.componentMux.RLock()
+	defer r.componentMux.RUnlock()
+
+	return len(r.components)
+}
+
+// List describes every registered component, sorted by ID
+// Unlike GetAll it copies no state values, and computed values aren't
+// evaluated.
+func (r *Registry) List() []ComponentInfo {
+	components := r.GetAll()
+	sort.Slice(components, func(i, j int) bool {
+		return components[i].ID < components[j].ID
+	})
+
+	infos := make([]ComponentInfo, 0, len(components))
+	for _, comp := range components {
+		infos = append(infos, ComponentInfo{
+			ID:          comp.ID,
+			Name:        comp.Name,
+			StateKeys:   comp.State.Keys(),
+			MethodNames: comp.MethodNames(),
+		})
+	}
+	return infos
+}
+
+// CompileAll parses the template of every registered component, and its
+// variant for each catalog locale, so template errors surface at startup
+// and no request pays for compiling
+// Templates edited after registration are recompiled. Call it before
+// serving; it replaces compiled templates without coordinating with
+// renders in progress.
+func (r *Registry) CompileAll() error {
+	components := r.GetAll()
+	sort.Slice(components, func(i, j int) bool {
+		return components[i].ID < components[j].ID
+	})
+
+	var locales []string
+	if catalog := r.Catalog(); catalog != nil {
+		locales = catalog.Locales()
+	}
+
+	var errs []error
+	for _, comp := range components {
+		if err := comp.compile(locales); err != nil {
+			errs = append(errs, fmt.Errorf("component %s: %w", comp.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,297 @@
+package component
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/magooney-loon/webrender/pkg/logger"
+)
+
+// recordingBroadcaster records the state updates sent to clients
+type recordingBroadcaster struct {
+	mutex   sync.Mutex
+	updates []recordedUpdate
+}
+
+// recordedUpdate is one call to BroadcastStateUpdate
+type recordedUpdate struct {
+	componentID, key, updateType string
+	value                        interface{}
+}
+
+func (b *recordingBroadcaster) BroadcastStateUpdate(componentID, key string, value interface{}, updateType string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.updates = append(b.updates, recordedUpdate{componentID: componentID, key: key, updateType: updateType, value: value})
+	return nil
+}
+
+// keys returns the keys broadcast so far, in order
+func (b *recordingBroadcaster) keys() []string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	keys := make([]string, 0, len(b.updates))
+	for _, update := range b.updates {
+		keys = append(keys, update.key)
+	}
+	return keys
+}
+
+// newTestRegistry returns a quiet registry broadcasting to b
+func newTestRegistry(b StateBroadcaster) *Registry {
+	r := NewRegistry(b)
+	r.SetLogger(logger.Discard())
+	return r
+}
+
+// mustRegister registers a component or fails the test
+func mustRegister(t *testing.T, r *Registry, c *Component) {
+	t.Helper()
+	if err := r.Register(c); err != nil {
+		t.Fatalf("Register(%s): %v", c.ID, err)
+	}
+}
+
+// withinSecond fails the test if fn doesn't return within a second, e.g.
+// because it deadlocked
+func withinSecond(t *testing.T, what string, fn func()) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("%s did not return; deadlock?", what)
+	}
+}
+
+func TestDisabledComponentRendersPlaceholder(t *testing.T) {
+	r := newTestRegistry(nil)
+	mustRegister(t, r, New("banner-1", "banner", `<p id="{{.ID}}">Sale</p>`))
+
+	html, err := r.RenderComponent("banner-1", nil)
+	if err != nil || !strings.Contains(html, "Sale") {
+		t.Fatalf("enabled render = %q, %v", html, err)
+	}
+
+	r.SetEnabled("banner-1", false)
+	html, err = r.RenderComponent("banner-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(html, "Sale") || !strings.Contains(html, `id="banner-1"`) || !strings.Contains(html, "data-component-disabled") {
+		t.Errorf("disabled render = %q, want an empty placeholder keeping the ID", html)
+	}
+
+	r.SetEnabled("banner-1", true)
+	if html, _ := r.RenderComponent("banner-1", nil); !strings.Contains(html, "Sale") {
+		t.Errorf("re-enabled render = %q, want the template output", html)
+	}
+}
+
+func TestDisabledComponentUpdatesNotBroadcast(t *testing.T) {
+	b := &recordingBroadcaster{}
+	r := newTestRegistry(b)
+	c := New("banner-1", "banner", "<p></p>")
+	mustRegister(t, r, c)
+
+	r.SetEnabled("banner-1", false)
+	c.State.Set("paused", 1)
+	r.SetEnabled("banner-1", true)
+	c.State.Set("live", 1)
+
+	if got := b.keys(); len(got) != 1 || got[0] != "live" {
+		t.Errorf("broadcast keys = %v, want only [live]", got)
+	}
+	if c.State.Get("paused") != 1 {
+		t.Error("state changed while disabled was not kept")
+	}
+}
+
+func TestLifecycleHooksMayChangeState(t *testing.T) {
+	b := &recordingBroadcaster{}
+	r := newTestRegistry(b)
+
+	c := New("clock-1", "clock", "<p></p>")
+	c.Lifecycle.OnMount = func(c *Component) error {
+		c.State.Set("mounted", true)
+		return nil
+	}
+	c.Lifecycle.OnDestroy = func(c *Component) error {
+		c.State.Set("mounted", false)
+		return nil
+	}
+
+	withinSecond(t, "Register", func() {
+		if err := r.Register(c); err != nil {
+			t.Error(err)
+		}
+	})
+	withinSecond(t, "Remove", func() {
+		if err := r.Remove("clock-1"); err != nil {
+			t.Error(err)
+		}
+	})
+
+	if got := b.keys(); len(got) != 2 {
+		t.Errorf("broadcast keys = %v, want one update from each hook", got)
+	}
+}
+
+func TestRegisterValidatesIDs(t *testing.T) {
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{"counter-1", true},
+		{"user_card_2", true},
+		{"Header", true},
+		{"", false},
+		{"my component", false},
+		{"card#1", false},
+		{`x"><script>`, false},
+	}
+
+	for _, tt := range tests {
+		r := newTestRegistry(nil)
+		err := r.Register(New(tt.id, "widget", `<div></div>`))
+		if tt.valid && err != nil {
+			t.Errorf("Register(%q): %v", tt.id, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("Register(%q) succeeded, want an invalid ID error", tt.id)
+		}
+	}
+}
+
+func TestRegisterRejectsEmptyTemplates(t *testing.T) {
+	for _, tmpl := range []string{"", "  \n\t"} {
+		r := newTestRegistry(nil)
+		err := r.Register(New("blank-1", "blank", tmpl))
+		if !errors.Is(err, ErrEmptyTemplate) {
+			t.Errorf("Register with template %q = %v, want ErrEmptyTemplate", tmpl, err)
+		}
+		if _, ok := r.Get("blank-1"); ok {
+			t.Errorf("component with template %q was registered", tmpl)
+		}
+	}
+}
+
+func TestRegisterAllowsOptedInEmptyTemplate(t *testing.T) {
+	r := newTestRegistry(nil)
+	c := New("spacer-1", "spacer", "")
+	c.AllowEmptyTemplate()
+	mustRegister(t, r, c)
+
+	html, err := r.RenderComponent("spacer-1", nil)
+	if err != nil {
+		t.Fatalf("RenderComponent: %v", err)
+	}
+	if html != "" {
+		t.Errorf("empty template rendered %q", html)
+	}
+}
+
+func TestSanitizeID(t *testing.T) {
+	tests := map[string]string{
+		"counter-1":    "counter-1",
+		"my component": "my-component",
+		"a/b  c":       "a-b-c",
+	}
+	for in, want := range tests {
+		got := SanitizeID(in)
+		if got != want {
+			t.Errorf("SanitizeID(%q) = %q, want %q", in, got, want)
+		}
+		if err := ValidateID(got); err != nil {
+			t.Errorf("SanitizeID(%q) = %q, still invalid: %v", in, got, err)
+		}
+	}
+}
+
+func TestDebugModeAnnotatesBoundaries(t *testing.T) {
+	r := newTestRegistry(nil)
+	mustRegister(t, r, New("card-1", "card", `<p>card</p>`))
+
+	html, err := r.RenderComponent("card-1", nil)
+	if err != nil {
+		t.Fatalf("RenderComponent: %v", err)
+	}
+	if strings.Contains(html, "<!-- component:") {
+		t.Errorf("production render contains boundary comments: %s", html)
+	}
+
+	r.SetDebug(true)
+	html, err = r.RenderComponent("card-1", nil)
+	if err != nil {
+		t.Fatalf("RenderComponent: %v", err)
+	}
+	if !strings.HasPrefix(html, "<!-- component:card-1 start (rendered in ") {
+		t.Errorf("debug render does not open with the start comment: %s", html)
+	}
+	if !strings.HasSuffix(html, "<!-- component:card-1 end -->") || !strings.Contains(html, "<p>card</p>") {
+		t.Errorf("debug render does not wrap the component in boundary comments: %s", html)
+	}
+
+	r.SetDebug(false)
+	if html, _ = r.RenderComponent("card-1", nil); strings.Contains(html, "<!-- component:") {
+		t.Errorf("boundary comments remain after leaving debug mode: %s", html)
+	}
+}
+
+func TestCompileAllCompilesEveryLocale(t *testing.T) {
+	r := newTestRegistry(nil)
+	r.SetCatalog(MapCatalog{"en": {"title": "Cart"}, "fr": {"title": "Panier"}})
+
+	cart := New("cart-1", "cart", `<h2>{{t "title"}}</h2>`)
+	menu := New("menu-1", "menu", `<nav>{{.ID}}</nav>`)
+	mustRegister(t, r, cart)
+	mustRegister(t, r, menu)
+
+	if err := r.CompileAll(); err != nil {
+		t.Fatalf("CompileAll: %v", err)
+	}
+
+	for _, c := range []*Component{cart, menu} {
+		if c.CompiledTmpl == nil {
+			t.Errorf("%s: default template not compiled", c.ID)
+		}
+		c.localizedMux.Lock()
+		for _, locale := range []string{"en", "fr"} {
+			if c.localized[locale] == nil {
+				t.Errorf("%s: %s template not compiled", c.ID, locale)
+			}
+		}
+		c.localizedMux.Unlock()
+	}
+}
+
+func TestCompileAllReportsBadTemplates(t *testing.T) {
+	r := newTestRegistry(nil)
+	good := New("good-1", "good", `<p>{{.ID}}</p>`)
+	bad := New("bad-1", "bad", `<p>{{.ID}}</p>`)
+	mustRegister(t, r, good)
+	mustRegister(t, r, bad)
+
+	// Templates edited after registration are only parsed again here
+	bad.Template = `<p>{{if .ID}}</p>`
+
+	err := r.CompileAll()
+	if err == nil {
+		t.Fatal("CompileAll succeeded with a broken template")
+	}
+	if !strings.Contains(err.Error(), "component bad-1") {
+		t.Errorf("error %q does not name the broken component", err)
+	}
+	if strings.Contains(err.Error(), "good-1") {
+		t.Errorf("error %q blames the valid component", err)
+	}
+}
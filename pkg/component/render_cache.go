@@ -0,0 +1,137 @@
+package component
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// renderCacheKey is the context key for the request-scoped render cache
+type renderCacheKey struct{}
+
+// renderCache memoizes rendered component HTML for a single request
+type renderCache struct {
+	entries map[string]string
+	mutex   sync.Mutex
+
+	// IDs of the components rendered, e.g. to collect their CSP sources
+	rendered map[string]bool
+}
+
+// WithRenderCache returns a context in which RenderComponentContext
+// memoizes renders by component ID and props
+func WithRenderCache(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(renderCacheKey{}).(*renderCache); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, renderCacheKey{}, &renderCache{
+		entries:  make(map[string]string),
+		rendered: make(map[string]bool),
+	})
+}
+
+// RenderComponentContext renders a component, reusing the HTML of an
+// identical render (same ID and props) made earlier with the same context.
+// Components render in the locale set with WithLocale; without a cache in
+// ctx nothing is memoized.
+func (r *Registry) RenderComponentContext(ctx context.Context, id string, props map[string]interface{}) (string, error) {
+	// Render in the locale set with WithLocale, if the catalog has it
+	locale := r.resolveLocale(ctx)
+
+	cache, ok := ctx.Value(renderCacheKey{}).(*renderCache)
+	if !ok {
+		return r.renderComponent(id, props, locale)
+	}
+
+	// Props that can't be serialized can't be compared, so skip the cache
+	propsKey, err := json.Marshal(props)
+	if err != nil {
+		html, err := r.renderComponent(id, props, locale)
+		if err == nil {
+			cache.mutex.Lock()
+			cache.rendered[id] = true
+			cache.mutex.Unlock()
+		}
+		return html, err
+	}
+	key := id + "\x00" + locale + "\x00" + string(propsKey)
+
+	cache.mutex.Lock()
+	html, cached := cache.entries[key]
+	cache.mutex.Unlock()
+	if cached {
+		return html, nil
+	}
+
+	html, err = r.renderComponent(id, props, locale)
+	if err != nil {
+		return "", err
+	}
+
+	cache.mutex.Lock()
+	cache.entries[key] = html
+	cache.rendered[id] = true
+	cache.mutex.Unlock()
+
+	return html, nil
+}
+
+// maxMemoEntries bounds the renders a component keeps with CacheRenders
+const maxMemoEntries = 64
+
+// renderMemo keeps a component's rendered HTML by props and locale for one
+// version of its state
+// With CacheRenders set, Render reuses the output until the state changes
+// through Set, SetBatch, Delete, DeleteBatch, Restore, or Compute. The
+// lifecycle hooks still run on a hit, AfterRender with the cached output.
+// Components whose output depends on anything else (computed properties
+// reading outside data, values mutated in place, windowed sources) should
+// not cache.
+type renderMemo struct {
+	version uint64
+	entries map[string]string
+	mutex   sync.Mutex
+}
+
+// get returns the output stored for key at a state version
+func (m *renderMemo) get(key string, version uint64) (string, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.version != version {
+		return "", false
+	}
+	html, ok := m.entries[key]
+	return html, ok
+}
+
+// put stores the output for key, dropping entries from older versions
+func (m *renderMemo) put(key string, version uint64, html string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.version != version || m.entries == nil || len(m.entries) >= maxMemoEntries {
+		m.version = version
+		m.entries = make(map[string]string)
+	}
+	m.entries[key] = html
+}
+
+// memoKey returns the render memo key for props and locale with the
+// current state version, or false when the render can't be cached
+func (c *Component) memoKey(props map[string]interface{}, locale string) (string, uint64, bool) {
+	if !c.CacheRenders || c.windowedSource != nil {
+		return "", 0, false
+	}
+
+	propsKey, err := json.Marshal(props)
+	if err != nil {
+		return "", 0, false
+	}
+
+	c.State.mutex.RLock()
+	version := c.State.version
+	c.State.mutex.RUnlock()
+
+	return locale + "\x00" + string(propsKey), version, true
+}
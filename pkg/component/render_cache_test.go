@@ -0,0 +1,185 @@
+package component
+
+import (
+	"context"
+	"testing"
+)
+
+// newCountingRenderer registers a component that counts its renders
+func newCountingRenderer(t *testing.T, r *Registry) *int {
+	t.Helper()
+
+	renders := 0
+	c := New("card-1", "card", `<p>{{.Props.title}}</p>`)
+	c.Lifecycle.BeforeRender = func(c *Component) error {
+		renders++
+		return nil
+	}
+	mustRegister(t, r, c)
+	return &renders
+}
+
+func TestRenderCacheRendersOncePerRequest(t *testing.T) {
+	r := newTestRegistry(nil)
+	renders := newCountingRenderer(t, r)
+
+	ctx := WithRenderCache(context.Background())
+	props := map[string]interface{}{"title": "Hello"}
+
+	first, err := r.RenderComponentContext(ctx, "card-1", props)
+	if err != nil {
+		t.Fatalf("first render: %v", err)
+	}
+	second, err := r.RenderComponentContext(ctx, "card-1", map[string]interface{}{"title": "Hello"})
+	if err != nil {
+		t.Fatalf("second render: %v", err)
+	}
+
+	if *renders != 1 {
+		t.Errorf("template executed %d times, want 1", *renders)
+	}
+	if first != second {
+		t.Errorf("cached render %q differs from %q", second, first)
+	}
+
+	// Different props are a different render
+	if _, err := r.RenderComponentContext(ctx, "card-1", map[string]interface{}{"title": "Bye"}); err != nil {
+		t.Fatalf("render with other props: %v", err)
+	}
+	if *renders != 2 {
+		t.Errorf("template executed %d times after new props, want 2", *renders)
+	}
+}
+
+func TestRenderCacheIsScopedToContext(t *testing.T) {
+	r := newTestRegistry(nil)
+	renders := newCountingRenderer(t, r)
+
+	// Without a cache every render runs
+	for i := 0; i < 2; i++ {
+		if _, err := r.RenderComponentContext(context.Background(), "card-1", nil); err != nil {
+			t.Fatalf("render: %v", err)
+		}
+	}
+	if *renders != 2 {
+		t.Errorf("template executed %d times without a cache, want 2", *renders)
+	}
+
+	// Each request gets its own cache
+	for i := 0; i < 2; i++ {
+		if _, err := r.RenderComponentContext(WithRenderCache(context.Background()), "card-1", nil); err != nil {
+			t.Fatalf("render: %v", err)
+		}
+	}
+	if *renders != 4 {
+		t.Errorf("template executed %d times across two requests, want 4", *renders)
+	}
+}
+
+// executions counts how often a template prints it
+// Its only field is unexported, so it marshals the same for every render.
+type executions struct {
+	count *int
+}
+
+func (e executions) String() string {
+	*e.count++
+	return ""
+}
+
+// newCachedComponent returns a component whose template counts its
+// executions, with CacheRenders set as given
+func newCachedComponent(cache bool) (*Component, map[string]interface{}, *int) {
+	count := 0
+	c := New("banner-1", "banner", `<p>{{.props.runs}}{{.props.title}} {{.State.Get "visits"}}</p>`)
+	c.CacheRenders = cache
+	c.State.Set("visits", 1)
+	props := map[string]interface{}{"title": "Welcome", "runs": executions{&count}}
+	return c, props, &count
+}
+
+func TestCacheRendersReusesOutputUntilStateChanges(t *testing.T) {
+	c, props, executed := newCachedComponent(true)
+
+	var before, after int
+	c.Lifecycle.BeforeRender = func(c *Component) error {
+		before++
+		return nil
+	}
+	c.Lifecycle.AfterRender = func(c *Component, html string) error {
+		after++
+		return nil
+	}
+
+	render := func() string {
+		t.Helper()
+		html, err := c.Render(props)
+		if err != nil {
+			t.Fatalf("Render: %v", err)
+		}
+		return html
+	}
+
+	first := render()
+	if second := render(); second != first {
+		t.Errorf("cached render %q differs from %q", second, first)
+	}
+	if *executed != 1 {
+		t.Errorf("template executed %d times, want 1", *executed)
+	}
+	if before != 2 || after != 2 {
+		t.Errorf("hooks ran before=%d after=%d times, want 2 each on a cache hit", before, after)
+	}
+
+	// A state change clears the cache
+	c.State.Set("visits", 2)
+	if html := render(); html == first {
+		t.Errorf("render after Set = %q, want the new state", html)
+	}
+	c.State.Delete("visits")
+	render()
+	if *executed != 3 {
+		t.Errorf("template executed %d times after Set and Delete, want 3", *executed)
+	}
+
+	// Different props are cached separately
+	props["title"] = "Goodbye"
+	render()
+	if *executed != 4 {
+		t.Errorf("template executed %d times after new props, want 4", *executed)
+	}
+}
+
+func TestRendersWithoutCacheAlwaysExecute(t *testing.T) {
+	c, props, executed := newCachedComponent(false)
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Render(props); err != nil {
+			t.Fatalf("Render: %v", err)
+		}
+	}
+	if *executed != 3 {
+		t.Errorf("template executed %d times, want 3", *executed)
+	}
+}
+
+func BenchmarkStaticRender(b *testing.B) {
+	for _, bm := range []struct {
+		name  string
+		cache bool
+	}{
+		{"uncached", false},
+		{"cached", true},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			c, props, _ := newCachedComponent(bm.cache)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.Render(props); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package component
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// dashboardKeys are the state keys read by the snapshot test template
+var dashboardKeys = []string{"cpu", "memory", "disk", "network", "requests", "errors", "latency", "uptime"}
+
+// newDashboard creates a component whose template reads every dashboard key
+func newDashboard(id string, snapshot bool) *Component {
+	var tmpl strings.Builder
+	tmpl.WriteString("<div>")
+	for _, key := range dashboardKeys {
+		fmt.Fprintf(&tmpl, `<span>{{.State.Get %q}}</span>`, key)
+	}
+	tmpl.WriteString("</div>")
+
+	c := New(id, "dashboard", tmpl.String())
+	for i, key := range dashboardKeys {
+		c.State.Set(key, i)
+	}
+	c.RenderFromSnapshot(snapshot)
+	return c
+}
+
+func TestSnapshotRenderMatchesLiveRender(t *testing.T) {
+	live, err := newDashboard("live-1", false).Render(nil)
+	if err != nil {
+		t.Fatalf("live render: %v", err)
+	}
+	snap, err := newDashboard("snap-1", true).Render(nil)
+	if err != nil {
+		t.Fatalf("snapshot render: %v", err)
+	}
+	if live != snap {
+		t.Errorf("snapshot render %q differs from live render %q", snap, live)
+	}
+}
+
+func BenchmarkRenderUnderConcurrentWrites(b *testing.B) {
+	for _, bm := range []struct {
+		name     string
+		snapshot bool
+	}{
+		{"per-call Get", false},
+		{"snapshot", true},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			c := newDashboard("dash-1", bm.snapshot)
+			if _, err := c.Render(nil); err != nil {
+				b.Fatal(err)
+			}
+
+			// Keep writing while the benchmark renders
+			stop := make(chan struct{})
+			var wg sync.WaitGroup
+			for w := 0; w < 4; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for i := 0; ; i++ {
+						select {
+						case <-stop:
+							return
+						default:
+							c.State.Set(dashboardKeys[(w+i)%len(dashboardKeys)], i)
+						}
+					}
+				}(w)
+			}
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := c.Render(nil); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.StopTimer()
+
+			close(stop)
+			wg.Wait()
+		})
+	}
+}
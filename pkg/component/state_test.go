@@ -0,0 +1,149 @@
+package component
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+// stateWith returns a state holding the given values
+func stateWith(values map[string]interface{}) *State {
+	c := New("typed-1", "typed", `<div></div>`)
+	for k, v := range values {
+		c.State.Set(k, v)
+	}
+	return c.State
+}
+
+func TestStateGetInt(t *testing.T) {
+	s := stateWith(map[string]interface{}{
+		"int":       7,
+		"int64":     int64(-3),
+		"uint8":     uint8(200),
+		"float":     4.0,
+		"fraction":  4.5,
+		"string":    " 42 ",
+		"json":      json.Number("12"),
+		"text":      "forty",
+		"bool":      true,
+		"overflow":  uint64(math.MaxUint64),
+		"bigFloat":  1e30,
+		"nilValue":  nil,
+		"floatText": "1.5",
+	})
+
+	tests := []struct {
+		key    string
+		want   int
+		wantOK bool
+	}{
+		{"int", 7, true},
+		{"int64", -3, true},
+		{"uint8", 200, true},
+		{"float", 4, true},
+		{"string", 42, true},
+		{"json", 12, true},
+		{"fraction", 0, false},
+		{"text", 0, false},
+		{"bool", 0, false},
+		{"overflow", 0, false},
+		{"bigFloat", 0, false},
+		{"nilValue", 0, false},
+		{"floatText", 0, false},
+		{"missing", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := s.GetInt(tt.key)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("GetInt(%q) = %d, %v; want %d, %v", tt.key, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestStateGetFloat(t *testing.T) {
+	s := stateWith(map[string]interface{}{
+		"float":  2.5,
+		"int":    3,
+		"string": "0.25",
+		"json":   json.Number("1e3"),
+		"text":   "half",
+		"bool":   false,
+	})
+
+	tests := []struct {
+		key    string
+		want   float64
+		wantOK bool
+	}{
+		{"float", 2.5, true},
+		{"int", 3, true},
+		{"string", 0.25, true},
+		{"json", 1000, true},
+		{"text", 0, false},
+		{"bool", 0, false},
+		{"missing", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := s.GetFloat(tt.key)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("GetFloat(%q) = %v, %v; want %v, %v", tt.key, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestStateGetString(t *testing.T) {
+	s := stateWith(map[string]interface{}{
+		"string":   "hello",
+		"bytes":    []byte("raw"),
+		"stringer": 90 * time.Second,
+		"int":      5,
+	})
+
+	tests := []struct {
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{"string", "hello", true},
+		{"bytes", "raw", true},
+		{"stringer", "1m30s", true},
+		{"int", "", false},
+		{"missing", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := s.GetString(tt.key)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("GetString(%q) = %q, %v; want %q, %v", tt.key, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestStateGetBool(t *testing.T) {
+	s := stateWith(map[string]interface{}{
+		"bool":   true,
+		"string": "false",
+		"one":    "1",
+		"text":   "yes",
+		"int":    1,
+	})
+
+	tests := []struct {
+		key    string
+		want   bool
+		wantOK bool
+	}{
+		{"bool", true, true},
+		{"string", false, true},
+		{"one", true, true},
+		{"text", false, false},
+		{"int", false, false},
+		{"missing", false, false},
+	}
+	for _, tt := range tests {
+		got, ok := s.GetBool(tt.key)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("GetBool(%q) = %v, %v; want %v, %v", tt.key, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package component
+
+import (
+	"sync"
+	"time"
+
+	"github.com/magooney-loon/webrender/internal/durations"
+)
+
+// LifecycleStats summarizes component mount and destroy activity
+type LifecycleStats struct {
+	Mounts        int64         `json:"mounts"`
+	Destroys      int64         `json:"destroys"`
+	Live          int           `json:"live"`
+	MountDuration DurationStats `json:"mount_duration"`
+}
+
+// DurationStats describes a distribution of durations
+// Percentiles are computed over the most recent samples.
+type DurationStats struct {
+	Count int64         `json:"count"`
+	Min   time.Duration `json:"min"`
+	Max   time.Duration `json:"max"`
+	Mean  time.Duration `json:"mean"`
+	P50   time.Duration `json:"p50"`
+	P95   time.Duration `json:"p95"`
+	P99   time.Duration `json:"p99"`
+}
+
+// durationStats converts a recorder's summary for the stats API
+func durationStats(d *durations.Recorder) DurationStats {
+	summary := d.Summary()
+	return DurationStats{
+		Count: summary.Count,
+		Min:   summary.Min,
+		Max:   summary.Max,
+		Mean:  summary.Mean,
+		P50:   summary.P50,
+		P95:   summary.P95,
+		P99:   summary.P99,
+	}
+}
+
+// RenderStats counts render outcomes for a component
+type RenderStats struct {
+	Successes int64  `json:"successes"`
+	Failures  int64  `json:"failures"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// registryStats holds lifecycle and render counters for a registry
+type registryStats struct {
+	mounts        int64
+	destroys      int64
+	mountDuration durations.Recorder
+	renders       map[string]*RenderStats
+	mutex         sync.Mutex
+}
+
+// recordMount counts a mounted component and the time its OnMount hook took
+func (s *registryStats) recordMount(hookDuration time.Duration, hasHook bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.mounts++
+	if hasHook {
+		s.mountDuration.Record(hookDuration)
+	}
+}
+
+// recordDestroy counts a destroyed component
+func (s *registryStats) recordDestroy() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.destroys++
+}
+
+// recordRender counts a successful or failed render of a component
+func (s *registryStats) recordRender(id string, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.renders == nil {
+		s.renders = make(map[string]*RenderStats)
+	}
+	stats, ok := s.renders[id]
+	if !ok {
+		stats = &RenderStats{}
+		s.renders[id] = stats
+	}
+
+	if err != nil {
+		stats.Failures++
+		stats.LastError = err.Error()
+		return
+	}
+	stats.Successes++
+}
+
+// RenderStats returns render success and failure counts per component ID
+// Counts are kept for components that have since been removed.
+func (r *Registry) RenderStats() map[string]RenderStats {
+	r.stats.mutex.Lock()
+	defer r.stats.mutex.Unlock()
+
+	result := make(map[string]RenderStats, len(r.stats.renders))
+	for id, stats := range r.stats.renders {
+		result[id] = *stats
+	}
+	return result
+}
+
+// LifecycleStats returns mount and destroy counts, the number of live
+// components, and the distribution of OnMount hook durations
+func (r *Registry) LifecycleStats() LifecycleStats {
+	r.componentMux.RLock()
+	live := len(r.components)
+	r.componentMux.RUnlock()
+
+	r.stats.mutex.Lock()
+	defer r.stats.mutex.Unlock()
+
+	return LifecycleStats{
+		Mounts:        r.stats.mounts,
+		Destroys:      r.stats.destroys,
+		Live:          live,
+		MountDuration: durationStats(&r.stats.mountDuration),
+	}
+}
@@ -0,0 +1,78 @@
+package component
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestLifecycleStatsAcrossMountAndDestroy(t *testing.T) {
+	r := newTestRegistry(nil)
+
+	const mountDelay = 2 * time.Millisecond
+	for i := 0; i < 3; i++ {
+		c := New(fmt.Sprintf("widget-%d", i), "widget", `<div></div>`)
+		c.Lifecycle.OnMount = func(c *Component) error {
+			time.Sleep(mountDelay)
+			return nil
+		}
+		mustRegister(t, r, c)
+	}
+
+	stats := r.LifecycleStats()
+	if stats.Mounts != 3 || stats.Destroys != 0 || stats.Live != 3 {
+		t.Errorf("after mounting: mounts=%d destroys=%d live=%d, want 3, 0, 3", stats.Mounts, stats.Destroys, stats.Live)
+	}
+	if stats.MountDuration.Count != 3 {
+		t.Errorf("mount duration count = %d, want 3", stats.MountDuration.Count)
+	}
+	if stats.MountDuration.Min < mountDelay {
+		t.Errorf("shortest mount took %v, want at least %v", stats.MountDuration.Min, mountDelay)
+	}
+
+	if err := r.Remove("widget-0"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	stats = r.LifecycleStats()
+	if stats.Mounts != 3 || stats.Destroys != 1 || stats.Live != 2 {
+		t.Errorf("after destroying: mounts=%d destroys=%d live=%d, want 3, 1, 2", stats.Mounts, stats.Destroys, stats.Live)
+	}
+
+	// Mounting again counts as another mount
+	mustRegister(t, r, New("widget-0", "widget", `<div></div>`))
+
+	stats = r.LifecycleStats()
+	if stats.Mounts != 4 || stats.Live != 3 {
+		t.Errorf("after remounting: mounts=%d live=%d, want 4, 3", stats.Mounts, stats.Live)
+	}
+}
+
+func TestRenderStatsCountFailuresPerComponent(t *testing.T) {
+	r := newTestRegistry(nil)
+	mustRegister(t, r, New("good-1", "good", `<p>{{.ID}}</p>`))
+	mustRegister(t, r, New("bad-1", "bad", `<p>{{template "missing"}}</p>`))
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.RenderComponent("good-1", nil); err != nil {
+			t.Fatalf("rendering good-1: %v", err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := r.RenderComponent("bad-1", nil); err == nil {
+			t.Fatal("rendering bad-1 succeeded, want a template error")
+		}
+	}
+
+	stats := r.RenderStats()
+	if got := stats["good-1"]; got.Successes != 2 || got.Failures != 0 {
+		t.Errorf("good-1: %+v, want 2 successes and no failures", got)
+	}
+	bad := stats["bad-1"]
+	if bad.Successes != 0 || bad.Failures != 3 {
+		t.Errorf("bad-1: %+v, want 3 failures and no successes", bad)
+	}
+	if bad.LastError == "" {
+		t.Error("bad-1: last error not recorded")
+	}
+}
@@ -0,0 +1,7 @@
+package widgets
+
+import ui "github.com/magooney-loon/webrender/pkg/component"
+
+func NewAliased(id string) *ui.Component {
+	return ui.New(id, "aliased", "<div></div>")
+}
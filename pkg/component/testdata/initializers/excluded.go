@@ -0,0 +1,9 @@
+//go:build never
+
+package widgets
+
+import "github.com/magooney-loon/webrender/pkg/component"
+
+func NewExcluded(id string) *component.Component {
+	return component.New(id, "excluded", "<div></div>")
+}
@@ -0,0 +1,7 @@
+package widgets
+
+type Component struct{}
+
+func NewUnrelated(id string) *Component {
+	return &Component{}
+}
@@ -0,0 +1,41 @@
+package widgets
+
+import "github.com/magooney-loon/webrender/pkg/component"
+
+type Other struct{}
+
+type Widget struct{}
+
+func NewCard(id string) *component.Component {
+	return component.New(id, "card", "<div></div>")
+}
+
+func NewTable(
+	id string,
+) *component.Component {
+	return component.New(id, "table", "<table></table>")
+}
+
+func newHidden(id string) *component.Component {
+	return component.New(id, "hidden", "<div></div>")
+}
+
+func NewTwoArgs(id, name string) *component.Component {
+	return component.New(id, name, "<div></div>")
+}
+
+func NewWrongReturn(id string) *Other {
+	return &Other{}
+}
+
+func NewByValue(id string) component.Component {
+	return component.Component{}
+}
+
+func (w *Widget) NewMethod(id string) *component.Component {
+	return component.New(id, "method", "<div></div>")
+}
+
+func NewGeneric[T any](id string) *component.Component {
+	return component.New(id, "generic", "<div></div>")
+}
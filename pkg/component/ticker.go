@@ -0,0 +1,75 @@
+package component
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// JitteredTicker delivers ticks at an interval randomized by a fraction of
+// itself, so periodic updates from many components don't fire in lockstep
+type JitteredTicker struct {
+	C <-chan time.Time
+
+	interval time.Duration
+	jitter   float64
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewJitteredTicker returns a ticker whose intervals fall within
+// interval ± interval*jitter. Jitter is clamped to [0, 1); like
+// time.Ticker, ticks are dropped when the reader falls behind.
+func NewJitteredTicker(interval time.Duration, jitter float64) *JitteredTicker {
+	if interval <= 0 {
+		panic("component: non-positive interval for NewJitteredTicker")
+	}
+	if jitter < 0 {
+		jitter = 0
+	}
+	if jitter >= 1 {
+		jitter = 0.99
+	}
+
+	c := make(chan time.Time, 1)
+	t := &JitteredTicker{
+		C:        c,
+		interval: interval,
+		jitter:   jitter,
+		stop:     make(chan struct{}),
+	}
+	go t.run(c)
+	return t
+}
+
+// Stop turns off the ticker; no more ticks are sent after it returns
+func (t *JitteredTicker) Stop() {
+	t.stopOnce.Do(func() {
+		close(t.stop)
+	})
+}
+
+// next returns the next randomized interval
+func (t *JitteredTicker) next() time.Duration {
+	spread := float64(t.interval) * t.jitter
+	return t.interval + time.Duration((rand.Float64()*2-1)*spread)
+}
+
+// run sends ticks until Stop is called
+func (t *JitteredTicker) run(c chan time.Time) {
+	timer := time.NewTimer(t.next())
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		case now := <-timer.C:
+			select {
+			case c <- now:
+			default:
+			}
+			timer.Reset(t.next())
+		}
+	}
+}
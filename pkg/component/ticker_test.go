@@ -0,0 +1,69 @@
+package component
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJitteredTickerIntervalsVaryWithinBounds(t *testing.T) {
+	ticker := NewJitteredTicker(100*time.Millisecond, 0.2)
+	ticker.Stop()
+
+	low, high := 80*time.Millisecond, 120*time.Millisecond
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 1000; i++ {
+		d := ticker.next()
+		if d < low || d > high {
+			t.Fatalf("interval %v outside [%v, %v]", d, low, high)
+		}
+		seen[d] = true
+	}
+	if len(seen) < 100 {
+		t.Errorf("only %d distinct intervals in 1000 ticks; jitter isn't varying them", len(seen))
+	}
+}
+
+func TestJitteredTickerClampsJitter(t *testing.T) {
+	none := NewJitteredTicker(10*time.Millisecond, -1)
+	none.Stop()
+	if d := none.next(); d != 10*time.Millisecond {
+		t.Errorf("negative jitter gave interval %v, want exactly 10ms", d)
+	}
+
+	wide := NewJitteredTicker(10*time.Millisecond, 5)
+	wide.Stop()
+	for i := 0; i < 1000; i++ {
+		if d := wide.next(); d <= 0 || d >= 20*time.Millisecond {
+			t.Fatalf("jitter above 1 gave interval %v, want it within (0, 20ms)", d)
+		}
+	}
+}
+
+func TestJitteredTickerTicksAndStops(t *testing.T) {
+	ticker := NewJitteredTicker(10*time.Millisecond, 0.5)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		select {
+		case <-ticker.C:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for a tick")
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("3 ticks took %v, want at least 3 × 5ms", elapsed)
+	}
+
+	ticker.Stop()
+	// Drain a tick that may have been in flight when Stop was called
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case <-ticker.C:
+	default:
+	}
+	select {
+	case <-ticker.C:
+		t.Error("ticked after Stop")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,10 @@
+package component
+
+// UploadParam is the action param key holding bytes uploaded with the action
+const UploadParam = "upload"
+
+// Upload returns the binary data uploaded with an action, if any
+func Upload(params map[string]interface{}) ([]byte, bool) {
+	data, ok := params[UploadParam].([]byte)
+	return data, ok
+}
@@ -0,0 +1,180 @@
+package component
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errTooHigh = errors.New("count above 10")
+
+// newGuardedCounter registers a counter whose OnStateChange rejects counts
+// above 10 and deleting the count
+func newGuardedCounter(t *testing.T) (*Component, *recordingBroadcaster, *int32) {
+	t.Helper()
+
+	b := &recordingBroadcaster{}
+	r := newTestRegistry(b)
+	c := New("counter-1", "counter", `<span>{{.State.Get "count"}}</span>`)
+	c.State.Set("count", 0)
+	c.Lifecycle.OnStateChange = func(c *Component, key string, oldVal, newVal interface{}) error {
+		if key != "count" {
+			return nil
+		}
+		if newVal == nil {
+			return errors.New("count can't be deleted")
+		}
+		if n, ok := newVal.(int); ok && n > 10 {
+			return errTooHigh
+		}
+		return nil
+	}
+	mustRegister(t, r, c)
+
+	var watched int32
+	c.State.Watch("count", func(oldVal, newVal interface{}) {
+		atomic.AddInt32(&watched, 1)
+	})
+	return c, b, &watched
+}
+
+func TestOnStateChangeRejectionUndoesSet(t *testing.T) {
+	c, b, watched := newGuardedCounter(t)
+
+	c.State.Set("count", 5)
+	c.State.Set("count", 11)
+
+	if got, _ := c.State.GetInt("count"); got != 5 {
+		t.Errorf("count = %d after a rejected Set, want 5", got)
+	}
+	if got := b.keys(); !reflect.DeepEqual(got, []string{"count"}) {
+		t.Errorf("broadcast keys = %v, want only the accepted change", got)
+	}
+
+	// Give a stray watcher call time to show up
+	time.Sleep(20 * time.Millisecond)
+	if got := atomic.LoadInt32(watched); got != 1 {
+		t.Errorf("watchers ran %d times, want 1", got)
+	}
+}
+
+func TestOnStateChangeRejectionUndoesDelete(t *testing.T) {
+	c, b, _ := newGuardedCounter(t)
+	c.State.Set("count", 3)
+
+	c.State.Delete("count")
+
+	if got, ok := c.State.GetInt("count"); !ok || got != 3 {
+		t.Errorf("count = %d (present %v) after a rejected Delete, want 3", got, ok)
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, update := range b.updates {
+		if update.updateType == "delete" {
+			t.Errorf("rejected delete was broadcast: %+v", update)
+		}
+	}
+}
+
+func TestOnStateChangeRejectionKeepsNewKeysOut(t *testing.T) {
+	c := New("form-1", "form", `<form></form>`)
+	c.Lifecycle.OnStateChange = func(c *Component, key string, oldVal, newVal interface{}) error {
+		if key == "admin" {
+			return errors.New("read-only")
+		}
+		return nil
+	}
+
+	c.State.SetBatch(map[string]interface{}{"name": "Ada", "admin": true})
+
+	if _, exists := c.State.GetAll()["admin"]; exists {
+		t.Error("rejected key was added")
+	}
+	if got := c.State.Get("name"); got != "Ada" {
+		t.Errorf("name = %v, want the accepted part of the batch", got)
+	}
+}
+
+func TestOnStateChangeCanClampValues(t *testing.T) {
+	b := &recordingBroadcaster{}
+	r := newTestRegistry(b)
+	c := New("volume-1", "volume", `<span></span>`)
+	c.Lifecycle.OnStateChange = func(c *Component, key string, oldVal, newVal interface{}) error {
+		if n, ok := newVal.(int); ok && n > 10 {
+			c.State.Set(key, 10)
+			return errTooHigh
+		}
+		return nil
+	}
+	mustRegister(t, r, c)
+
+	c.State.Set("level", 15)
+
+	if got, _ := c.State.GetInt("level"); got != 10 {
+		t.Errorf("level = %d, want the clamped 10", got)
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if len(b.updates) != 1 || b.updates[0].value != 10 {
+		t.Errorf("broadcasts = %+v, want only the clamped value", b.updates)
+	}
+}
+
+func TestOnStateChangeRunsBeforeTheChangeIsVisible(t *testing.T) {
+	c := New("form-1", "form", `<span>{{.State.Get "role"}}</span>`)
+	c.State.Set("role", "viewer")
+
+	var seen []interface{}
+	c.Lifecycle.OnStateChange = func(c *Component, key string, oldVal, newVal interface{}) error {
+		seen = append(seen, c.State.Get(key))
+		if newVal == "admin" || newVal == nil {
+			return errors.New("read-only")
+		}
+		return nil
+	}
+
+	c.State.Set("role", "admin")
+	c.State.SetBatch(map[string]interface{}{"role": "admin"})
+	c.State.Delete("role")
+	c.State.DeleteBatch("role")
+	c.State.Set("role", "editor")
+
+	for i, value := range seen {
+		if value != "viewer" {
+			t.Errorf("hook %d saw role = %v, want the committed viewer", i, value)
+		}
+	}
+	if got := c.State.Get("role"); got != "editor" {
+		t.Errorf("role = %v, want the accepted editor", got)
+	}
+}
+
+func TestRejectedValuesNeverReachReaders(t *testing.T) {
+	c, _, _ := newGuardedCounter(t)
+
+	var leaked atomic.Value
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 2000; i++ {
+			if n, _ := c.State.GetInt("count"); n > 10 {
+				leaked.Store(n)
+			}
+			if html, err := c.Render(nil); err == nil && strings.Contains(html, "11") {
+				leaked.Store(html)
+			}
+		}
+	}()
+	for i := 0; i < 500; i++ {
+		c.State.Set("count", 11)
+		c.State.SetBatch(map[string]interface{}{"count": 11})
+	}
+	<-done
+
+	if v := leaked.Load(); v != nil {
+		t.Errorf("reader saw rejected value %v", v)
+	}
+}
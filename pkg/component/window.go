@@ -0,0 +1,70 @@
+package component
+
+import "fmt"
+
+const (
+	// DefaultWindowSize is used when a window request has no limit
+	DefaultWindowSize = 50
+
+	// MaxWindowSize caps the number of items sent in one window
+	MaxWindowSize = 500
+)
+
+// Window is a slice of a component's windowed data source
+type Window struct {
+	Offset int           `json:"offset"`
+	Limit  int           `json:"limit"`
+	Items  []interface{} `json:"items"`
+	Total  int           `json:"total"`
+}
+
+// WindowedSource returns up to limit items starting at offset, along
+// with the total number of items available
+type WindowedSource func(offset, limit int) ([]interface{}, int)
+
+// SetWindowedSource sets the data source for a large list so only a window
+// of it is rendered and sent to the client. Templates render the first
+// window with {{call .Window 0 50}}; clients request others as they scroll.
+func (c *Component) SetWindowedSource(source func(offset, limit int) ([]interface{}, int)) {
+	c.windowedSource = source
+}
+
+// HasWindowedSource reports whether the component has a windowed source
+func (c *Component) HasWindowedSource() bool {
+	return c.windowedSource != nil
+}
+
+// Window returns the items in [offset, offset+limit) from the windowed source
+// A limit of zero uses DefaultWindowSize; larger limits are capped at MaxWindowSize.
+func (c *Component) Window(offset, limit int) (Window, error) {
+	if c.windowedSource == nil {
+		return Window{}, fmt.Errorf("component %s has no windowed source", c.ID)
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = DefaultWindowSize
+	}
+	if limit > MaxWindowSize {
+		limit = MaxWindowSize
+	}
+
+	items, total := c.windowedSource(offset, limit)
+
+	// Don't trust the source to respect the limit
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	if items == nil {
+		items = []interface{}{}
+	}
+
+	return Window{
+		Offset: offset,
+		Limit:  limit,
+		Items:  items,
+		Total:  total,
+	}, nil
+}
@@ -0,0 +1,77 @@
+package component
+
+import (
+	"reflect"
+	"testing"
+)
+
+// numberSource serves the integers 0..total-1 as a windowed source
+func numberSource(total int) func(offset, limit int) ([]interface{}, int) {
+	return func(offset, limit int) ([]interface{}, int) {
+		var items []interface{}
+		for i := offset; i < offset+limit && i < total; i++ {
+			items = append(items, i)
+		}
+		return items, total
+	}
+}
+
+func TestWindowReturnsRequestedSlice(t *testing.T) {
+	c := New("rows-1", "rows", `<ul></ul>`)
+	c.SetWindowedSource(numberSource(1000))
+
+	tests := []struct {
+		name                  string
+		offset, limit         int
+		wantOffset, wantLimit int
+		wantItems             []interface{}
+	}{
+		{"first window", 0, 3, 0, 3, []interface{}{0, 1, 2}},
+		{"middle window", 500, 2, 500, 2, []interface{}{500, 501}},
+		{"past the end", 998, 5, 998, 5, []interface{}{998, 999}},
+		{"beyond the total", 2000, 5, 2000, 5, []interface{}{}},
+		{"negative offset", -10, 1, 0, 1, []interface{}{0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, err := c.Window(tt.offset, tt.limit)
+			if err != nil {
+				t.Fatalf("Window: %v", err)
+			}
+			if window.Offset != tt.wantOffset || window.Limit != tt.wantLimit || window.Total != 1000 {
+				t.Errorf("got offset %d, limit %d, total %d", window.Offset, window.Limit, window.Total)
+			}
+			if !reflect.DeepEqual(window.Items, tt.wantItems) {
+				t.Errorf("items = %v, want %v", window.Items, tt.wantItems)
+			}
+		})
+	}
+}
+
+func TestWindowLimits(t *testing.T) {
+	c := New("rows-1", "rows", `<ul></ul>`)
+	c.SetWindowedSource(numberSource(10000))
+
+	window, _ := c.Window(0, 0)
+	if window.Limit != DefaultWindowSize || len(window.Items) != DefaultWindowSize {
+		t.Errorf("zero limit: got limit %d with %d items, want %d", window.Limit, len(window.Items), DefaultWindowSize)
+	}
+
+	window, _ = c.Window(0, MaxWindowSize*2)
+	if window.Limit != MaxWindowSize || len(window.Items) != MaxWindowSize {
+		t.Errorf("oversized limit: got limit %d with %d items, want %d", window.Limit, len(window.Items), MaxWindowSize)
+	}
+
+	// A source ignoring the limit is cut down to it
+	c.SetWindowedSource(func(offset, limit int) ([]interface{}, int) {
+		return []interface{}{1, 2, 3, 4}, 4
+	})
+	if window, _ = c.Window(0, 2); len(window.Items) != 2 {
+		t.Errorf("got %d items from an overeager source, want 2", len(window.Items))
+	}
+
+	if _, err := New("plain-1", "plain", `<div></div>`).Window(0, 10); err == nil {
+		t.Error("Window on a component without a source returned no error")
+	}
+}
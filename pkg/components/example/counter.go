@@ -1,6 +1,11 @@
+//go:build webrender_demos
+
 package example
 
 import (
+	"fmt"
+	"math"
+
 	"github.com/magooney-loon/webrender/pkg/component"
 )
 
@@ -48,29 +53,14 @@ const (
 
 	counterScript = `
 		// Counter component handler
+		// The server owns the count; its broadcast updates every open tab
 		const Counter = {
-			increment(componentId) {
-				const component = document.getElementById(componentId);
-				const state = JSON.parse(component.getAttribute('data-state'));
-				const newCount = state.count + 1;
-				
-				// Update the UI
-				component.querySelector('[data-bind="count"]').textContent = newCount;
-				
-				// Send update to server
-				WSManager.sendStateUpdate(componentId, "count", newCount);
+			increment(componentId, step) {
+				WSManager.sendAction(componentId, "increment", step ? { step: step } : {});
 			},
 
-			decrement(componentId) {
-				const component = document.getElementById(componentId);
-				const state = JSON.parse(component.getAttribute('data-state'));
-				const newCount = state.count - 1;
-				
-				// Update the UI
-				component.querySelector('[data-bind="count"]').textContent = newCount;
-				
-				// Send update to server
-				WSManager.sendStateUpdate(componentId, "count", newCount);
+			decrement(componentId, step) {
+				WSManager.sendAction(componentId, "decrement", step ? { step: step } : {});
 			}
 		};
 	`
@@ -78,9 +68,21 @@ const (
 
 // NewCounter creates a new counter component
 func NewCounter(id string) *component.Component {
-	counter := component.New(id, "counter", counterTemplate)
+	counter := component.New(id, "counter", counterTemplate).WithAssets(counterStyles, counterScript)
 	counter.State.Set("count", 0)
 
+	// Only the increment and decrement methods move the count
+	counter.ClientReadOnly("count")
+
+	// Actions from several tabs must not interleave their read-modify-write
+	counter.SerializeActions(true)
+	counter.AddTypedMethod("increment", func(params map[string]interface{}) error {
+		return addToCount(counter, params, 1)
+	})
+	counter.AddTypedMethod("decrement", func(params map[string]interface{}) error {
+		return addToCount(counter, params, -1)
+	})
+
 	// Add lifecycle hooks
 	counter.Lifecycle.OnMount = func(c *component.Component) error {
 		// Initialize anything needed on mount
@@ -95,6 +97,33 @@ func NewCounter(id string) *component.Component {
 	return counter
 }
 
+// addToCount moves the count by the optional "step" param (default 1) in
+// the given direction
+func addToCount(c *component.Component, params map[string]interface{}, direction int) error {
+	step := 1
+	if raw, ok := params["step"]; ok {
+		switch v := raw.(type) {
+		case int:
+			step = v
+		case float64:
+			// JSON numbers arrive as float64
+			if v != math.Trunc(v) || math.Abs(v) > 1e9 {
+				return fmt.Errorf("step must be a whole number, got %v", v)
+			}
+			step = int(v)
+		default:
+			return fmt.Errorf("step must be a number, got %T", raw)
+		}
+	}
+
+	count, ok := c.State.GetInt("count")
+	if !ok {
+		return fmt.Errorf("count is not a whole number: %v", c.State.Get("count"))
+	}
+	c.State.Set("count", count+direction*step)
+	return nil
+}
+
 // GetStyles returns the component's styles
 func GetStyles() string {
 	return counterStyles
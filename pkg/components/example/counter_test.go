@@ -0,0 +1,77 @@
+//go:build webrender_demos
+
+package example
+
+import (
+	"testing"
+)
+
+func TestCounterIncrementTwice(t *testing.T) {
+	counter := NewCounter("counter-1")
+
+	for i := 0; i < 2; i++ {
+		if err := counter.CallMethod("increment", map[string]interface{}{}); err != nil {
+			t.Fatalf("increment: %v", err)
+		}
+	}
+
+	if count, _ := counter.State.GetInt("count"); count != 2 {
+		t.Errorf("count = %d, want 2", count)
+	}
+}
+
+func TestCounterStep(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		params map[string]interface{}
+		want   int
+	}{
+		{"increment by step", "increment", map[string]interface{}{"step": 5}, 5},
+		{"JSON step", "increment", map[string]interface{}{"step": 3.0}, 3},
+		{"decrement default", "decrement", nil, -1},
+		{"decrement by step", "decrement", map[string]interface{}{"step": 4.0}, -4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := NewCounter("counter-1")
+			if err := counter.CallMethod(tt.method, tt.params); err != nil {
+				t.Fatalf("%s: %v", tt.method, err)
+			}
+			if count, _ := counter.State.GetInt("count"); count != tt.want {
+				t.Errorf("count = %d, want %d", count, tt.want)
+			}
+		})
+	}
+}
+
+func TestCounterRejectsInvalidStep(t *testing.T) {
+	for _, step := range []interface{}{"2", 1.5} {
+		counter := NewCounter("counter-1")
+		if err := counter.CallMethod("increment", map[string]interface{}{"step": step}); err == nil {
+			t.Errorf("increment with step %#v succeeded, want an error", step)
+		}
+		if count, _ := counter.State.GetInt("count"); count != 0 {
+			t.Errorf("count = %d after an invalid step, want 0", count)
+		}
+	}
+}
+
+func TestCounterReportsNonNumericCount(t *testing.T) {
+	counter := NewCounter("counter-1")
+	counter.State.Set("count", "lots")
+
+	if err := counter.CallMethod("increment", nil); err == nil {
+		t.Error("increment of a non-numeric count succeeded, want an error")
+	}
+	if got := counter.State.Get("count"); got != "lots" {
+		t.Errorf("count = %v, want it left alone", got)
+	}
+}
+
+func TestCounterCountIsReadOnlyForClients(t *testing.T) {
+	if !NewCounter("counter-1").IsClientReadOnly("count") {
+		t.Error("clients may overwrite count, want it read-only")
+	}
+}
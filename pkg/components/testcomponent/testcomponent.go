@@ -1,3 +1,5 @@
+//go:build webrender_demos
+
 package testcomponent
 
 import (
@@ -79,7 +81,7 @@ const (
 
 // NewTestComponent creates a new TestComponent component
 func NewTestComponent(id string) *component.Component {
-	testcomponentComp := component.New(id, "testcomponent", testcomponentTemplate)
+	testcomponentComp := component.New(id, "testcomponent", testcomponentTemplate).WithAssets(testcomponentStyles, testcomponentScript)
 
 	// Initialize state
 	testcomponentComp.State.Set("exampleState", "value")
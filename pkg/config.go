@@ -0,0 +1,222 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/magooney-loon/webrender/pkg/logger"
+	"github.com/magooney-loon/webrender/pkg/router"
+	tmpl "github.com/magooney-loon/webrender/pkg/template"
+	"github.com/magooney-loon/webrender/pkg/websocket"
+)
+
+// reservedPaths are URL prefixes WebRender serves itself, besides the
+// configurable WebSocket and SSE endpoints
+var reservedPaths = []string{"/static", "/_"}
+
+// ConfigBuilder builds a Config starting from DefaultConfig
+// Values are validated together in Build.
+type ConfigBuilder struct {
+	config Config
+}
+
+// NewConfigBuilder creates a builder with the default configuration
+func NewConfigBuilder() *ConfigBuilder {
+	return &ConfigBuilder{config: DefaultConfig()}
+}
+
+// WithStaticDir sets the directory served under /static
+func (b *ConfigBuilder) WithStaticDir(dir string) *ConfigBuilder {
+	b.config.StaticDir = dir
+	return b
+}
+
+// WithRouter sets the router used for all routes
+func (b *ConfigBuilder) WithRouter(r *router.Router) *ConfigBuilder {
+	b.config.Router = r
+	return b
+}
+
+// WithServeMux sets the legacy ServeMux
+func (b *ConfigBuilder) WithServeMux(mux *http.ServeMux) *ConfigBuilder {
+	b.config.ServeMux = mux
+	return b
+}
+
+// WithAdminPanel enables or disables the admin panel at /_/
+func (b *ConfigBuilder) WithAdminPanel(enabled bool) *ConfigBuilder {
+	b.config.EnableAdminPanel = enabled
+	return b
+}
+
+// WithAutoRegister sets the component namespace and directories to
+// auto-register; no directories disables auto-registration
+func (b *ConfigBuilder) WithAutoRegister(namespace string, dirs ...string) *ConfigBuilder {
+	b.config.AutoRegisterNamespace = namespace
+	b.config.AutoRegisterDirs = dirs
+	return b
+}
+
+// WithBaseTemplate enables or disables the base page template
+func (b *ConfigBuilder) WithBaseTemplate(enabled bool) *ConfigBuilder {
+	b.config.UseBaseTemplate = enabled
+	return b
+}
+
+// WithContentSecurityPolicy sets the CSP applied with a per-request nonce
+func (b *ConfigBuilder) WithContentSecurityPolicy(policy string) *ConfigBuilder {
+	b.config.ContentSecurityPolicy = policy
+	return b
+}
+
+// WithMaintenanceMode sets whether the app starts in maintenance mode
+func (b *ConfigBuilder) WithMaintenanceMode(enabled bool) *ConfigBuilder {
+	b.config.MaintenanceMode = enabled
+	return b
+}
+
+// WithWebSocketPath sets the path of the WebSocket endpoint
+func (b *ConfigBuilder) WithWebSocketPath(path string) *ConfigBuilder {
+	b.config.WebSocketPath = path
+	return b
+}
+
+// WithSSEPath sets the path of the Server-Sent Events fallback endpoint
+func (b *ConfigBuilder) WithSSEPath(path string) *ConfigBuilder {
+	b.config.SSEPath = path
+	return b
+}
+
+// WithDebug enables component boundary annotations for development
+func (b *ConfigBuilder) WithDebug(enabled bool) *ConfigBuilder {
+	b.config.Debug = enabled
+	return b
+}
+
+// WithBroadcastBuffer sets the broadcast queue size and what happens when
+// it fills up
+func (b *ConfigBuilder) WithBroadcastBuffer(size int, policy websocket.OverflowPolicy) *ConfigBuilder {
+	b.config.WebSocketOptions.BroadcastBuffer = size
+	b.config.WebSocketOptions.OverflowPolicy = policy
+	return b
+}
+
+// WithShutdownGrace sets how long clients are warned before shutdown
+func (b *ConfigBuilder) WithShutdownGrace(d time.Duration) *ConfigBuilder {
+	b.config.ShutdownGrace = d
+	return b
+}
+
+// WithAllowedOrigins restricts WebSocket and SSE connections to the given
+// origins (e.g. "https://example.com")
+func (b *ConfigBuilder) WithAllowedOrigins(origins ...string) *ConfigBuilder {
+	b.config.WebSocketOptions.AllowedOrigins = origins
+	return b
+}
+
+// WithSelfHostedAssets drops the CDN tags from the base template and
+// loads the given stylesheet URLs (e.g. under /static) instead
+func (b *ConfigBuilder) WithSelfHostedAssets(stylesheets ...string) *ConfigBuilder {
+	b.config.Assets.SelfHosted = true
+	b.config.Assets.Stylesheets = stylesheets
+	return b
+}
+
+// WithTheme sets the base template body styling, e.g. tmpl.ThemeNone to
+// style the page entirely yourself
+func (b *ConfigBuilder) WithTheme(theme string) *ConfigBuilder {
+	b.config.Theme = theme
+	return b
+}
+
+// WithLogger sets the logger used by all WebRender packages
+func (b *ConfigBuilder) WithLogger(l logger.Logger) *ConfigBuilder {
+	b.config.Logger = l
+	return b
+}
+
+// Build validates and returns the configuration
+func (b *ConfigBuilder) Build() (Config, error) {
+	if err := b.config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return b.config, nil
+}
+
+// Validate reports invalid or conflicting configuration values
+func (c Config) Validate() error {
+	var errs []error
+
+	if strings.TrimSpace(c.StaticDir) == "" {
+		errs = append(errs, errors.New("static dir is required"))
+	}
+	if c.Router == nil {
+		errs = append(errs, errors.New("router is required"))
+	}
+	if c.ServeMux == nil {
+		errs = append(errs, errors.New("serve mux is required"))
+	}
+	if len(c.AutoRegisterDirs) > 0 && c.AutoRegisterNamespace == "" {
+		errs = append(errs, errors.New("auto-register namespace is required when auto-register dirs are set"))
+	}
+
+	errs = append(errs, validateEndpointPath("websocket", c.WebSocketPath)...)
+	errs = append(errs, validateEndpointPath("sse", c.SSEPath)...)
+	if endpointPath(c.WebSocketPath, "/ws") == endpointPath(c.SSEPath, "/sse") {
+		errs = append(errs, fmt.Errorf("websocket and sse paths must differ, both are %q", endpointPath(c.WebSocketPath, "/ws")))
+	}
+
+	for _, url := range append(append([]string{}, c.Assets.Stylesheets...), c.Assets.Scripts...) {
+		if strings.TrimSpace(url) == "" {
+			errs = append(errs, errors.New("asset URLs must not be empty"))
+			break
+		}
+	}
+
+	if !tmpl.ValidTheme(c.Theme) {
+		errs = append(errs, fmt.Errorf("unknown theme %q", c.Theme))
+	}
+
+	if c.ShutdownGrace < 0 {
+		errs = append(errs, fmt.Errorf("shutdown grace must not be negative, got %s", c.ShutdownGrace))
+	}
+
+	if err := c.WebSocketOptions.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("websocket options: %w", err))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// validateEndpointPath checks a configured endpoint path ("" uses the
+// default) against the paths WebRender reserves
+func validateEndpointPath(name, path string) []error {
+	if path == "" {
+		return nil
+	}
+
+	var errs []error
+	if !strings.HasPrefix(path, "/") || path == "/" {
+		errs = append(errs, fmt.Errorf("%s path %q must be an absolute path other than /", name, path))
+	}
+	for _, reserved := range reservedPaths {
+		if path == reserved || strings.HasPrefix(path, reserved+"/") {
+			errs = append(errs, fmt.Errorf("%s path %q conflicts with reserved path %s", name, path, reserved))
+		}
+	}
+	return errs
+}
+
+// endpointPath returns path, or fallback when it is empty
+func endpointPath(path, fallback string) string {
+	if path == "" {
+		return fallback
+	}
+	return path
+}
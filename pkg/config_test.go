@@ -0,0 +1,85 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tmpl "github.com/magooney-loon/webrender/pkg/template"
+)
+
+func TestConfigBuilderBuildsConfig(t *testing.T) {
+	config, err := NewConfigBuilder().
+		WithStaticDir("./public").
+		WithAdminPanel(false).
+		WithWebSocketPath("/live").
+		WithSSEPath("/live-events").
+		WithAutoRegister("shop", "components").
+		WithShutdownGrace(3 * time.Second).
+		WithTheme(tmpl.ThemeLight).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	if config.StaticDir != "./public" || config.EnableAdminPanel || config.WebSocketPath != "/live" {
+		t.Errorf("got static dir %q, admin panel %v, websocket path %q", config.StaticDir, config.EnableAdminPanel, config.WebSocketPath)
+	}
+	if config.SSEPath != "/live-events" {
+		t.Errorf("got sse path %q", config.SSEPath)
+	}
+	if config.AutoRegisterNamespace != "shop" || len(config.AutoRegisterDirs) != 1 || config.AutoRegisterDirs[0] != "components" {
+		t.Errorf("got auto-register %q %v", config.AutoRegisterNamespace, config.AutoRegisterDirs)
+	}
+	if config.ShutdownGrace != 3*time.Second || config.Theme != tmpl.ThemeLight {
+		t.Errorf("got shutdown grace %v, theme %q", config.ShutdownGrace, config.Theme)
+	}
+
+	// Unset values keep their defaults
+	if config.Router == nil || config.ServeMux == nil || !config.UseBaseTemplate {
+		t.Error("builder dropped default values")
+	}
+}
+
+func TestConfigBuilderRejectsInvalidValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *ConfigBuilder
+		want    string
+	}{
+		{"empty static dir", NewConfigBuilder().WithStaticDir(" "), "static dir is required"},
+		{"relative websocket path", NewConfigBuilder().WithWebSocketPath("ws"), "must be an absolute path"},
+		{"reserved websocket path", NewConfigBuilder().WithWebSocketPath("/_/ws"), "conflicts with reserved path /_"},
+		{"relative sse path", NewConfigBuilder().WithSSEPath("events"), `sse path "events" must be an absolute path`},
+		{"reserved sse path", NewConfigBuilder().WithSSEPath("/static/sse"), "conflicts with reserved path /static"},
+		{"websocket on sse path", NewConfigBuilder().WithWebSocketPath("/sse"), "websocket and sse paths must differ"},
+		{"dirs without namespace", NewConfigBuilder().WithAutoRegister("", "components"), "namespace is required"},
+		{"empty asset URL", NewConfigBuilder().WithSelfHostedAssets(""), "asset URLs must not be empty"},
+		{"unknown theme", NewConfigBuilder().WithTheme("neon"), `unknown theme "neon"`},
+		{"negative grace", NewConfigBuilder().WithShutdownGrace(-time.Second), "shutdown grace must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.builder.Build()
+			if err == nil {
+				t.Fatal("Build succeeded, want an error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigBuilderReportsEveryProblem(t *testing.T) {
+	_, err := NewConfigBuilder().WithStaticDir("").WithTheme("neon").Build()
+	if err == nil {
+		t.Fatal("Build succeeded, want an error")
+	}
+	for _, want := range []string{"static dir is required", "unknown theme"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
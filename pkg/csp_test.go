@@ -0,0 +1,119 @@
+package pkg
+
+import (
+	"html"
+	"html/template"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/magooney-loon/webrender/pkg/component"
+	tmpl "github.com/magooney-loon/webrender/pkg/template"
+)
+
+var (
+	nonceSourcePattern = regexp.MustCompile(`'nonce-([^']+)'`)
+	nonceAttrPattern   = regexp.MustCompile(`<(script|style)[^>]* nonce="([^"]+)"`)
+)
+
+// directive returns the sources of a CSP directive
+func directive(policy, name string) string {
+	for _, part := range strings.Split(policy, ";") {
+		part = strings.TrimSpace(part)
+		if strings.HasPrefix(part, name+" ") {
+			return part
+		}
+	}
+	return ""
+}
+
+func TestCSPNonceMatchesInlineTags(t *testing.T) {
+	tests := []struct {
+		name           string
+		selfHosted     bool
+		wantStyleNonce bool
+	}{
+		{"self-hosted assets", true, true},
+		{"CDN assets", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wr := newTestWebRender(t, func(c *Config) {
+				c.ContentSecurityPolicy = "default-src 'self'"
+				c.Assets = tmpl.Assets{SelfHosted: tt.selfHosted}
+			})
+			wr.RouteWithTemplate("/", "Home", func() (template.HTML, error) {
+				return "<p>home</p>", nil
+			}, nil, nil)
+
+			rec := get(wr, "/")
+			policy := rec.Header().Get("Content-Security-Policy")
+			match := nonceSourcePattern.FindStringSubmatch(directive(policy, "script-src"))
+			if match == nil {
+				t.Fatalf("script-src has no nonce: %s", policy)
+			}
+			nonce := match[1]
+
+			// Attribute values may be entity-escaped; browsers decode them
+			tags := nonceAttrPattern.FindAllStringSubmatch(rec.Body.String(), -1)
+			seen := map[string]bool{}
+			for _, tag := range tags {
+				if got := html.UnescapeString(tag[2]); got != nonce {
+					t.Errorf("<%s> nonce = %q, want the header's %q", tag[1], got, nonce)
+				}
+				seen[tag[1]] = true
+			}
+			if !seen["script"] || !seen["style"] {
+				t.Errorf("nonced tags = %v, want inline scripts and styles", seen)
+			}
+
+			styleSrc := directive(policy, "style-src")
+			if got := strings.Contains(styleSrc, "'nonce-"+nonce+"'"); got != tt.wantStyleNonce {
+				t.Errorf("style-src nonce = %v, want %v: %s", got, tt.wantStyleNonce, styleSrc)
+			}
+			if !tt.wantStyleNonce && !strings.Contains(styleSrc, "'unsafe-inline'") {
+				t.Errorf("style-src must allow the CDN's injected styles: %s", styleSrc)
+			}
+		})
+	}
+}
+
+func TestComponentCSPSourcesMergedIntoPage(t *testing.T) {
+	wr := newTestWebRender(t, func(c *Config) {
+		c.ContentSecurityPolicy = "default-src 'self'; img-src 'self'"
+	})
+
+	tiles := component.New("map-1", "map", `<div id="map"></div>`)
+	if err := tiles.CSPSources(map[string][]string{
+		"img-src":     {"https://tiles.example.com"},
+		"connect-src": {"https://api.example.com"},
+	}); err != nil {
+		t.Fatalf("CSPSources: %v", err)
+	}
+	for _, c := range []*component.Component{tiles, component.New("text-1", "text", `<p>text</p>`)} {
+		if err := wr.RegisterComponent(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wr.ComponentRoute("/map", "Map", "map-1", nil, nil, nil)
+	wr.ComponentRoute("/text", "Text", "text-1", nil, nil, nil)
+
+	policy := get(wr, "/map").Header().Get("Content-Security-Policy")
+	if got, want := directive(policy, "img-src"), "img-src 'self' https://tiles.example.com"; got != want {
+		t.Errorf("img-src = %q, want %q", got, want)
+	}
+	// Missing directives start from default-src
+	if got, want := directive(policy, "connect-src"), "connect-src 'self' https://api.example.com"; got != want {
+		t.Errorf("connect-src = %q, want %q", got, want)
+	}
+	if got := directive(policy, "default-src"); got != "default-src 'self'" {
+		t.Errorf("default-src changed to %q", got)
+	}
+
+	// Pages without the component keep the base policy
+	policy = get(wr, "/text").Header().Get("Content-Security-Policy")
+	if strings.Contains(policy, "tiles.example.com") || strings.Contains(policy, "api.example.com") {
+		t.Errorf("page without the map component got its sources: %s", policy)
+	}
+}
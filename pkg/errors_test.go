@@ -0,0 +1,44 @@
+package pkg
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestErrorPages(t *testing.T) {
+	wr := newTestWebRender(t)
+	wr.Route("/boom", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	// Defaults render inside the base layout
+	for path, status := range map[string]int{"/missing": http.StatusNotFound, "/boom": http.StatusInternalServerError} {
+		rec := get(wr, path)
+		if rec.Code != status {
+			t.Errorf("%s: status = %d, want %d", path, rec.Code, status)
+		}
+		body := rec.Body.String()
+		if !strings.Contains(body, "<!DOCTYPE html>") || !strings.Contains(body, http.StatusText(status)) {
+			t.Errorf("%s: default page missing the layout or status text:\n%s", path, body)
+		}
+	}
+
+	// A custom page replaces the default for its status only
+	wr.SetErrorPage(http.StatusNotFound, template.Must(template.New("404").Parse(`<h1>Lost: {{.Path}}</h1>`)))
+
+	rec := get(wr, "/missing")
+	if rec.Code != http.StatusNotFound || rec.Body.String() != "<h1>Lost: /missing</h1>" {
+		t.Errorf("custom 404: status %d, body %q", rec.Code, rec.Body.String())
+	}
+	if rec := get(wr, "/boom"); strings.Contains(rec.Body.String(), "Lost") {
+		t.Error("the custom 404 page was used for a 500")
+	}
+
+	// Unsetting it falls back to the default again
+	wr.SetErrorPage(http.StatusNotFound, nil)
+	if rec := get(wr, "/missing"); strings.Contains(rec.Body.String(), "Lost") {
+		t.Error("the custom 404 page is still used after removing it")
+	}
+}
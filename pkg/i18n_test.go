@@ -0,0 +1,36 @@
+package pkg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/magooney-loon/webrender/pkg/component"
+)
+
+func TestComponentRouteUsesAcceptLanguage(t *testing.T) {
+	wr := newTestWebRender(t)
+	wr.ComponentRegistry.SetCatalog(component.MapCatalog{
+		"en": {"greeting": "Hello"},
+		"fr": {"greeting": "Bonjour"},
+	})
+	if err := wr.RegisterComponent(component.New("greeter-1", "greeter", `<p>{{t "greeting"}}</p>`)); err != nil {
+		t.Fatal(err)
+	}
+	wr.ComponentRoute("/greet", "Greeting", "greeter-1", nil, nil, nil)
+
+	for header, want := range map[string]string{
+		"fr-FR,fr;q=0.9": "<p>Bonjour</p>",
+		"en-US":          "<p>Hello</p>",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/greet", nil)
+		req.Header.Set("Accept-Language", header)
+		rec := httptest.NewRecorder()
+		wr.Router.ServeHTTP(rec, req)
+
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("Accept-Language %q: page does not contain %s", header, want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Package logger defines the logging interface used across WebRender
+package logger
+
+import (
+	"io"
+	"log"
+)
+
+// Level is the minimum severity a logger writes
+type Level int
+
+const (
+	// LevelDebug logs everything, including per-message chatter
+	LevelDebug Level = iota
+	// LevelInfo logs lifecycle events such as connections and registrations
+	LevelInfo
+	// LevelWarn logs recoverable problems
+	LevelWarn
+	// LevelError logs failures only
+	LevelError
+)
+
+// Logger receives WebRender's log output
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// stdLogger adapts a standard library logger, dropping messages below level
+type stdLogger struct {
+	out   *log.Logger
+	level Level
+}
+
+// New returns a Logger writing to out at or above level
+func New(out *log.Logger, level Level) Logger {
+	return &stdLogger{out: out, level: level}
+}
+
+// Default returns a Logger writing everything to the standard logger
+func Default() Logger {
+	return New(log.Default(), LevelDebug)
+}
+
+// Discard returns a Logger that drops all messages
+func Discard() Logger {
+	return New(log.New(io.Discard, "", 0), LevelError+1)
+}
+
+// Debugf logs a debug message
+func (l *stdLogger) Debugf(format string, args ...interface{}) {
+	l.logf(LevelDebug, format, args...)
+}
+
+// Infof logs an informational message
+func (l *stdLogger) Infof(format string, args ...interface{}) {
+	l.logf(LevelInfo, format, args...)
+}
+
+// Warnf logs a warning
+func (l *stdLogger) Warnf(format string, args ...interface{}) {
+	l.logf(LevelWarn, format, args...)
+}
+
+// Errorf logs an error
+func (l *stdLogger) Errorf(format string, args ...interface{}) {
+	l.logf(LevelError, format, args...)
+}
+
+// logf writes a message if it meets the minimum level
+func (l *stdLogger) logf(level Level, format string, args ...interface{}) {
+	if level < l.level {
+		return
+	}
+	l.out.Printf(format, args...)
+}
@@ -0,0 +1,21 @@
+package logger
+
+import (
+	"bytes"
+	"log"
+	"testing"
+)
+
+func TestLevelFiltering(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(log.New(&buf, "", 0), LevelWarn)
+
+	l.Debugf("debug %d", 1)
+	l.Infof("info %d", 2)
+	l.Warnf("warn %d", 3)
+	l.Errorf("error %d", 4)
+
+	if got, want := buf.String(), "warn 3\nerror 4\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,111 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingLogger keeps every message it is given
+type recordingLogger struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+func (l *recordingLogger) record(level, format string, args ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.messages = append(l.messages, level+": "+fmt.Sprintf(format, args...))
+}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) {
+	l.record("debug", format, args...)
+}
+
+func (l *recordingLogger) Infof(format string, args ...interface{}) {
+	l.record("info", format, args...)
+}
+
+func (l *recordingLogger) Warnf(format string, args ...interface{}) {
+	l.record("warn", format, args...)
+}
+
+func (l *recordingLogger) Errorf(format string, args ...interface{}) {
+	l.record("error", format, args...)
+}
+
+// logged reports whether a message containing text was logged
+func (l *recordingLogger) logged(text string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for _, message := range l.messages {
+		if strings.Contains(message, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// waitForLog polls until a message containing text is logged or fails the
+// test after a second
+func waitForLog(t *testing.T, l *recordingLogger, text string) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !l.logged(text) {
+		if time.Now().After(deadline) {
+			l.mutex.Lock()
+			defer l.mutex.Unlock()
+			t.Fatalf("logger did not receive %q; got %q", text, l.messages)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestInjectedLoggerReceivesAutoRegistration(t *testing.T) {
+	rec := &recordingLogger{}
+	wr := newTestWebRender(t, func(c *Config) { c.Logger = rec })
+
+	dir := t.TempDir()
+	source := `package widgets
+
+import "github.com/magooney-loon/webrender/pkg/component"
+
+func NewWidget(id string) *component.Component { return nil }
+`
+	if err := os.WriteFile(filepath.Join(dir, "widget.go"), []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.go"), []byte("package widgets\nfunc {"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := wr.AutoRegisterComponents(dir, "test"); err != nil {
+		t.Fatalf("AutoRegisterComponents: %v", err)
+	}
+
+	for _, want := range []string{
+		"debug: Auto-registered component 'NewWidget'",
+		"info: Auto-registration for " + dir + ": 1 registered, 1 skipped, 0 failed",
+		"warn: Auto-registration skipped " + filepath.Join(dir, "broken.go"),
+	} {
+		if !rec.logged(want) {
+			t.Errorf("logger did not receive %q; got %q", want, rec.messages)
+		}
+	}
+}
+
+func TestInjectedLoggerReceivesWebSocketLifecycle(t *testing.T) {
+	rec := &recordingLogger{}
+	wr := newTestWebRender(t, func(c *Config) { c.Logger = rec })
+
+	conn := dialWebSocket(t, wr)
+	waitForLog(t, rec, "info: WebSocket client registered")
+
+	conn.Close()
+	waitForLog(t, rec, "info: WebSocket client unregistered")
+}
@@ -0,0 +1,202 @@
+package router
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// cspNonceKey is the context key for the per-request CSP nonce
+type cspNonceKey struct{}
+
+// CSPNonceMiddleware generates a nonce for every request and writes a
+// Content-Security-Policy header whose script-src and style-src directives
+// allow that nonce instead of 'unsafe-inline'.
+// An existing CSP header set by earlier middleware is rewritten; otherwise
+// defaultPolicy is used. Templates read the nonce with CSPNonce.
+func CSPNonceMiddleware(defaultPolicy string) func(http.Handler) http.Handler {
+	return cspNonceMiddleware(defaultPolicy, false)
+}
+
+// CSPScriptNonceMiddleware is CSPNonceMiddleware for pages whose styles
+// can't all carry the nonce, such as those using the Tailwind play CDN,
+// which injects <style> tags at runtime. Only script-src gets the nonce;
+// style-src allows 'unsafe-inline' instead, since browsers ignore
+// 'unsafe-inline' in a directive that also lists a nonce.
+func CSPScriptNonceMiddleware(defaultPolicy string) func(http.Handler) http.Handler {
+	return cspNonceMiddleware(defaultPolicy, true)
+}
+
+// cspNonceMiddleware implements the CSP nonce middlewares
+func cspNonceMiddleware(defaultPolicy string, inlineStyles bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nonce, err := generateNonce()
+			if err != nil {
+				http.Error(w, "Failed to generate CSP nonce", http.StatusInternalServerError)
+				return
+			}
+
+			policy := w.Header().Get("Content-Security-Policy")
+			if policy == "" {
+				policy = defaultPolicy
+			}
+			w.Header().Set("Content-Security-Policy", applyNonce(policy, nonce, inlineStyles))
+
+			ctx := context.WithValue(r.Context(), cspNonceKey{}, nonce)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// CSPNonce returns the CSP nonce for the request, or an empty string when
+// CSPNonceMiddleware is not in use
+func CSPNonce(r *http.Request) string {
+	if nonce, ok := r.Context().Value(cspNonceKey{}).(string); ok {
+		return nonce
+	}
+	return ""
+}
+
+// generateNonce returns a random base64 encoded nonce
+func generateNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// applyNonce adds the nonce to script-src and style-src, dropping
+// 'unsafe-inline' from them. Missing directives are added.
+// With inlineStyles, style-src allows 'unsafe-inline' instead of the nonce;
+// a missing style-src starts from default-src, or is left out when
+// default-src is missing too.
+func applyNonce(policy, nonce string, inlineStyles bool) string {
+	nonceSource := "'nonce-" + nonce + "'"
+	found := map[string]bool{}
+	var defaultSources []string
+
+	var directives []string
+	for _, directive := range strings.Split(policy, ";") {
+		fields := strings.Fields(directive)
+		if len(fields) == 0 {
+			continue
+		}
+
+		name := strings.ToLower(fields[0])
+		if name == "default-src" && defaultSources == nil {
+			defaultSources = append([]string{}, fields[1:]...)
+		}
+		if name == "style-src" && inlineStyles {
+			found[name] = true
+			if !containsSource(fields, "'unsafe-inline'") {
+				fields = append(fields, "'unsafe-inline'")
+			}
+		} else if name == "script-src" || name == "style-src" {
+			found[name] = true
+
+			sources := []string{fields[0]}
+			for _, source := range fields[1:] {
+				if source != "'unsafe-inline'" {
+					sources = append(sources, source)
+				}
+			}
+			fields = append(sources, nonceSource)
+		}
+
+		directives = append(directives, strings.Join(fields, " "))
+	}
+
+	if !found["script-src"] {
+		directives = append(directives, "script-src 'self' "+nonceSource)
+	}
+	if !found["style-src"] {
+		switch {
+		case !inlineStyles:
+			directives = append(directives, "style-src 'self' "+nonceSource)
+		case defaultSources != nil:
+			sources := defaultSources
+			if len(sources) == 1 && strings.EqualFold(sources[0], "'none'") {
+				sources = nil
+			}
+			directives = append(directives, strings.Join(append(append([]string{"style-src"}, sources...), "'unsafe-inline'"), " "))
+		}
+	}
+
+	return strings.Join(directives, "; ")
+}
+
+// MergeCSPSources adds sources to the directives of a policy
+// Only the given sources are added. A directive missing from the policy
+// starts from default-src so other resource types aren't loosened; when
+// default-src is missing too the resource type is unrestricted and left
+// alone. A lone 'none' is replaced by the added sources.
+func MergeCSPSources(policy string, sources map[string][]string) string {
+	type directive struct {
+		name    string
+		sources []string
+	}
+
+	var directives []*directive
+	byName := map[string]*directive{}
+	for _, part := range strings.Split(policy, ";") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+
+		d := &directive{name: fields[0], sources: fields[1:]}
+		directives = append(directives, d)
+		if _, seen := byName[strings.ToLower(d.name)]; !seen {
+			byName[strings.ToLower(d.name)] = d
+		}
+	}
+
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		d, exists := byName[strings.ToLower(name)]
+		if !exists {
+			fallback, ok := byName["default-src"]
+			if !ok {
+				continue
+			}
+			d = &directive{name: name, sources: append([]string(nil), fallback.sources...)}
+			directives = append(directives, d)
+			byName[strings.ToLower(name)] = d
+		}
+
+		for _, source := range sources[name] {
+			if len(d.sources) == 1 && strings.EqualFold(d.sources[0], "'none'") {
+				d.sources = nil
+			}
+			if !containsSource(d.sources, source) {
+				d.sources = append(d.sources, source)
+			}
+		}
+	}
+
+	parts := make([]string, 0, len(directives))
+	for _, d := range directives {
+		parts = append(parts, strings.Join(append([]string{d.name}, d.sources...), " "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// containsSource reports whether sources contains source
+func containsSource(sources []string, source string) bool {
+	for _, s := range sources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
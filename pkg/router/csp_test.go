@@ -0,0 +1,67 @@
+package router
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyNonce(t *testing.T) {
+	const nonce = "abc"
+	tests := []struct {
+		name         string
+		policy       string
+		inlineStyles bool
+		want         string
+	}{
+		{
+			name:   "nonce replaces unsafe-inline",
+			policy: "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'",
+			want:   "default-src 'self'; script-src 'self' 'nonce-abc'; style-src 'self' 'nonce-abc'",
+		},
+		{
+			name:   "missing directives added",
+			policy: "default-src 'self'",
+			want:   "default-src 'self'; script-src 'self' 'nonce-abc'; style-src 'self' 'nonce-abc'",
+		},
+		{
+			name:         "inline styles keep unsafe-inline without the nonce",
+			policy:       "default-src 'self'; style-src 'self' https://fonts.googleapis.com",
+			inlineStyles: true,
+			want:         "default-src 'self'; style-src 'self' https://fonts.googleapis.com 'unsafe-inline'; script-src 'self' 'nonce-abc'",
+		},
+		{
+			name:         "inline styles start from default-src",
+			policy:       "default-src 'self' https://cdn.example.com",
+			inlineStyles: true,
+			want:         "default-src 'self' https://cdn.example.com; script-src 'self' 'nonce-abc'; style-src 'self' https://cdn.example.com 'unsafe-inline'",
+		},
+		{
+			name:         "inline styles unrestricted without default-src",
+			policy:       "img-src 'self'",
+			inlineStyles: true,
+			want:         "img-src 'self'; script-src 'self' 'nonce-abc'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := applyNonce(tt.policy, nonce, tt.inlineStyles)
+			if got != tt.want {
+				t.Errorf("applyNonce() =\n  %s\nwant\n  %s", got, tt.want)
+			}
+			if tt.inlineStyles && strings.Contains(got, "style-src") && strings.Contains(styleSrc(got), "nonce-") {
+				t.Errorf("style-src lists a nonce, which disables 'unsafe-inline': %s", got)
+			}
+		})
+	}
+}
+
+// styleSrc returns the style-src directive of a policy
+func styleSrc(policy string) string {
+	for _, directive := range strings.Split(policy, ";") {
+		if strings.HasPrefix(strings.TrimSpace(directive), "style-src") {
+			return directive
+		}
+	}
+	return ""
+}
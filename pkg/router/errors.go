@@ -0,0 +1,125 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"sync"
+)
+
+// ErrorPageData is passed to error page templates
+type ErrorPageData struct {
+	Status     int
+	StatusText string
+	Message    string
+	Path       string
+	Nonce      string
+}
+
+// ErrorPages renders HTML pages for HTTP error statuses
+// Templates registered with Set take precedence; other statuses are
+// rendered by the fallback function, or as plain text when none is set.
+type ErrorPages struct {
+	pages    map[int]*template.Template
+	fallback func(w http.ResponseWriter, r *http.Request, data ErrorPageData)
+	mutex    sync.RWMutex
+}
+
+// NewErrorPages creates an empty set of error pages
+func NewErrorPages() *ErrorPages {
+	return &ErrorPages{
+		pages: make(map[int]*template.Template),
+	}
+}
+
+// Set registers the template rendered for a status code
+// Passing a nil template removes it.
+func (e *ErrorPages) Set(status int, tmpl *template.Template) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if tmpl == nil {
+		delete(e.pages, status)
+		return
+	}
+	e.pages[status] = tmpl
+}
+
+// SetFallback sets the function that renders statuses without a template
+func (e *ErrorPages) SetFallback(fallback func(w http.ResponseWriter, r *http.Request, data ErrorPageData)) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.fallback = fallback
+}
+
+// Render writes the error page for status to the response
+func (e *ErrorPages) Render(w http.ResponseWriter, r *http.Request, status int, message string) {
+	data := ErrorPageData{
+		Status:     status,
+		StatusText: http.StatusText(status),
+		Message:    message,
+		Path:       r.URL.Path,
+		Nonce:      CSPNonce(r),
+	}
+
+	e.mutex.RLock()
+	tmpl := e.pages[status]
+	fallback := e.fallback
+	e.mutex.RUnlock()
+
+	if tmpl != nil {
+		// Render into a buffer so a failing template doesn't leave a partial page
+		var buf bytes.Buffer
+		err := tmpl.Execute(&buf, data)
+		if err == nil {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(status)
+			w.Write(buf.Bytes())
+			return
+		}
+		log.Printf("Error rendering %d page: %v", status, err)
+	}
+
+	if fallback != nil {
+		fallback(w, r, data)
+		return
+	}
+
+	http.Error(w, fmt.Sprintf("%d %s", status, data.StatusText), status)
+}
+
+// NotFoundHandler returns a handler that renders the 404 page
+func (e *ErrorPages) NotFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e.Render(w, r, http.StatusNotFound, "The page you're looking for doesn't exist.")
+	})
+}
+
+// MethodNotAllowedHandler returns a handler that renders the 405 page
+func (e *ErrorPages) MethodNotAllowedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e.Render(w, r, http.StatusMethodNotAllowed, fmt.Sprintf("%s is not allowed for this page.", r.Method))
+	})
+}
+
+// RecoveryMiddleware recovers from panics and renders the 500 page
+func (e *ErrorPages) RecoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				// Let the server handle deliberate aborts
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
+				log.Printf("Recovered from panic: %v\n%s", err, debug.Stack())
+				e.Render(w, r, http.StatusInternalServerError, "Something went wrong on our end.")
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,137 @@
+package router
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// maintenancePage is served to blocked requests while maintenance is enabled
+var maintenancePage = template.Must(template.New("maintenance").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+	<meta charset="UTF-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1.0">
+	<title>Down for maintenance</title>
+	<style{{if .Nonce}} nonce="{{.Nonce}}"{{end}}>
+		body {
+			background: radial-gradient(circle at center top, #111, #000);
+			color: #fff;
+			font-family: Inter, ui-sans-serif, system-ui, -apple-system, sans-serif;
+			min-height: 100vh;
+			margin: 0;
+			display: flex;
+			align-items: center;
+			justify-content: center;
+		}
+		.card {
+			background: rgba(32, 32, 36, 0.5);
+			border: 1px solid rgba(63, 63, 70, 0.5);
+			border-radius: 0.5rem;
+			padding: 2rem;
+			max-width: 28rem;
+			text-align: center;
+		}
+		p { color: #888c94; }
+	</style>
+</head>
+<body>
+	<div class="card">
+		<h1>Down for maintenance</h1>
+		<p>We're making some improvements and will be back shortly.</p>
+	</div>
+</body>
+</html>
+`))
+
+// Maintenance serves a 503 page to every request while enabled, except for
+// exempt paths and requests accepted by the bypass function (e.g. admins).
+// It can be toggled at runtime.
+type Maintenance struct {
+	enabled        bool
+	exemptPrefixes []string
+	bypass         func(r *http.Request) bool
+	mutex          sync.RWMutex
+}
+
+// NewMaintenance creates a disabled maintenance toggle
+// The admin panel, static files, and the default WebSocket (/ws) and SSE
+// (/sse) endpoints are exempt by default; exempt other endpoint paths with
+// Exempt.
+func NewMaintenance() *Maintenance {
+	return &Maintenance{
+		exemptPrefixes: []string{"/_/", "/static/", "/ws", "/sse"},
+	}
+}
+
+// SetEnabled turns maintenance mode on or off
+func (m *Maintenance) SetEnabled(enabled bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.enabled = enabled
+}
+
+// Enabled reports whether maintenance mode is on
+func (m *Maintenance) Enabled() bool {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.enabled
+}
+
+// Exempt adds path prefixes (such as health checks) that stay reachable
+// A prefix matches on path segment boundaries: "/health" exempts /health
+// and /health/live but not /healthz.
+func (m *Maintenance) Exempt(prefixes ...string) *Maintenance {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.exemptPrefixes = append(m.exemptPrefixes, prefixes...)
+	return m
+}
+
+// SetBypass sets a function that lets matching requests through
+func (m *Maintenance) SetBypass(bypass func(r *http.Request) bool) *Maintenance {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.bypass = bypass
+	return m
+}
+
+// allowed reports whether a request may pass while maintenance is enabled
+func (m *Maintenance) allowed(r *http.Request) bool {
+	m.mutex.RLock()
+	prefixes := m.exemptPrefixes
+	bypass := m.bypass
+	m.mutex.RUnlock()
+
+	for _, prefix := range prefixes {
+		if hasPathPrefix(r.URL.Path, prefix) {
+			return true
+		}
+	}
+
+	return bypass != nil && bypass(r)
+}
+
+// hasPathPrefix reports whether path is prefix or lies beneath it
+func hasPathPrefix(path, prefix string) bool {
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || strings.HasSuffix(prefix, "/") || path[len(prefix)] == '/'
+}
+
+// Middleware blocks non-exempt requests with a 503 page while enabled
+func (m *Maintenance) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !m.Enabled() || m.allowed(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		maintenancePage.Execute(w, map[string]string{"Nonce": CSPNonce(r)})
+	})
+}
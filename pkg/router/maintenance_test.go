@@ -0,0 +1,75 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMaintenanceExemptPaths(t *testing.T) {
+	m := NewMaintenance().Exempt("/health")
+	m.SetEnabled(true)
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/ws", http.StatusOK},
+		{"/sse", http.StatusOK},
+		{"/sse/message", http.StatusOK},
+		{"/static/app.css", http.StatusOK},
+		{"/_/dashboard", http.StatusOK},
+		{"/health", http.StatusOK},
+		{"/health/live", http.StatusOK},
+		{"/wsx", http.StatusServiceUnavailable},
+		{"/sse-admin", http.StatusServiceUnavailable},
+		{"/healthz", http.StatusServiceUnavailable},
+		{"/", http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.path, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestMaintenanceToggle(t *testing.T) {
+	m := NewMaintenance().SetBypass(func(r *http.Request) bool {
+		return r.Header.Get("X-Admin") == "yes"
+	})
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(admin bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if admin {
+			req.Header.Set("X-Admin", "yes")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve(false); rec.Code != http.StatusOK {
+		t.Fatalf("disabled: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	m.SetEnabled(true)
+	rec := serve(false)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("enabled: status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("enabled: 503 without a Retry-After header")
+	}
+	if rec := serve(true); rec.Code != http.StatusOK {
+		t.Errorf("enabled, admin: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	m.SetEnabled(false)
+	if rec := serve(false); rec.Code != http.StatusOK {
+		t.Errorf("disabled again: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
@@ -12,10 +12,16 @@ import (
 )
 
 // StandardMiddleware adds a set of common middleware to a router
+// Panics render the router's 500 page when it has error pages.
 func StandardMiddleware(r *Router) *Router {
+	recovery := RecoveryMiddleware
+	if r.ErrorPages != nil {
+		recovery = r.ErrorPages.RecoveryMiddleware
+	}
+
 	return r.
 		UseMiddleware(LoggingMiddleware).
-		UseMiddleware(RecoveryMiddleware).
+		UseMiddleware(recovery).
 		UseMiddleware(CompressionMiddleware)
 }
 
@@ -13,14 +13,26 @@ import (
 type Router struct {
 	*mux.Router
 	middlewares []func(http.Handler) http.Handler
+
+	// Pages rendered for 404, 405, and recovered panics
+	ErrorPages *ErrorPages
 }
 
 // New creates a new Router instance
 func New() *Router {
-	return &Router{
+	r := &Router{
 		Router:      mux.NewRouter(),
 		middlewares: []func(http.Handler) http.Handler{},
 	}
+	return r.WithErrorPages(NewErrorPages())
+}
+
+// WithErrorPages uses the given error pages for unmatched routes and methods
+func (r *Router) WithErrorPages(pages *ErrorPages) *Router {
+	r.ErrorPages = pages
+	r.Router.NotFoundHandler = pages.NotFoundHandler()
+	r.Router.MethodNotAllowedHandler = pages.MethodNotAllowedHandler()
+	return r
 }
 
 // WithStrictSlash sets the router's StrictSlash option
@@ -40,6 +52,7 @@ func (r *Router) Group(pathPrefix string) *Router {
 	return &Router{
 		Router:      r.Router.PathPrefix(pathPrefix).Subrouter(),
 		middlewares: r.middlewares,
+		ErrorPages:  r.ErrorPages,
 	}
 }
 
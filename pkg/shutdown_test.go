@@ -0,0 +1,160 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	wsmanager "github.com/magooney-loon/webrender/pkg/websocket"
+)
+
+// dialWebSocket connects a WebSocket client to a test server for wr and
+// waits until it is registered
+func dialWebSocket(t *testing.T, wr *WebRender) *websocket.Conn {
+	t.Helper()
+
+	server := httptest.NewServer(wr)
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	deadline := time.Now().Add(time.Second)
+	for wr.WebSocketManager.Stats().Clients == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for client registration")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return conn
+}
+
+func TestShutdownWarnsClientsBeforeClosing(t *testing.T) {
+	const grace = 50 * time.Millisecond
+	wr := newTestWebRender(t, func(c *Config) { c.ShutdownGrace = grace })
+
+	conn := dialWebSocket(t, wr)
+
+	shutdownErr := make(chan error, 1)
+	started := time.Now()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		shutdownErr <- wr.Shutdown(ctx)
+	}()
+
+	// The notice arrives while the connection is still open
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	var notice wsmanager.ShutdownNotice
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("connection closed before the shutdown notice: %v", err)
+		}
+		var message wsmanager.Message
+		if err := json.Unmarshal(data, &message); err != nil {
+			t.Fatalf("decoding message: %v", err)
+		}
+		if message.Type == wsmanager.MessageTypeShutdown {
+			if err := json.Unmarshal(message.Payload, &notice); err != nil {
+				t.Fatalf("decoding shutdown notice: %v", err)
+			}
+			break
+		}
+	}
+	if notice.GraceMS != grace.Milliseconds() {
+		t.Errorf("grace_ms = %d, want %d", notice.GraceMS, grace.Milliseconds())
+	}
+
+	// Then the connection is closed once the grace period has passed
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+	if err := <-shutdownErr; err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed < grace {
+		t.Errorf("connections closed after %v, before the %v grace period", elapsed, grace)
+	}
+}
+
+func TestShutdownWithoutGraceSendsNoNotice(t *testing.T) {
+	wr := newTestWebRender(t)
+
+	conn := dialWebSocket(t, wr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := wr.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		var message wsmanager.Message
+		if json.Unmarshal(data, &message) == nil && message.Type == wsmanager.MessageTypeShutdown {
+			t.Error("shutdown notice sent without a grace period")
+		}
+	}
+}
+
+func TestStartWithContextStopsOnCancel(t *testing.T) {
+	wr := newTestWebRender(t)
+
+	// Reserve a free port for the server
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	startErr := make(chan error, 1)
+	go func() {
+		startErr <- wr.StartWithContext(ctx, addr)
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server never started listening: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case err := <-startErr:
+		if err != nil {
+			t.Fatalf("StartWithContext: %v", err)
+		}
+	case <-time.After(DefaultShutdownTimeout):
+		t.Fatal("StartWithContext did not return within the shutdown timeout")
+	}
+
+	if conn, err := net.Dial("tcp", addr); err == nil {
+		conn.Close()
+		t.Error("listener still accepting connections after shutdown")
+	}
+}
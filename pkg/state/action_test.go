@@ -0,0 +1,163 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/magooney-loon/webrender/pkg/component"
+	wsmanager "github.com/magooney-loon/webrender/pkg/websocket"
+)
+
+// sendAction runs an action message through the action handler
+func sendAction(t *testing.T, sm *StateManager, conn wsmanager.Conn, action wsmanager.ActionMessage) {
+	t.Helper()
+
+	payload, err := json.Marshal(action)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.handleAction(conn, payload)
+}
+
+// actionErrors decodes the action errors written to a connection
+func actionErrors(t *testing.T, conn *fakeConn) []wsmanager.ActionError {
+	t.Helper()
+
+	var errs []wsmanager.ActionError
+	for _, message := range conn.messages(wsmanager.MessageTypeActionError) {
+		var actionErr wsmanager.ActionError
+		if err := json.Unmarshal(message.Payload, &actionErr); err != nil {
+			t.Fatal(err)
+		}
+		errs = append(errs, actionErr)
+	}
+	return errs
+}
+
+// newCountingComponent registers a component whose "bump" action counts
+// its calls
+func newCountingComponent(t *testing.T, sm *StateManager, id string) *int {
+	t.Helper()
+
+	calls := new(int)
+	comp := component.New(id, "counter", "<div></div>")
+	comp.AddTypedMethod("bump", func(map[string]interface{}) error {
+		*calls++
+		return nil
+	})
+	if err := sm.componentRegistry.Register(comp); err != nil {
+		t.Fatal(err)
+	}
+	return calls
+}
+
+func TestActionsRefusedWhileDisabled(t *testing.T) {
+	sm := newTestStateManager(t)
+	calls := newCountingComponent(t, sm, "counter-1")
+	conn := newFakeConn()
+	action := wsmanager.ActionMessage{ComponentID: "counter-1", Action: "bump"}
+
+	sm.componentRegistry.SetEnabled("counter-1", false)
+	sendAction(t, sm, conn, action)
+	if *calls != 0 {
+		t.Errorf("method ran %d times on a disabled component", *calls)
+	}
+	if errs := actionErrors(t, conn); len(errs) != 1 || errs[0].Error != "component is disabled" {
+		t.Errorf("action errors = %+v, want one \"component is disabled\"", errs)
+	}
+
+	sm.componentRegistry.SetEnabled("counter-1", true)
+	sendAction(t, sm, conn, action)
+	if *calls != 1 {
+		t.Errorf("method ran %d times after re-enabling, want 1", *calls)
+	}
+}
+
+func TestFailedActionsReportedToClient(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	comp := component.New("form-1", "form", "<div></div>")
+	comp.AddTypedMethod("submit", func(params map[string]interface{}) error {
+		if params["email"] == nil {
+			return errors.New("email is required")
+		}
+		return nil
+	})
+	comp.Methods["legacy"] = func() {}
+	if err := sm.componentRegistry.Register(comp); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		action wsmanager.ActionMessage
+		want   string
+	}{
+		{"unknown component", wsmanager.ActionMessage{ComponentID: "missing-1", Action: "submit"}, "component not found"},
+		{"unknown method", wsmanager.ActionMessage{ComponentID: "form-1", Action: "reset"}, component.ErrMethodNotFound.Error()},
+		{"method error", wsmanager.ActionMessage{ComponentID: "form-1", Action: "submit"}, "email is required"},
+		{"invalid method", wsmanager.ActionMessage{ComponentID: "form-1", Action: "legacy"}, component.ErrInvalidMethod.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := newFakeConn()
+			sendAction(t, sm, conn, tt.action)
+
+			errs := actionErrors(t, conn)
+			if len(errs) != 1 {
+				t.Fatalf("got %d action errors, want 1", len(errs))
+			}
+			got := errs[0]
+			if got.ComponentID != tt.action.ComponentID || got.Action != tt.action.Action {
+				t.Errorf("error is for %s/%s, want %s/%s", got.ComponentID, got.Action, tt.action.ComponentID, tt.action.Action)
+			}
+			if !strings.HasPrefix(got.Error, tt.want) {
+				t.Errorf("error = %q, want %q", got.Error, tt.want)
+			}
+		})
+	}
+
+	// Successful actions send no error
+	conn := newFakeConn()
+	sendAction(t, sm, conn, wsmanager.ActionMessage{
+		ComponentID: "form-1",
+		Action:      "submit",
+		Params:      map[string]interface{}{"email": "a@example.com"},
+	})
+	if errs := actionErrors(t, conn); len(errs) != 0 {
+		t.Errorf("successful action reported errors %+v", errs)
+	}
+}
+
+func TestClientWritesToReadOnlyKeysRejected(t *testing.T) {
+	sm := newTestStateManager(t)
+	comp := component.New("counter-1", "counter", "<div></div>")
+	comp.State.Set("count", 3)
+	comp.State.Set("label", "Clicks")
+	comp.ClientReadOnly("count")
+	if err := sm.componentRegistry.Register(comp); err != nil {
+		t.Fatal(err)
+	}
+	conn := newFakeConn()
+
+	for _, update := range []string{
+		`{"component_id":"counter-1","key":"count","value":1000,"type":"update"}`,
+		`{"component_id":"counter-1","key":"count","type":"delete"}`,
+		`{"component_id":"counter-1","key":"label","value":"Taps","type":"update"}`,
+	} {
+		sm.handleStateUpdate(conn, []byte(update))
+	}
+
+	if got, _ := comp.State.GetInt("count"); got != 3 {
+		t.Errorf("count = %d after client writes, want 3", got)
+	}
+	if got := comp.State.Get("label"); got != "Taps" {
+		t.Errorf("label = %v, want the writable key updated", got)
+	}
+	errs := actionErrors(t, conn)
+	if len(errs) != 2 || errs[0].Action != "update:count" || errs[1].Action != "delete:count" {
+		t.Errorf("action errors = %+v, want the update and delete of count refused", errs)
+	}
+}
@@ -0,0 +1,67 @@
+package state
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/magooney-loon/webrender/pkg/component"
+	"github.com/magooney-loon/webrender/pkg/logger"
+	wsmanager "github.com/magooney-loon/webrender/pkg/websocket"
+)
+
+func TestCloseStopsEverything(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	opts := wsmanager.DefaultManagerOptions()
+	opts.Logger = logger.Discard()
+	sm := NewStateManagerWithOptions(opts)
+
+	destroyed := false
+	c := component.New("clock-1", "clock", `<div></div>`)
+	c.Lifecycle.OnDestroy = func(*component.Component) error {
+		destroyed = true
+		return nil
+	}
+	if err := sm.RegisterComponent(c); err != nil {
+		t.Fatalf("RegisterComponent: %v", err)
+	}
+	conn := connect(t, sm)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := sm.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if !destroyed {
+		t.Error("OnDestroy did not run")
+	}
+	if n := len(sm.GetComponentRegistry().GetAll()); n != 0 {
+		t.Errorf("%d components still registered", n)
+	}
+	select {
+	case <-conn.closed:
+	default:
+		t.Error("client connection left open")
+	}
+
+	// The run loop, heartbeat, and client reader have all exited
+	waitFor(t, "goroutines to exit", func() bool {
+		return runtime.NumGoroutine() <= baseline
+	})
+}
+
+func TestCloseHonorsContext(t *testing.T) {
+	sm := newTestStateManager(t)
+	if err := sm.RegisterComponent(component.New("clock-1", "clock", `<div></div>`)); err != nil {
+		t.Fatalf("RegisterComponent: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sm.Close(ctx); err == nil {
+		t.Error("Close with a cancelled context returned nil")
+	}
+}
@@ -0,0 +1,147 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/magooney-loon/webrender/pkg/logger"
+	wsmanager "github.com/magooney-loon/webrender/pkg/websocket"
+)
+
+// fakeConn is an in-memory connection that records what the manager writes
+type fakeConn struct {
+	incoming  chan inboundFrame
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	mutex   sync.Mutex
+	written []wsmanager.Message
+}
+
+// inboundFrame is a message queued for the manager to read
+type inboundFrame struct {
+	messageType int
+	data        []byte
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{
+		incoming: make(chan inboundFrame, 16),
+		closed:   make(chan struct{}),
+	}
+}
+
+func (c *fakeConn) ReadMessage() (int, []byte, error) {
+	select {
+	case frame := <-c.incoming:
+		return frame.messageType, frame.data, nil
+	case <-c.closed:
+		return 0, nil, errors.New("connection closed")
+	}
+}
+
+// send queues a text message from the client
+func (c *fakeConn) send(data string) {
+	c.incoming <- inboundFrame{messageType: websocket.TextMessage, data: []byte(data)}
+}
+
+// sendBinary queues a binary frame from the client
+func (c *fakeConn) sendBinary(data []byte) {
+	c.incoming <- inboundFrame{messageType: websocket.BinaryMessage, data: data}
+}
+
+func (c *fakeConn) WriteMessage(messageType int, data []byte) error {
+	var message wsmanager.Message
+	if err := json.Unmarshal(data, &message); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.written = append(c.written, message)
+	return nil
+}
+
+func (c *fakeConn) WriteControl(int, []byte, time.Time) error { return nil }
+func (c *fakeConn) SetReadDeadline(time.Time) error           { return nil }
+func (c *fakeConn) SetWriteDeadline(time.Time) error          { return nil }
+
+func (c *fakeConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+// messages returns the messages of one type written so far
+func (c *fakeConn) messages(msgType wsmanager.MessageType) []wsmanager.Message {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var matched []wsmanager.Message
+	for _, message := range c.written {
+		if message.Type == msgType {
+			matched = append(matched, message)
+		}
+	}
+	return matched
+}
+
+// stateUpdates decodes the state updates written so far
+func (c *fakeConn) stateUpdates(t *testing.T) []wsmanager.StateUpdate {
+	t.Helper()
+
+	var updates []wsmanager.StateUpdate
+	for _, message := range c.messages(wsmanager.MessageTypeStateUpdate) {
+		var update wsmanager.StateUpdate
+		if err := json.Unmarshal(message.Payload, &update); err != nil {
+			t.Fatalf("decoding state update: %v", err)
+		}
+		updates = append(updates, update)
+	}
+	return updates
+}
+
+// waitFor polls until cond holds or fails the test after a second
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// newTestStateManager returns a quiet state manager closed with the test
+func newTestStateManager(t *testing.T) *StateManager {
+	t.Helper()
+
+	opts := wsmanager.DefaultManagerOptions()
+	opts.Logger = logger.Discard()
+	sm := NewStateManagerWithOptions(opts)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		sm.Close(ctx)
+	})
+	return sm
+}
+
+// connect accepts a fake client and waits until it receives broadcasts
+func connect(t *testing.T, sm *StateManager) *fakeConn {
+	t.Helper()
+
+	conn := newFakeConn()
+	before := sm.wsManager.Stats().Clients
+	sm.wsManager.Accept(conn)
+	waitFor(t, "client registration", func() bool {
+		return sm.wsManager.Stats().Clients > before
+	})
+	return conn
+}
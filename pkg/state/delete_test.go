@@ -0,0 +1,85 @@
+package state
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/magooney-loon/webrender/pkg/component"
+	wsmanager "github.com/magooney-loon/webrender/pkg/websocket"
+)
+
+func TestDeleteBatchSendsOneFrame(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	form := component.New("form-1", "form", `<form></form>`)
+	for _, key := range []string{"name", "email", "phone", "keep"} {
+		form.State.Set(key, "value")
+	}
+
+	var mutex sync.Mutex
+	var watched []string
+	for _, key := range []string{"name", "email", "phone"} {
+		key := key
+		form.State.Watch(key, func(oldVal, newVal interface{}) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			if oldVal == "value" && newVal == nil {
+				watched = append(watched, key)
+			}
+		})
+	}
+	if err := sm.RegisterComponent(form); err != nil {
+		t.Fatal(err)
+	}
+	conn := connect(t, sm)
+
+	// Unset keys are ignored
+	form.State.DeleteBatch("name", "email", "phone", "missing")
+
+	state := form.State.GetAll()
+	for _, key := range []string{"name", "email", "phone"} {
+		if _, ok := state[key]; ok {
+			t.Errorf("%s still set after DeleteBatch", key)
+		}
+	}
+	if _, ok := state["keep"]; !ok {
+		t.Error("DeleteBatch removed a key it wasn't given")
+	}
+
+	// Watchers run on their own goroutines
+	waitFor(t, "a watcher per deleted key", func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(watched) == 3
+	})
+	mutex.Lock()
+	sort.Strings(watched)
+	if want := []string{"email", "name", "phone"}; !reflect.DeepEqual(watched, want) {
+		t.Errorf("watchers fired for %v, want %v", watched, want)
+	}
+	mutex.Unlock()
+
+	waitFor(t, "the batch delete frame", func() bool {
+		return len(conn.messages(wsmanager.MessageTypeStateBatch)) > 0
+	})
+	batches := conn.messages(wsmanager.MessageTypeStateBatch)
+	if len(batches) != 1 {
+		t.Fatalf("got %d batch frames, want 1", len(batches))
+	}
+	var batch wsmanager.StateBatch
+	if err := json.Unmarshal(batches[0].Payload, &batch); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(batch.Deleted)
+	if batch.ComponentID != "form-1" || !reflect.DeepEqual(batch.Deleted, []string{"email", "name", "phone"}) {
+		t.Errorf("batch = %+v, want form-1 deleting email, name, phone", batch)
+	}
+	for _, update := range conn.stateUpdates(t) {
+		if update.Type == "delete" {
+			t.Errorf("per-key delete sent for %s alongside the batch", update.Key)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package state
+
+import (
+	"container/heap"
+	"time"
+)
+
+// expiryEntry is a key and when it expires
+type expiryEntry struct {
+	key     string
+	expires time.Time
+}
+
+// expiryQueue orders keys by expiry so trackers can prune the expired ones
+// without scanning everything they remember
+// It implements heap.Interface; use push and expire rather than the heap
+// functions directly.
+type expiryQueue []expiryEntry
+
+func (q expiryQueue) Len() int           { return len(q) }
+func (q expiryQueue) Less(i, j int) bool { return q[i].expires.Before(q[j].expires) }
+func (q expiryQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+func (q *expiryQueue) Push(x interface{}) {
+	*q = append(*q, x.(expiryEntry))
+}
+
+func (q *expiryQueue) Pop() interface{} {
+	old := *q
+	entry := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return entry
+}
+
+// push adds a key expiring at expires
+func (q *expiryQueue) push(key string, expires time.Time) {
+	heap.Push(q, expiryEntry{key: key, expires: expires})
+}
+
+// expire removes the entries that expired by now, calling drop for each
+func (q *expiryQueue) expire(now time.Time, drop func(entry expiryEntry)) {
+	for q.Len() > 0 && !(*q)[0].expires.After(now) {
+		drop(heap.Pop(q).(expiryEntry))
+	}
+}
+
+// popOldest removes the entry expiring first, calling drop for it
+// It reports false when the queue is empty.
+func (q *expiryQueue) popOldest(drop func(entry expiryEntry)) bool {
+	if q.Len() == 0 {
+		return false
+	}
+	drop(heap.Pop(q).(expiryEntry))
+	return true
+}
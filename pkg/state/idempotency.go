@@ -0,0 +1,113 @@
+package state
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultIdempotencyTTL is how long an action's result is kept for
+	// retries carrying the same idempotency key
+	DefaultIdempotencyTTL = 5 * time.Minute
+
+	// maxIdempotencyKeyLength bounds the keys clients may send
+	maxIdempotencyKeyLength = 128
+
+	// maxTrackedResults bounds the results remembered at once; the result
+	// closest to expiring is forgotten to make room
+	maxTrackedResults = 100000
+)
+
+// actionResult is the outcome of an action run under an idempotency key
+type actionResult struct {
+	key     string
+	done    chan struct{} // closed once reason is set
+	reason  string        // error sent to the client, or "" on success
+	expires time.Time
+}
+
+// idempotencyTracker remembers action results by idempotency key so
+// retried actions run once
+type idempotencyTracker struct {
+	results map[string]*actionResult
+	// Finished results by expiry; running ones aren't queued yet
+	expiry expiryQueue
+	ttl    time.Duration
+	now    func() time.Time
+	mutex  sync.Mutex
+}
+
+// newIdempotencyTracker creates an empty tracker keeping results for ttl
+func newIdempotencyTracker(ttl time.Duration) *idempotencyTracker {
+	return &idempotencyTracker{
+		results: make(map[string]*actionResult),
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+// setTTL changes how long new results are kept; 0 disables deduplication
+func (t *idempotencyTracker) setTTL(ttl time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.ttl = ttl
+}
+
+// begin claims a key for a new run, or returns the earlier run's result
+// When first is true the caller must run the action and call finish; a
+// repeat waits for the first run to finish. Expired results are pruned in
+// expiry order as new keys arrive.
+func (t *idempotencyTracker) begin(key string) (result *actionResult, first bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.expiry.expire(t.now(), t.forget)
+
+	if r, seen := t.results[key]; seen {
+		return r, false
+	}
+
+	result = &actionResult{key: key, done: make(chan struct{})}
+	if t.ttl <= 0 {
+		return result, true
+	}
+
+	// Make room by forgetting the results closest to expiring; when every
+	// tracked key is still running, this run goes untracked
+	for len(t.results) >= maxTrackedResults {
+		if !t.expiry.popOldest(t.forget) {
+			break
+		}
+	}
+	if len(t.results) < maxTrackedResults {
+		t.results[key] = result
+	}
+	return result, true
+}
+
+// forget drops a queued result unless its key has been claimed again
+func (t *idempotencyTracker) forget(entry expiryEntry) {
+	if r, ok := t.results[entry.key]; ok && r.expires.Equal(entry.expires) {
+		delete(t.results, entry.key)
+	}
+}
+
+// finish records the result of a run claimed with begin
+func (t *idempotencyTracker) finish(result *actionResult, reason string) {
+	t.mutex.Lock()
+	result.reason = reason
+	result.expires = t.now().Add(t.ttl)
+	if t.results[result.key] == result {
+		t.expiry.push(result.key, result.expires)
+	}
+	t.mutex.Unlock()
+
+	close(result.done)
+}
+
+// wait blocks until a run has finished and returns its result
+func (r *actionResult) wait() string {
+	<-r.done
+	return r.reason
+}
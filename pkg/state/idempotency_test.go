@@ -0,0 +1,158 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/magooney-loon/webrender/pkg/component"
+	wsmanager "github.com/magooney-loon/webrender/pkg/websocket"
+)
+
+func TestIdempotencyKeyRunsActionOnce(t *testing.T) {
+	sm := newTestStateManager(t)
+	calls := newCountingComponent(t, sm, "counter-1")
+	conn := newFakeConn()
+
+	retry := wsmanager.ActionMessage{ComponentID: "counter-1", Action: "bump", IdempotencyKey: "k-1"}
+	sendAction(t, sm, conn, retry)
+	sendAction(t, sm, conn, retry)
+	if *calls != 1 {
+		t.Errorf("method ran %d times for one idempotency key, want 1", *calls)
+	}
+
+	retry.IdempotencyKey = "k-2"
+	sendAction(t, sm, conn, retry)
+	if *calls != 2 {
+		t.Errorf("method ran %d times after a new key, want 2", *calls)
+	}
+
+	// Actions without a key are never deduplicated
+	plain := wsmanager.ActionMessage{ComponentID: "counter-1", Action: "bump"}
+	sendAction(t, sm, conn, plain)
+	sendAction(t, sm, conn, plain)
+	if *calls != 4 {
+		t.Errorf("method ran %d times, want 4", *calls)
+	}
+	if errs := actionErrors(t, conn); len(errs) != 0 {
+		t.Errorf("action errors = %+v, want none", errs)
+	}
+}
+
+func TestIdempotencyKeyReplaysFailure(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	calls := 0
+	comp := component.New("cache-1", "cache", "<div></div>")
+	comp.AddTypedMethod("clear", func(map[string]interface{}) error {
+		calls++
+		return errors.New("cache busy")
+	})
+	if err := sm.componentRegistry.Register(comp); err != nil {
+		t.Fatal(err)
+	}
+
+	conn := newFakeConn()
+	retry := wsmanager.ActionMessage{ComponentID: "cache-1", Action: "clear", IdempotencyKey: "k-1"}
+	sendAction(t, sm, conn, retry)
+	sendAction(t, sm, conn, retry)
+
+	if calls != 1 {
+		t.Errorf("method ran %d times, want 1", calls)
+	}
+	errs := actionErrors(t, conn)
+	if len(errs) != 2 || errs[0].Error != "cache busy" || errs[1].Error != "cache busy" {
+		t.Errorf("action errors = %+v, want the first result reported twice", errs)
+	}
+}
+
+func TestIdempotencyKeyConcurrentRetries(t *testing.T) {
+	sm := newTestStateManager(t)
+	calls := newCountingComponent(t, sm, "counter-1")
+
+	payload, err := json.Marshal(wsmanager.ActionMessage{ComponentID: "counter-1", Action: "bump", IdempotencyKey: "k-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sm.handleAction(newFakeConn(), payload)
+		}()
+	}
+	wg.Wait()
+
+	if *calls != 1 {
+		t.Errorf("method ran %d times for concurrent retries, want 1", *calls)
+	}
+}
+
+func TestIdempotencyDisabledWithZeroTTL(t *testing.T) {
+	sm := newTestStateManager(t)
+	sm.SetIdempotencyTTL(0)
+	calls := newCountingComponent(t, sm, "counter-1")
+
+	retry := wsmanager.ActionMessage{ComponentID: "counter-1", Action: "bump", IdempotencyKey: "k-1"}
+	sendAction(t, sm, newFakeConn(), retry)
+	sendAction(t, sm, newFakeConn(), retry)
+	if *calls != 2 {
+		t.Errorf("method ran %d times with deduplication off, want 2", *calls)
+	}
+}
+
+func TestIdempotencyTrackerPrunesExpiredResults(t *testing.T) {
+	tracker := newIdempotencyTracker(time.Minute)
+	now := time.Now()
+	tracker.now = func() time.Time { return now }
+
+	result, first := tracker.begin("k-1")
+	if !first {
+		t.Fatal("first claim of a key was not first")
+	}
+	tracker.finish(result, "")
+	if _, first := tracker.begin("k-1"); first {
+		t.Fatal("retry within the TTL ran again")
+	}
+
+	// Expired results are dropped when the next key arrives
+	now = now.Add(2 * time.Minute)
+	result, _ = tracker.begin("k-2")
+	tracker.finish(result, "")
+	if len(tracker.results) != 1 || tracker.expiry.Len() != 1 {
+		t.Errorf("tracking %d results (%d queued), want only k-2", len(tracker.results), tracker.expiry.Len())
+	}
+	if _, first := tracker.begin("k-1"); !first {
+		t.Error("key reused after its TTL did not run again")
+	}
+}
+
+func TestIdempotencyTrackerIsBounded(t *testing.T) {
+	tracker := newIdempotencyTracker(time.Minute)
+	now := time.Now()
+	tracker.now = func() time.Time { return now }
+
+	for i := 0; i < maxTrackedResults; i++ {
+		result, _ := tracker.begin(fmt.Sprint(i))
+		tracker.finish(result, "")
+		now = now.Add(time.Microsecond)
+	}
+
+	// The oldest result makes room for the next key
+	result, _ := tracker.begin("extra")
+	tracker.finish(result, "")
+	if len(tracker.results) != maxTrackedResults {
+		t.Errorf("tracking %d results, want the cap of %d", len(tracker.results), maxTrackedResults)
+	}
+	if _, kept := tracker.results["0"]; kept {
+		t.Error("the oldest result was not forgotten")
+	}
+	if _, first := tracker.begin("extra"); first {
+		t.Error("the newest result was forgotten")
+	}
+}
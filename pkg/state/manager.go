@@ -1,16 +1,20 @@
 package state
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
-	"log"
 	"net/http"
+	"reflect"
 	"sync"
 	"time"
+	"unicode"
 
 	"github.com/gorilla/websocket"
 	"github.com/magooney-loon/webrender/pkg/component"
+	"github.com/magooney-loon/webrender/pkg/logger"
 	wsmanager "github.com/magooney-loon/webrender/pkg/websocket"
 )
 
@@ -34,18 +38,43 @@ type StateManager struct {
 
 	// WebSocket management
 	wsManager *wsmanager.Manager
+
+	// Recently used nonces of replay-protected actions
+	nonces *nonceTracker
+
+	// Results of actions sent with idempotency keys
+	idempotency *idempotencyTracker
+
+	// Destination for state sync and action logs
+	logger logger.Logger
 }
 
 // NewStateManager creates a new StateManager instance
 func NewStateManager() *StateManager {
+	return NewStateManagerWithOptions(wsmanager.DefaultManagerOptions())
+}
+
+// NewStateManagerWithOptions creates a new StateManager whose WebSocket
+// manager uses the given options
+// The component registry logs to opts.Logger as well.
+func NewStateManagerWithOptions(opts wsmanager.ManagerOptions) *StateManager {
+	if opts.Logger == nil {
+		opts.Logger = logger.Default()
+	}
+
 	sm := &StateManager{
 		templates: make(map[string]*template.Template),
 		funcMap:   make(template.FuncMap),
-		wsManager: wsmanager.NewManager(),
+		wsManager: wsmanager.NewManagerWithOptions(opts),
+		nonces:    newNonceTracker(),
+		logger:    opts.Logger,
+
+		idempotency: newIdempotencyTracker(DefaultIdempotencyTTL),
 	}
 
 	// Initialize component registry with this state manager as broadcaster
 	sm.componentRegistry = component.NewRegistry(sm)
+	sm.componentRegistry.SetLogger(opts.Logger)
 
 	// Register message handlers
 	sm.wsManager.RegisterHandler(wsmanager.MessageTypeStateUpdate, sm.handleStateUpdate)
@@ -56,6 +85,19 @@ func NewStateManager() *StateManager {
 	// Register state refresh request handler
 	sm.wsManager.RegisterHandler(wsmanager.MessageTypeStateRefreshRequest, sm.handleStateRefreshRequest)
 
+	// Register windowed list request handler
+	sm.wsManager.RegisterHandler(wsmanager.MessageTypeWindowRequest, sm.handleWindowRequest)
+
+	// Route component-scoped handlers by the component's name, skipping
+	// disabled components as the global handlers do
+	sm.wsManager.SetComponentTypeResolver(func(componentID string) string {
+		comp, exists := sm.componentRegistry.Get(componentID)
+		if !exists || !sm.componentRegistry.IsEnabled(componentID) {
+			return ""
+		}
+		return comp.Name
+	})
+
 	// Start WebSocket manager
 	sm.wsManager.Start()
 
@@ -65,66 +107,184 @@ func NewStateManager() *StateManager {
 	return sm
 }
 
+// Close shuts down the state manager's subsystems in a fixed order:
+// the heartbeat stops, the WebSocket manager delivers queued broadcasts and
+// disconnects clients, components are removed (running OnDestroy and
+// flushing persisted state), and finally Close waits for the manager's
+// goroutines to exit. It returns early with ctx's error on timeout.
+func (sm *StateManager) Close(ctx context.Context) error {
+	// Stop the heartbeat so nothing new is queued
+	sm.wsManager.StopHeartbeat()
+
+	// Stop the WebSocket manager, draining queued broadcasts
+	sm.wsManager.Stop()
+
+	// Remove components so their cleanup hooks run
+	var errs []error
+	for _, comp := range sm.componentRegistry.GetAll() {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("closing state manager: %w", err)
+		}
+		if err := sm.componentRegistry.Remove(comp.ID); err != nil {
+			errs = append(errs, fmt.Errorf("removing component %s: %w", comp.ID, err))
+		}
+	}
+
+	// Wait for the run loop, heartbeat, and client readers to exit
+	if err := sm.wsManager.Wait(ctx); err != nil {
+		return fmt.Errorf("closing state manager: %w", err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// BroadcastShutdownNotice tells clients the server will shut down after
+// the grace period so they can warn the user and prepare to reconnect
+func (sm *StateManager) BroadcastShutdownNotice(grace time.Duration) error {
+	return sm.wsManager.BroadcastCustomMessage(wsmanager.MessageTypeShutdown, wsmanager.ShutdownNotice{
+		GraceMS: grace.Milliseconds(),
+	})
+}
+
 // HandleWebSocket handles WebSocket connections
 func (sm *StateManager) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	sm.wsManager.HandleConnection(w, r)
 }
 
+// HandleSSE streams state updates to clients that can't use WebSockets
+func (sm *StateManager) HandleSSE(w http.ResponseWriter, r *http.Request) {
+	sm.wsManager.HandleSSE(w, r)
+}
+
+// HandleSSEMessage accepts messages posted by SSE clients
+func (sm *StateManager) HandleSSEMessage(w http.ResponseWriter, r *http.Request) {
+	sm.wsManager.HandleSSEMessage(w, r)
+}
+
 // handleStateUpdate processes state updates received from clients
-func (sm *StateManager) handleStateUpdate(conn *websocket.Conn, payload []byte) {
+func (sm *StateManager) handleStateUpdate(conn wsmanager.Conn, payload []byte) {
 	var update wsmanager.StateUpdate
 	if err := json.Unmarshal(payload, &update); err != nil {
-		log.Printf("Error unmarshaling state update: %v", err)
+		sm.logger.Warnf("Error unmarshaling state update: %v", err)
 		return
 	}
 
 	// Get the component
 	comp, exists := sm.componentRegistry.Get(update.ComponentID)
 	if !exists {
-		log.Printf("Component not found: %s", update.ComponentID)
+		sm.logger.Warnf("Component not found: %s", update.ComponentID)
 		return
 	}
 
-	// Update the component state
+	// Ignore client updates to disabled components
+	if !sm.componentRegistry.IsEnabled(update.ComponentID) {
+		sm.logger.Warnf("Ignoring state update for disabled component: %s", update.ComponentID)
+		return
+	}
+
+	// Keys the server owns can only change through methods
+	if update.Type != "compute" && comp.IsClientReadOnly(update.Key) {
+		sm.logger.Warnf("Rejected client %s of read-only key %s on component %s", update.Type, update.Key, update.ComponentID)
+		sm.sendMessage(conn, wsmanager.MessageTypeActionError, wsmanager.ActionError{
+			ComponentID: update.ComponentID,
+			Action:      update.Type + ":" + update.Key,
+			Error:       "state key is read-only",
+		})
+		return
+	}
+
+	// Update the component state; the state broadcasts accepted changes
+	// itself, so transforms apply and rejected values never go out
 	switch update.Type {
 	case "update":
 		comp.State.Set(update.Key, update.Value)
 	case "delete":
-		// Would implement delete functionality here
-		log.Printf("Delete operation not implemented")
+		// Delete broadcasts the removal itself
+		comp.State.Delete(update.Key)
+		return
 	case "compute":
-		// Would trigger recomputation here
-		log.Printf("Compute operation not implemented")
+		// Recompute broadcasts the fresh value itself
+		if _, ok := comp.State.Recompute(update.Key); !ok {
+			sm.logger.Warnf("No computed property %s on component %s", update.Key, update.ComponentID)
+			sm.sendMessage(conn, wsmanager.MessageTypeActionError, wsmanager.ActionError{
+				ComponentID: update.ComponentID,
+				Action:      "compute:" + update.Key,
+				Error:       "computed property not found",
+			})
+		}
+		return
 	default:
-		log.Printf("Unknown update type: %s", update.Type)
+		sm.logger.Warnf("Unknown update type: %s", update.Type)
 	}
-
-	// Broadcast the update to all clients
-	sm.wsManager.BroadcastStateUpdate(update)
 }
 
 // handleStateRefreshRequest processes state refresh requests from clients
-func (sm *StateManager) handleStateRefreshRequest(conn *websocket.Conn, _ []byte) {
-	log.Printf("Received state refresh request from client")
+// Only components whose broadcasts reach the client are replayed, and
+// those whose state hash matches the one the client sent are skipped; the
+// refresh ends with an ack carrying the current hashes.
+func (sm *StateManager) handleStateRefreshRequest(conn wsmanager.Conn, payload []byte) {
+	sm.logger.Debugf("Received state refresh request from client")
+
+	// Older clients send an empty payload and get a full replay
+	var req wsmanager.StateRefreshRequest
+	if len(payload) > 0 {
+		if err := json.Unmarshal(payload, &req); err != nil {
+			sm.logger.Warnf("Error unmarshaling state refresh request: %v", err)
+		}
+	}
+
+	ack := wsmanager.StateRefreshAck{
+		Hashes:    make(map[string]string),
+		Unchanged: []string{},
+	}
+
+	// Newer clients take one snapshot per component instead of one
+	// update per key
+	client, known := sm.wsManager.ClientForConn(conn)
+	snapshots := known && client.HasCapability(wsmanager.CapabilityStateSnapshot)
 
 	// Get all components
 	components := sm.componentRegistry.GetAll()
-	log.Printf("Sending state refresh for %d components", len(components))
+	sm.logger.Debugf("Sending state refresh for %d components", len(components))
 
 	// Number of updates actually sent
 	updateCount := 0
 
 	// Send all component states to the requesting client
 	for _, comp := range components {
-		// Get serializable state
-		stateMap := comp.State.GetAll()
+		// Replay only what live broadcasts would deliver; rooms stay
+		// hidden from connections that aren't registered clients
+		if (known && !sm.wsManager.Receives(client, comp.ID)) || (!known && sm.wsManager.IsRoom(comp.ID)) {
+			continue
+		}
+
+		// Get the state as clients see it
+		stateMap := comp.BroadcastState()
+
+		hash := component.HashState(stateMap)
+		if hash != "" {
+			ack.Hashes[comp.ID] = hash
+			if req.Hashes[comp.ID] == hash {
+				ack.Unchanged = append(ack.Unchanged, comp.ID)
+				continue
+			}
+		}
 
 		if len(stateMap) == 0 {
-			log.Printf("Component %s has no state to refresh", comp.ID)
+			sm.logger.Debugf("Component %s has no state to refresh", comp.ID)
 			continue
 		}
 
-		log.Printf("Refreshing state for component %s with %d state keys", comp.ID, len(stateMap))
+		sm.logger.Debugf("Refreshing state for component %s with %d state keys", comp.ID, len(stateMap))
+
+		if snapshots {
+			sm.sendMessage(conn, wsmanager.MessageTypeStateSnapshot, wsmanager.StateSnapshot{
+				ComponentID: comp.ID,
+				State:       stateMap,
+			})
+			updateCount++
+			continue
+		}
 
 		// For each state value, send an individual update to the client
 		for key, value := range stateMap {
@@ -138,7 +298,7 @@ func (sm *StateManager) handleStateRefreshRequest(conn *websocket.Conn, _ []byte
 			// Send only to the requesting client
 			data, err := json.Marshal(update)
 			if err != nil {
-				log.Printf("Error marshaling state update: %v", err)
+				sm.logger.Errorf("Error marshaling state update: %v", err)
 				continue
 			}
 
@@ -149,12 +309,12 @@ func (sm *StateManager) handleStateRefreshRequest(conn *websocket.Conn, _ []byte
 
 			msgData, err := json.Marshal(msg)
 			if err != nil {
-				log.Printf("Error marshaling message: %v", err)
+				sm.logger.Errorf("Error marshaling message: %v", err)
 				continue
 			}
 
 			if err := conn.WriteMessage(websocket.TextMessage, msgData); err != nil {
-				log.Printf("Error sending state refresh: %v", err)
+				sm.logger.Warnf("Error sending state refresh: %v", err)
 				return
 			}
 
@@ -162,44 +322,179 @@ func (sm *StateManager) handleStateRefreshRequest(conn *websocket.Conn, _ []byte
 		}
 	}
 
-	log.Printf("State refresh completed for client - sent %d total state updates", updateCount)
+	sm.logger.Debugf("State refresh completed for client - sent %d messages, %d components unchanged",
+		updateCount, len(ack.Unchanged))
+
+	sm.sendMessage(conn, wsmanager.MessageTypeStateRefreshAck, ack)
 }
 
 // handleAction processes action requests from clients
-func (sm *StateManager) handleAction(conn *websocket.Conn, payload []byte) {
+func (sm *StateManager) handleAction(conn wsmanager.Conn, payload []byte) {
 	var action wsmanager.ActionMessage
 	if err := json.Unmarshal(payload, &action); err != nil {
-		log.Printf("Error unmarshaling action message: %v", err)
+		sm.logger.Warnf("Error unmarshaling action message: %v", err)
 		return
 	}
 
+	// Attach the binary upload sent ahead of the action
+	if action.UploadID != "" {
+		data, ok := sm.wsManager.TakeUpload(conn, action.UploadID)
+		if !ok {
+			sm.logger.Warnf("Upload %s not found for action %s", action.UploadID, action.Action)
+			sm.sendActionError(conn, action, "upload not found")
+			return
+		}
+		if action.Params == nil {
+			action.Params = make(map[string]interface{})
+		}
+		action.Params[component.UploadParam] = data
+	}
+
 	// Get the component
 	comp, exists := sm.componentRegistry.Get(action.ComponentID)
 	if !exists {
-		log.Printf("Component not found for action: %s", action.ComponentID)
+		sm.logger.Warnf("Component not found for action: %s", action.ComponentID)
+		sm.sendActionError(conn, action, "component not found")
 		return
 	}
 
-	// Check if the component has a handler for this action
-	methodVal, exists := comp.Methods[action.Action]
-	if !exists {
-		log.Printf("Action not found: %s for component %s", action.Action, action.ComponentID)
+	// Reject actions on disabled components
+	if !sm.componentRegistry.IsEnabled(action.ComponentID) {
+		sm.logger.Warnf("Rejected action %s for disabled component %s", action.Action, action.ComponentID)
+		sm.sendActionError(conn, action, "component is disabled")
 		return
 	}
 
-	// Execute the action - type assert to the expected function signature
-	if method, ok := methodVal.(func(map[string]interface{}) error); ok {
-		if err := method(action.Params); err != nil {
-			log.Printf("Error executing action %s: %v", action.Action, err)
+	// Sensitive actions must carry a nonce that hasn't been seen before,
+	// checked ahead of idempotency so a replay isn't answered with the
+	// cached result of the original
+	if comp.RequiresNonce(action.Action) {
+		if err := sm.nonces.use(action.ComponentID, action.Nonce); err != nil {
+			sm.logger.Warnf("Rejected action %s for component %s: %v", action.Action, action.ComponentID, err)
+			sm.sendActionError(conn, action, err.Error())
 			return
 		}
-	} else {
-		log.Printf("Invalid method type for action %s", action.Action)
+	}
+
+	// Without an idempotency key every message runs the action
+	if action.IdempotencyKey == "" {
+		if reason := sm.runAction(comp, action); reason != "" {
+			sm.sendActionError(conn, action, reason)
+		}
+		return
+	}
+
+	if len(action.IdempotencyKey) > maxIdempotencyKeyLength {
+		sm.logger.Warnf("Rejected action %s for component %s: invalid idempotency key", action.Action, action.ComponentID)
+		sm.sendActionError(conn, action, "invalid idempotency key")
 		return
 	}
 
+	// A retried action gets the first run's result instead of running again
+	key := action.ComponentID + "\x00" + action.Action + "\x00" + action.IdempotencyKey
+	result, first := sm.idempotency.begin(key)
+	if first {
+		sm.idempotency.finish(result, sm.runAction(comp, action))
+	} else {
+		sm.logger.Debugf("Action %s for component %s already handled for idempotency key %s", action.Action, action.ComponentID, action.IdempotencyKey)
+	}
+
+	if reason := result.wait(); reason != "" {
+		sm.sendActionError(conn, action, reason)
+	}
+}
+
+// runAction calls the component method
+// It returns the reason to report to the client, or "" on success.
+func (sm *StateManager) runAction(comp *component.Component, action wsmanager.ActionMessage) string {
+	// Execute the action, serialized per component when configured, and
+	// tell the client why it failed
+	if err := comp.CallMethod(action.Action, action.Params); err != nil {
+		sm.logger.Warnf("Error executing action %s: %v", action.Action, err)
+
+		var methodErr *component.MethodError
+		if errors.As(err, &methodErr) {
+			err = methodErr.Err
+		}
+		return err.Error()
+	}
+
 	// The state changes will be broadcasted automatically by the component's OnStateChange handler
-	log.Printf("Action %s executed for component %s", action.Action, action.ComponentID)
+	sm.logger.Debugf("Action %s executed for component %s", action.Action, action.ComponentID)
+	return ""
+}
+
+// SetIdempotencyTTL sets how long an action's result is kept for retries
+// with the same idempotency key (DefaultIdempotencyTTL by default)
+// A TTL of 0 disables deduplication.
+func (sm *StateManager) SetIdempotencyTTL(ttl time.Duration) {
+	sm.idempotency.setTTL(ttl)
+}
+
+// handleWindowRequest sends the requested window of a component's
+// windowed data source back to the requesting client only
+func (sm *StateManager) handleWindowRequest(conn wsmanager.Conn, payload []byte) {
+	var req wsmanager.WindowRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		sm.logger.Warnf("Error unmarshaling window request: %v", err)
+		return
+	}
+
+	comp, exists := sm.componentRegistry.Get(req.ComponentID)
+	if !exists {
+		sm.logger.Warnf("Component not found for window request: %s", req.ComponentID)
+		return
+	}
+
+	if !sm.componentRegistry.IsEnabled(req.ComponentID) {
+		sm.logger.Warnf("Ignoring window request for disabled component: %s", req.ComponentID)
+		return
+	}
+
+	window, err := comp.Window(req.Offset, req.Limit)
+	if err != nil {
+		sm.logger.Warnf("Error reading window for component %s: %v", req.ComponentID, err)
+		return
+	}
+
+	sm.sendMessage(conn, wsmanager.MessageTypeWindowUpdate, wsmanager.WindowUpdate{
+		ComponentID: req.ComponentID,
+		Offset:      window.Offset,
+		Limit:       window.Limit,
+		Items:       window.Items,
+		Total:       window.Total,
+	})
+}
+
+// sendActionError reports a rejected action back to the client that sent it
+func (sm *StateManager) sendActionError(conn wsmanager.Conn, action wsmanager.ActionMessage, reason string) {
+	sm.sendMessage(conn, wsmanager.MessageTypeActionError, wsmanager.ActionError{
+		ComponentID: action.ComponentID,
+		Action:      action.Action,
+		Error:       reason,
+	})
+}
+
+// sendMessage writes a single message to one client
+func (sm *StateManager) sendMessage(conn wsmanager.Conn, msgType wsmanager.MessageType, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		sm.logger.Errorf("Error marshaling %s payload: %v", msgType, err)
+		return
+	}
+
+	msgData, err := json.Marshal(wsmanager.Message{
+		Type:    msgType,
+		Payload: data,
+	})
+	if err != nil {
+		sm.logger.Errorf("Error marshaling message: %v", err)
+		return
+	}
+
+	if err := conn.WriteMessage(websocket.TextMessage, msgData); err != nil {
+		sm.logger.Warnf("Error sending %s: %v", msgType, err)
+	}
 }
 
 // RenderComponent renders a component with its state and props
@@ -214,6 +509,63 @@ func (sm *StateManager) RegisterComponent(c *component.Component) error {
 	return sm.componentRegistry.RegisterComponent(c)
 }
 
+// AddFunc registers a function usable in templates parsed by ParseString
+// The function must return one value, or a value and an error. Templates
+// parsed before the call don't see it.
+func (sm *StateManager) AddFunc(name string, fn interface{}) error {
+	if err := validateTemplateFunc(name, fn); err != nil {
+		return err
+	}
+
+	sm.templatesMux.Lock()
+	defer sm.templatesMux.Unlock()
+
+	sm.funcMap[name] = fn
+	return nil
+}
+
+// validateTemplateFunc checks a function against text/template's rules
+// up front, since FuncMap panics on invalid entries
+func validateTemplateFunc(name string, fn interface{}) error {
+	if name == "" {
+		return errors.New("template function name is required")
+	}
+	for i, r := range name {
+		if r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r)) {
+			continue
+		}
+		return fmt.Errorf("template function name %q is not a valid identifier", name)
+	}
+
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return fmt.Errorf("template function %s is not a function", name)
+	}
+
+	t := v.Type()
+	switch {
+	case t.NumOut() == 1:
+	case t.NumOut() == 2 && t.Out(1) == reflect.TypeOf((*error)(nil)).Elem():
+	default:
+		return fmt.Errorf("template function %s must return one value, or a value and an error", name)
+	}
+	return nil
+}
+
+// UnregisterComponent removes a component at runtime
+// Its OnDestroy hook runs first, and clients are told to remove it.
+func (sm *StateManager) UnregisterComponent(id string) error {
+	return sm.componentRegistry.Unregister(id)
+}
+
+// BroadcastComponentRemoved tells clients a component was removed
+// Implements the component.RemovalBroadcaster interface
+func (sm *StateManager) BroadcastComponentRemoved(componentID string) error {
+	return sm.wsManager.BroadcastCustomMessage(wsmanager.MessageTypeComponentRemoved, wsmanager.ComponentRemoved{
+		ComponentID: componentID,
+	})
+}
+
 // ParseString parses a template string and registers it
 func (sm *StateManager) ParseString(name, text string) error {
 	sm.templatesMux.Lock()
@@ -251,6 +603,35 @@ func (sm *StateManager) BroadcastStateUpdate(componentID, key string, value inte
 	return sm.wsManager.BroadcastStateUpdate(update)
 }
 
+// BroadcastStateBatch broadcasts several state changes of a component in
+// one message
+// Implements the component.BatchBroadcaster interface
+func (sm *StateManager) BroadcastStateBatch(componentID string, changes map[string]interface{}) error {
+	return sm.wsManager.BroadcastStateBatch(wsmanager.StateBatch{
+		ComponentID: componentID,
+		Changes:     changes,
+	})
+}
+
+// BroadcastStateDeletes broadcasts the deletion of several state keys of a
+// component in one message
+// Implements the component.BatchDeleteBroadcaster interface
+func (sm *StateManager) BroadcastStateDeletes(componentID string, keys []string) error {
+	return sm.wsManager.BroadcastStateBatch(wsmanager.StateBatch{
+		ComponentID: componentID,
+		Deleted:     keys,
+	})
+}
+
+// BroadcastRender sends a component's rendered HTML to all clients
+// Implements the component.RenderBroadcaster interface
+func (sm *StateManager) BroadcastRender(componentID, html string) error {
+	return sm.wsManager.BroadcastRender(wsmanager.RenderUpdate{
+		ComponentID: componentID,
+		HTML:        html,
+	})
+}
+
 // GetComponentRegistry returns the component registry
 func (sm *StateManager) GetComponentRegistry() *component.Registry {
 	return sm.componentRegistry
@@ -0,0 +1,90 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/magooney-loon/webrender/pkg/component"
+	wsmanager "github.com/magooney-loon/webrender/pkg/websocket"
+)
+
+// clientUpdate encodes a state update as a client would send it
+func clientUpdate(t *testing.T, componentID, key string, value interface{}) []byte {
+	t.Helper()
+
+	payload, err := json.Marshal(wsmanager.StateUpdate{
+		ComponentID: componentID,
+		Key:         key,
+		Value:       value,
+		Type:        "update",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func TestClientUpdateRejectedByHookIsNotBroadcast(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	comp := component.New("counter-1", "counter", "<div></div>")
+	comp.State.Set("count", 1)
+	comp.Lifecycle.OnStateChange = func(c *component.Component, key string, oldVal, newVal interface{}) error {
+		if key == "count" {
+			return errors.New("count is read-only")
+		}
+		return nil
+	}
+	if err := sm.componentRegistry.Register(comp); err != nil {
+		t.Fatal(err)
+	}
+	conn := connect(t, sm)
+
+	sm.handleStateUpdate(conn, clientUpdate(t, "counter-1", "count", 99))
+	// An accepted update afterwards shows the queue has been delivered
+	sm.handleStateUpdate(conn, clientUpdate(t, "counter-1", "label", "ok"))
+	waitFor(t, "label update", func() bool { return len(conn.stateUpdates(t)) > 0 })
+
+	if got := comp.State.Get("count"); got != 1 {
+		t.Errorf("count = %v, want the rejected value rolled back to 1", got)
+	}
+	for _, update := range conn.stateUpdates(t) {
+		if update.Key == "count" {
+			t.Errorf("rejected value was broadcast: %+v", update)
+		}
+	}
+}
+
+func TestClientUpdateBroadcastOnceWithTransform(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	comp := component.New("profile-1", "profile", "<div></div>")
+	comp.SetBroadcastTransform(func(key string, v interface{}) interface{} {
+		if key == "email" {
+			return "hidden"
+		}
+		return v
+	})
+	if err := sm.componentRegistry.Register(comp); err != nil {
+		t.Fatal(err)
+	}
+	conn := connect(t, sm)
+
+	sm.handleStateUpdate(conn, clientUpdate(t, "profile-1", "email", "me@example.com"))
+	sm.handleStateUpdate(conn, clientUpdate(t, "profile-1", "done", true))
+	waitFor(t, "done update", func() bool { return len(conn.stateUpdates(t)) >= 2 })
+
+	var emails []interface{}
+	for _, update := range conn.stateUpdates(t) {
+		if update.Key == "email" {
+			emails = append(emails, update.Value)
+		}
+	}
+	if len(emails) != 1 || emails[0] != "hidden" {
+		t.Errorf("email broadcasts = %v, want one transformed value", emails)
+	}
+	if got := comp.State.Get("email"); got != "me@example.com" {
+		t.Errorf("Get(email) = %v, want the untransformed value", got)
+	}
+}
@@ -0,0 +1,104 @@
+package state
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// nonceTTL is how far a nonce's timestamp may be from the server's
+	// clock; used nonces are remembered until their timestamp is this old
+	nonceTTL = 5 * time.Minute
+
+	// maxNonceLength bounds the nonces clients may send
+	maxNonceLength = 128
+
+	// maxTrackedNonces bounds the nonces remembered at once; fresh nonces
+	// are refused while the tracker is full
+	maxTrackedNonces = 100000
+)
+
+var (
+	// errNonceInvalid is returned for nonces without a timestamp prefix
+	errNonceInvalid = errors.New("missing or invalid nonce")
+	// errNonceExpired is returned for nonces whose timestamp is outside
+	// nonceTTL of the server's clock
+	errNonceExpired = errors.New("expired nonce")
+	// errNonceReplayed is returned for nonces already used
+	errNonceReplayed = errors.New("replayed action")
+	// errNoncesFull is returned while maxTrackedNonces are remembered
+	errNoncesFull = errors.New("too many recent actions, try again later")
+)
+
+// nonceTracker remembers recently used action nonces to reject replays
+// A nonce is "<unix milliseconds>-<random>". Its timestamp must be within
+// nonceTTL of now, and it is remembered until the timestamp is nonceTTL
+// old, after which the timestamp alone rejects it.
+type nonceTracker struct {
+	seen   map[string]bool
+	expiry expiryQueue
+	now    func() time.Time
+	mutex  sync.Mutex
+}
+
+// newNonceTracker creates an empty nonce tracker
+func newNonceTracker() *nonceTracker {
+	return &nonceTracker{
+		seen: make(map[string]bool),
+		now:  time.Now,
+	}
+}
+
+// nonceTime returns the timestamp a nonce starts with
+func nonceTime(nonce string) (time.Time, bool) {
+	prefix, random, found := strings.Cut(nonce, "-")
+	if !found || random == "" {
+		return time.Time{}, false
+	}
+	ms, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil || ms <= 0 {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
+
+// use records the nonce of an action on a component, or returns why it
+// can't be used
+// Expired nonces are pruned in expiry order as new ones arrive.
+func (t *nonceTracker) use(componentID, nonce string) error {
+	if nonce == "" || len(nonce) > maxNonceLength {
+		return errNonceInvalid
+	}
+	issued, ok := nonceTime(nonce)
+	if !ok {
+		return errNonceInvalid
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.now()
+	t.expiry.expire(now, func(entry expiryEntry) {
+		delete(t.seen, entry.key)
+	})
+
+	expires := issued.Add(nonceTTL)
+	if !expires.After(now) || issued.After(now.Add(nonceTTL)) {
+		return errNonceExpired
+	}
+
+	key := componentID + "\x00" + nonce
+	if t.seen[key] {
+		return errNonceReplayed
+	}
+	if len(t.seen) >= maxTrackedNonces {
+		return errNoncesFull
+	}
+
+	t.seen[key] = true
+	t.expiry.push(key, expires)
+	return nil
+}
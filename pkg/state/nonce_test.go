@@ -0,0 +1,189 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/magooney-loon/webrender/pkg/component"
+	wsmanager "github.com/magooney-loon/webrender/pkg/websocket"
+)
+
+// nonceAt returns a nonce issued at a time, as client.js builds them
+func nonceAt(issued time.Time, random string) string {
+	return fmt.Sprintf("%d-%s", issued.UnixMilli(), random)
+}
+
+// newNonceComponent registers a component whose "clear" action requires a
+// nonce and returns its call count
+func newNonceComponent(t *testing.T, sm *StateManager) *int {
+	t.Helper()
+
+	calls := 0
+	comp := component.New("cache-1", "cache", "<div></div>")
+	comp.AddTypedMethod("clear", func(map[string]interface{}) error {
+		calls++
+		return nil
+	})
+	comp.RequireNonce("clear")
+	if err := sm.componentRegistry.Register(comp); err != nil {
+		t.Fatal(err)
+	}
+	return &calls
+}
+
+func TestReplayedNonceRejected(t *testing.T) {
+	sm := newTestStateManager(t)
+	calls := newNonceComponent(t, sm)
+
+	conn := newFakeConn()
+	clear := wsmanager.ActionMessage{ComponentID: "cache-1", Action: "clear", Nonce: nonceAt(time.Now(), "a1")}
+
+	sendAction(t, sm, conn, clear)
+	if *calls != 1 || len(actionErrors(t, conn)) != 0 {
+		t.Fatalf("fresh nonce: calls=%d errors=%+v, want one call and no errors", *calls, actionErrors(t, conn))
+	}
+
+	sendAction(t, sm, conn, clear)
+	if *calls != 1 {
+		t.Errorf("replayed action ran, calls=%d", *calls)
+	}
+	if errs := actionErrors(t, conn); len(errs) != 1 || errs[0].Error != "replayed action" {
+		t.Errorf("action errors = %+v, want one \"replayed action\"", errs)
+	}
+
+	// A new nonce goes through again
+	clear.Nonce = nonceAt(time.Now(), "b2")
+	sendAction(t, sm, conn, clear)
+	if *calls != 2 {
+		t.Errorf("fresh nonce after a replay: calls=%d, want 2", *calls)
+	}
+}
+
+func TestReplayWithIdempotencyKeyRejected(t *testing.T) {
+	sm := newTestStateManager(t)
+	calls := newNonceComponent(t, sm)
+
+	// client.js sends an idempotency key with every action; a replayed
+	// frame must not get the cached success back
+	conn := newFakeConn()
+	clear := wsmanager.ActionMessage{ComponentID: "cache-1", Action: "clear", Nonce: nonceAt(time.Now(), "a1"), IdempotencyKey: "k-1"}
+	sendAction(t, sm, conn, clear)
+	sendAction(t, sm, conn, clear)
+
+	if *calls != 1 {
+		t.Errorf("calls = %d, want 1", *calls)
+	}
+	if errs := actionErrors(t, conn); len(errs) != 1 || errs[0].Error != "replayed action" {
+		t.Errorf("action errors = %+v, want one \"replayed action\"", errs)
+	}
+}
+
+func TestNonceTimestampChecked(t *testing.T) {
+	sm := newTestStateManager(t)
+	calls := newNonceComponent(t, sm)
+
+	tests := []struct {
+		name  string
+		nonce string
+		want  string
+	}{
+		{"no timestamp", "d41d8cd98f00b204", "missing or invalid nonce"},
+		{"stale", nonceAt(time.Now().Add(-nonceTTL-time.Minute), "a1"), "expired nonce"},
+		{"far future", nonceAt(time.Now().Add(nonceTTL+time.Minute), "a1"), "expired nonce"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := newFakeConn()
+			sendAction(t, sm, conn, wsmanager.ActionMessage{ComponentID: "cache-1", Action: "clear", Nonce: tt.nonce})
+			if errs := actionErrors(t, conn); len(errs) != 1 || errs[0].Error != tt.want {
+				t.Errorf("action errors = %+v, want one %q", errs, tt.want)
+			}
+		})
+	}
+	if *calls != 0 {
+		t.Errorf("calls = %d, want none", *calls)
+	}
+}
+
+func TestNonceRequiredOnlyWhereOptedIn(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	comp := component.New("cache-1", "cache", "<div></div>")
+	comp.AddTypedMethod("clear", func(map[string]interface{}) error { return nil })
+	comp.AddTypedMethod("peek", func(map[string]interface{}) error { return nil })
+	comp.RequireNonce("clear")
+	if err := sm.componentRegistry.Register(comp); err != nil {
+		t.Fatal(err)
+	}
+
+	conn := newFakeConn()
+	sendAction(t, sm, conn, wsmanager.ActionMessage{ComponentID: "cache-1", Action: "clear"})
+	if errs := actionErrors(t, conn); len(errs) != 1 || errs[0].Error != "missing or invalid nonce" {
+		t.Errorf("action errors = %+v, want one \"missing or invalid nonce\"", errs)
+	}
+
+	// Other actions accept repeated (or missing) nonces
+	conn = newFakeConn()
+	peek := wsmanager.ActionMessage{ComponentID: "cache-1", Action: "peek", Nonce: "same"}
+	sendAction(t, sm, conn, peek)
+	sendAction(t, sm, conn, peek)
+	peek.Nonce = ""
+	sendAction(t, sm, conn, peek)
+	if errs := actionErrors(t, conn); len(errs) != 0 {
+		t.Errorf("action errors = %+v, want none for an action without replay protection", errs)
+	}
+}
+
+func TestNonceTrackerForgetsExpiredNonces(t *testing.T) {
+	tracker := newNonceTracker()
+	now := time.Now()
+	tracker.now = func() time.Time { return now }
+
+	nonce := nonceAt(now, "a1")
+	if err := tracker.use("cache-1", nonce); err != nil {
+		t.Fatalf("first use of a nonce: %v", err)
+	}
+	if err := tracker.use("cache-1", nonce); !errors.Is(err, errNonceReplayed) {
+		t.Fatalf("second use of a nonce: %v, want errNonceReplayed", err)
+	}
+	// Nonces are scoped to a component
+	if err := tracker.use("cache-2", nonce); err != nil {
+		t.Errorf("same nonce on another component: %v", err)
+	}
+
+	// Once its timestamp is too old the nonce is pruned, and the timestamp
+	// alone keeps rejecting it
+	now = now.Add(nonceTTL + time.Second)
+	if err := tracker.use("cache-1", nonceAt(now, "b2")); err != nil {
+		t.Fatalf("fresh nonce: %v", err)
+	}
+	if len(tracker.seen) != 1 || tracker.expiry.Len() != 1 {
+		t.Errorf("tracking %d nonces (%d queued), want only the fresh one", len(tracker.seen), tracker.expiry.Len())
+	}
+	if err := tracker.use("cache-1", nonce); !errors.Is(err, errNonceExpired) {
+		t.Errorf("replay after the TTL: %v, want errNonceExpired", err)
+	}
+}
+
+func TestNonceTrackerIsBounded(t *testing.T) {
+	tracker := newNonceTracker()
+	now := time.Now()
+	tracker.now = func() time.Time { return now }
+
+	for i := 0; i < maxTrackedNonces; i++ {
+		if err := tracker.use("cache-1", nonceAt(now, fmt.Sprint(i))); err != nil {
+			t.Fatalf("nonce %d: %v", i, err)
+		}
+	}
+	if err := tracker.use("cache-1", nonceAt(now, "extra")); !errors.Is(err, errNoncesFull) {
+		t.Errorf("nonce past the cap: %v, want errNoncesFull", err)
+	}
+
+	// Room frees up as nonces expire
+	now = now.Add(nonceTTL + time.Second)
+	if err := tracker.use("cache-1", nonceAt(now, "extra")); err != nil {
+		t.Errorf("nonce after expiry: %v", err)
+	}
+}
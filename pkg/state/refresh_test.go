@@ -0,0 +1,208 @@
+package state
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/magooney-loon/webrender/pkg/component"
+	wsmanager "github.com/magooney-loon/webrender/pkg/websocket"
+)
+
+// refresh sends a state refresh request with the given hashes and returns
+// the IDs of the components replayed and the ack
+func refresh(t *testing.T, sm *StateManager, hashes map[string]string) ([]string, wsmanager.StateRefreshAck) {
+	t.Helper()
+
+	conn := newFakeConn()
+	payload, _ := json.Marshal(wsmanager.StateRefreshRequest{Hashes: hashes})
+	sm.handleStateRefreshRequest(conn, payload)
+
+	replayed := map[string]bool{}
+	for _, update := range conn.stateUpdates(t) {
+		replayed[update.ComponentID] = true
+	}
+	ids := make([]string, 0, len(replayed))
+	for id := range replayed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	acks := conn.messages(wsmanager.MessageTypeStateRefreshAck)
+	if len(acks) != 1 {
+		t.Fatalf("got %d refresh acks, want 1", len(acks))
+	}
+	var ack wsmanager.StateRefreshAck
+	if err := json.Unmarshal(acks[0].Payload, &ack); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(ack.Unchanged)
+	return ids, ack
+}
+
+func TestStateRefreshSkipsComponentsWithMatchingHash(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	cart := component.New("cart-1", "cart", `<div></div>`)
+	cart.State.Set("items", 2)
+	menu := component.New("menu-1", "menu", `<nav></nav>`)
+	menu.State.Set("open", false)
+	for _, c := range []*component.Component{cart, menu} {
+		if err := sm.RegisterComponent(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A client without hashes gets everything
+	replayed, ack := refresh(t, sm, nil)
+	if want := []string{"cart-1", "menu-1"}; !reflect.DeepEqual(replayed, want) {
+		t.Errorf("first refresh replayed %v, want %v", replayed, want)
+	}
+	if len(ack.Hashes) != 2 || ack.Hashes["cart-1"] == "" || ack.Hashes["menu-1"] == "" {
+		t.Fatalf("ack hashes = %v, want one per component", ack.Hashes)
+	}
+	hashes := ack.Hashes
+
+	// Matching hashes replay nothing
+	replayed, ack = refresh(t, sm, hashes)
+	if len(replayed) != 0 {
+		t.Errorf("refresh with current hashes replayed %v", replayed)
+	}
+	if want := []string{"cart-1", "menu-1"}; !reflect.DeepEqual(ack.Unchanged, want) {
+		t.Errorf("unchanged = %v, want %v", ack.Unchanged, want)
+	}
+
+	// Only the component whose state changed is replayed
+	cart.State.Set("items", 3)
+	replayed, ack = refresh(t, sm, hashes)
+	if want := []string{"cart-1"}; !reflect.DeepEqual(replayed, want) {
+		t.Errorf("refresh after a change replayed %v, want %v", replayed, want)
+	}
+	if want := []string{"menu-1"}; !reflect.DeepEqual(ack.Unchanged, want) {
+		t.Errorf("unchanged = %v, want %v", ack.Unchanged, want)
+	}
+	if ack.Hashes["cart-1"] == hashes["cart-1"] {
+		t.Error("cart-1 hash did not change with its state")
+	}
+}
+
+func TestStateRefreshSendsOneSnapshotPerComponent(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	cart := component.New("cart-1", "cart", `<div></div>`)
+	cart.State.SetBatch(map[string]interface{}{"items": 2, "total": 30, "currency": "EUR"})
+	menu := component.New("menu-1", "menu", `<nav></nav>`)
+	menu.State.SetBatch(map[string]interface{}{"open": false, "active": "home"})
+	for _, c := range []*component.Component{cart, menu} {
+		if err := sm.RegisterComponent(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		capabilities  []string
+		wantSnapshots int
+		wantUpdates   int
+	}{
+		{"snapshot client", []string{wsmanager.CapabilityStateSnapshot}, 2, 0},
+		{"older client", nil, 0, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := connect(t, sm)
+			sub, _ := json.Marshal(wsmanager.Subscription{ComponentIDs: []string{}, Capabilities: tt.capabilities})
+			conn.send(`{"type":"subscribe","payload":` + string(sub) + `}`)
+			conn.send(`{"type":"state_refresh_request","payload":{}}`)
+			waitFor(t, "the refresh ack", func() bool {
+				return len(conn.messages(wsmanager.MessageTypeStateRefreshAck)) == 1
+			})
+
+			snapshots := conn.messages(wsmanager.MessageTypeStateSnapshot)
+			if len(snapshots) != tt.wantSnapshots {
+				t.Errorf("got %d snapshot frames, want %d", len(snapshots), tt.wantSnapshots)
+			}
+			if updates := conn.stateUpdates(t); len(updates) != tt.wantUpdates {
+				t.Errorf("got %d state update frames, want %d", len(updates), tt.wantUpdates)
+			}
+
+			for _, message := range snapshots {
+				var snapshot wsmanager.StateSnapshot
+				if err := json.Unmarshal(message.Payload, &snapshot); err != nil {
+					t.Fatalf("decoding snapshot: %v", err)
+				}
+				want := map[string]interface{}{"items": 2.0, "total": 30.0, "currency": "EUR"}
+				if snapshot.ComponentID == "menu-1" {
+					want = map[string]interface{}{"open": false, "active": "home"}
+				}
+				if !reflect.DeepEqual(snapshot.State, want) {
+					t.Errorf("%s snapshot = %v, want %v", snapshot.ComponentID, snapshot.State, want)
+				}
+			}
+		})
+	}
+}
+
+func TestStateRefreshHidesRoomsFromNonMembers(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	doc := component.New("doc-42", "doc", `<div></div>`)
+	doc.State.Set("title", "Secret plans")
+	menu := component.New("menu-1", "menu", `<nav></nav>`)
+	menu.State.Set("open", false)
+	for _, c := range []*component.Component{doc, menu} {
+		if err := sm.RegisterComponent(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sm.wsManager.SetRoom("doc-42", true)
+
+	// replayed lists the components a client's refresh carried
+	replayed := func(t *testing.T, conn *fakeConn) map[string]bool {
+		t.Helper()
+		conn.send(`{"type":"state_refresh_request","payload":{}}`)
+		waitFor(t, "the refresh ack", func() bool {
+			return len(conn.messages(wsmanager.MessageTypeStateRefreshAck)) > 0
+		})
+
+		ids := map[string]bool{}
+		for _, update := range conn.stateUpdates(t) {
+			ids[update.ComponentID] = true
+		}
+		for _, message := range conn.messages(wsmanager.MessageTypeStateSnapshot) {
+			var snapshot wsmanager.StateSnapshot
+			json.Unmarshal(message.Payload, &snapshot)
+			ids[snapshot.ComponentID] = true
+		}
+		var ack wsmanager.StateRefreshAck
+		json.Unmarshal(conn.messages(wsmanager.MessageTypeStateRefreshAck)[0].Payload, &ack)
+		if _, leaked := ack.Hashes["doc-42"]; leaked != ids["doc-42"] {
+			t.Errorf("ack hashes %v disagree with the replay %v", ack.Hashes, ids)
+		}
+		return ids
+	}
+
+	for _, capabilities := range []string{`[]`, `["state_snapshot"]`} {
+		t.Run("capabilities "+capabilities, func(t *testing.T) {
+			member := connect(t, sm)
+			member.send(`{"type":"subscribe","payload":{"component_ids":["doc-42","menu-1"],"capabilities":` + capabilities + `}}`)
+			outsider := connect(t, sm)
+			outsider.send(`{"type":"subscribe","payload":{"component_ids":[],"capabilities":` + capabilities + `}}`)
+
+			if got := replayed(t, member); !got["doc-42"] || !got["menu-1"] {
+				t.Errorf("member's refresh carried %v, want the room and the menu", got)
+			}
+			if got := replayed(t, outsider); got["doc-42"] || !got["menu-1"] {
+				t.Errorf("outsider's refresh carried %v, want only the menu", got)
+			}
+		})
+	}
+
+	// Connections that aren't clients don't see rooms either
+	ids, _ := refresh(t, sm, nil)
+	if want := []string{"menu-1"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("unregistered connection's refresh replayed %v, want %v", ids, want)
+	}
+}
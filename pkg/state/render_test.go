@@ -0,0 +1,138 @@
+package state
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/magooney-loon/webrender/pkg/component"
+	wsmanager "github.com/magooney-loon/webrender/pkg/websocket"
+)
+
+// subscribe sets a client's subscriptions and waits until they apply
+func subscribe(t *testing.T, conn *fakeConn, componentIDs ...string) {
+	t.Helper()
+
+	ids, _ := json.Marshal(componentIDs)
+	conn.send(`{"type":"subscribe","payload":{"component_ids":` + string(ids) + `}}`)
+
+	// Messages are handled in order, so the refresh ack follows the subscription
+	acks := len(conn.messages(wsmanager.MessageTypeStateRefreshAck))
+	conn.send(`{"type":"state_refresh_request","payload":{}}`)
+	waitFor(t, "the subscription", func() bool {
+		return len(conn.messages(wsmanager.MessageTypeStateRefreshAck)) > acks
+	})
+}
+
+func TestBroadcastRenderSendsCurrentHTML(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	c := component.New("cart-1", "cart", `<div id="{{.ID}}">{{.State.Get "items"}} items</div>`)
+	c.State.Set("items", 2)
+	if err := sm.RegisterComponent(c); err != nil {
+		t.Fatal(err)
+	}
+	if err := sm.RegisterComponent(component.New("menu-1", "menu", `<nav></nav>`)); err != nil {
+		t.Fatal(err)
+	}
+
+	follower := connect(t, sm)
+	subscribe(t, follower, "cart-1")
+	bystander := connect(t, sm)
+	subscribe(t, bystander, "menu-1")
+
+	c.State.Set("items", 5)
+	if err := sm.GetComponentRegistry().BroadcastRender("cart-1"); err != nil {
+		t.Fatalf("BroadcastRender: %v", err)
+	}
+
+	waitFor(t, "the render message", func() bool {
+		return len(follower.messages(wsmanager.MessageTypeRender)) == 1
+	})
+	var update wsmanager.RenderUpdate
+	if err := json.Unmarshal(follower.messages(wsmanager.MessageTypeRender)[0].Payload, &update); err != nil {
+		t.Fatalf("invalid render payload: %v", err)
+	}
+	if update.ComponentID != "cart-1" {
+		t.Errorf("render for %q, want cart-1", update.ComponentID)
+	}
+	if !strings.Contains(update.HTML, `<div id="cart-1">5 items</div>`) {
+		t.Errorf("render HTML = %q, want the current state", update.HTML)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if got := bystander.messages(wsmanager.MessageTypeRender); len(got) != 0 {
+		t.Errorf("unsubscribed client received %d render messages", len(got))
+	}
+}
+
+func TestBroadcastRenderReusesLastProps(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	c := component.New("greeting-1", "greeting", `<p>Hello {{.props.name}}, {{.State.Get "unread"}} new</p>`)
+	c.PropSchema = map[string]reflect.Kind{"name": reflect.String}
+	c.State.Set("unread", 1)
+	if err := sm.RegisterComponent(c); err != nil {
+		t.Fatal(err)
+	}
+	registry := sm.GetComponentRegistry()
+	if _, err := registry.RenderComponent("greeting-1", map[string]interface{}{"name": "Ada"}); err != nil {
+		t.Fatal(err)
+	}
+
+	follower := connect(t, sm)
+	subscribe(t, follower, "greeting-1")
+
+	c.State.Set("unread", 3)
+	if err := registry.BroadcastRender("greeting-1"); err != nil {
+		t.Fatalf("BroadcastRender: %v", err)
+	}
+
+	waitFor(t, "the render message", func() bool {
+		return len(follower.messages(wsmanager.MessageTypeRender)) == 1
+	})
+	var update wsmanager.RenderUpdate
+	if err := json.Unmarshal(follower.messages(wsmanager.MessageTypeRender)[0].Payload, &update); err != nil {
+		t.Fatalf("invalid render payload: %v", err)
+	}
+	if !strings.Contains(update.HTML, "<p>Hello Ada, 3 new</p>") {
+		t.Errorf("render HTML = %q, want the page's props with the current state", update.HTML)
+	}
+}
+
+func TestReenabledComponentIsRenderedAgain(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	c := component.New("banner-1", "banner", `<p>{{.State.Get "text"}}</p>`)
+	c.State.Set("text", "Sale")
+	if err := sm.RegisterComponent(c); err != nil {
+		t.Fatal(err)
+	}
+	follower := connect(t, sm)
+	subscribe(t, follower, "banner-1")
+
+	registry := sm.GetComponentRegistry()
+	registry.SetEnabled("banner-1", false)
+	c.State.Set("text", "Closing down")
+	registry.SetEnabled("banner-1", true)
+
+	waitFor(t, "the render message", func() bool {
+		return len(follower.messages(wsmanager.MessageTypeRender)) == 1
+	})
+	var update wsmanager.RenderUpdate
+	if err := json.Unmarshal(follower.messages(wsmanager.MessageTypeRender)[0].Payload, &update); err != nil {
+		t.Fatalf("invalid render payload: %v", err)
+	}
+	if !strings.Contains(update.HTML, "<p>Closing down</p>") {
+		t.Errorf("render HTML = %q, want the change made while disabled", update.HTML)
+	}
+
+	// Enabling a component that wasn't disabled sends nothing
+	registry.SetEnabled("banner-1", true)
+	time.Sleep(20 * time.Millisecond)
+	if got := len(follower.messages(wsmanager.MessageTypeRender)); got != 1 {
+		t.Errorf("received %d render messages, want 1", got)
+	}
+}
@@ -0,0 +1,58 @@
+package state
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/magooney-loon/webrender/pkg/component"
+	wsmanager "github.com/magooney-loon/webrender/pkg/websocket"
+)
+
+func TestComponentHandlersSkipDisabledComponents(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	for _, id := range []string{"chart-on", "chart-off"} {
+		if err := sm.componentRegistry.Register(component.New(id, "chart", "<div></div>")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sm.componentRegistry.SetEnabled("chart-off", false)
+
+	var mutex sync.Mutex
+	calls := make(map[string]int)
+	sm.wsManager.RegisterComponentHandler("chart", "zoom", func(conn wsmanager.Conn, payload []byte) {
+		var target struct {
+			ComponentID string `json:"component_id"`
+		}
+		json.Unmarshal(payload, &target)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		calls[target.ComponentID]++
+	})
+
+	conn := connect(t, sm)
+	// Messages from one client are handled in order
+	for _, id := range []string{"chart-off", "chart-on"} {
+		data, err := json.Marshal(wsmanager.Message{
+			Type:    "zoom",
+			Payload: json.RawMessage(`{"component_id":"` + id + `"}`),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.send(string(data))
+	}
+
+	waitFor(t, "enabled component's handler", func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return calls["chart-on"] == 1
+	})
+	mutex.Lock()
+	defer mutex.Unlock()
+	if calls["chart-off"] != 0 {
+		t.Errorf("scoped handler ran %d times for a disabled component", calls["chart-off"])
+	}
+}
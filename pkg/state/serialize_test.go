@@ -0,0 +1,119 @@
+package state
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/magooney-loon/webrender/pkg/component"
+	wsmanager "github.com/magooney-loon/webrender/pkg/websocket"
+)
+
+// newOverlapComponent registers a component whose "work" action records
+// how many calls ran at the same time
+func newOverlapComponent(t *testing.T, sm *StateManager, serialize bool) (peak *int32, ran *[]int) {
+	t.Helper()
+
+	var running int32
+	peak = new(int32)
+	ran = new([]int)
+	comp := component.New("worker-1", "worker", "<div></div>")
+	comp.AddTypedMethod("work", func(params map[string]interface{}) error {
+		now := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			max := atomic.LoadInt32(peak)
+			if now <= max || atomic.CompareAndSwapInt32(peak, max, now) {
+				break
+			}
+		}
+
+		// Unsynchronized on purpose: -race flags overlapping calls
+		*ran = append(*ran, int(params["n"].(float64)))
+		time.Sleep(2 * time.Millisecond)
+		return nil
+	})
+	comp.SerializeActions(serialize)
+	if err := sm.componentRegistry.Register(comp); err != nil {
+		t.Fatal(err)
+	}
+	return peak, ran
+}
+
+// fireConcurrently sends n "work" actions from separate goroutines
+func fireConcurrently(t *testing.T, sm *StateManager, n int) {
+	t.Helper()
+
+	payloads := make([][]byte, n)
+	for i := range payloads {
+		payload, err := json.Marshal(wsmanager.ActionMessage{
+			ComponentID: "worker-1",
+			Action:      "work",
+			Params:      map[string]interface{}{"n": float64(i)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		payloads[i] = payload
+	}
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for _, payload := range payloads {
+		wg.Add(1)
+		go func(payload []byte) {
+			defer wg.Done()
+			<-start
+			sm.handleAction(newFakeConn(), payload)
+		}(payload)
+	}
+	close(start)
+	wg.Wait()
+}
+
+func TestSerializedActionsRunOneAtATime(t *testing.T) {
+	sm := newTestStateManager(t)
+	peak, ran := newOverlapComponent(t, sm, true)
+
+	const calls = 20
+	fireConcurrently(t, sm, calls)
+
+	if *peak != 1 {
+		t.Errorf("%d actions ran at once, want 1", *peak)
+	}
+	if len(*ran) != calls {
+		t.Errorf("%d actions ran, want %d", len(*ran), calls)
+	}
+}
+
+func TestUnserializedActionsMayOverlap(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	// Each call waits for the other, which only works if they overlap
+	arrived := make(chan struct{}, 2)
+	comp := component.New("worker-1", "worker", "<div></div>")
+	comp.AddTypedMethod("work", func(map[string]interface{}) error {
+		arrived <- struct{}{}
+		deadline := time.After(time.Second)
+		for len(arrived) < 2 {
+			select {
+			case <-deadline:
+				return nil
+			default:
+				time.Sleep(time.Millisecond)
+			}
+		}
+		return nil
+	})
+	if err := sm.componentRegistry.Register(comp); err != nil {
+		t.Fatal(err)
+	}
+
+	started := time.Now()
+	fireConcurrently(t, sm, 2)
+	if elapsed := time.Since(started); elapsed >= time.Second {
+		t.Errorf("actions without serialization did not overlap (took %v)", elapsed)
+	}
+}
@@ -0,0 +1,103 @@
+package state
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/magooney-loon/webrender/pkg/component"
+	wsmanager "github.com/magooney-loon/webrender/pkg/websocket"
+)
+
+// TestConcurrentWritesToOneConnection fires refresh requests, answered on
+// the client's reader goroutine, while state updates are broadcast from the
+// run loop. Unserialized writes would panic in gorilla/websocket or be
+// flagged by -race.
+func TestConcurrentWritesToOneConnection(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	c := component.New("ticker-1", "ticker", `<div></div>`)
+	c.State.Set("n", 0)
+	if err := sm.RegisterComponent(c); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(sm.wsManager.HandleConnection))
+	defer server.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	waitFor(t, "client registration", func() bool {
+		return sm.wsManager.Stats().Clients == 1
+	})
+
+	const rounds = 200
+	refresh, _ := json.Marshal(wsmanager.Message{
+		Type:    wsmanager.MessageTypeStateRefreshRequest,
+		Payload: json.RawMessage(`{}`),
+	})
+
+	// Count the replies; the client reads on its own goroutine
+	var acks, updates int
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for acks < rounds {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var message wsmanager.Message
+			if json.Unmarshal(data, &message) != nil {
+				continue
+			}
+			switch message.Type {
+			case wsmanager.MessageTypeStateRefreshAck:
+				acks++
+			case wsmanager.MessageTypeStateUpdate:
+				updates++
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			c.State.Set("n", i+1)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			if err := conn.WriteMessage(websocket.TextMessage, refresh); err != nil {
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		conn.Close()
+		<-done
+	}
+	if acks != rounds {
+		t.Errorf("got %d refresh acks, want %d", acks, rounds)
+	}
+	if updates < rounds {
+		t.Errorf("got %d state updates, want at least %d", updates, rounds)
+	}
+	if clients := sm.wsManager.Stats().Clients; clients != 1 {
+		t.Errorf("%d clients connected after the stress run, want 1", clients)
+	}
+}
@@ -0,0 +1,71 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAddFuncUsableInTemplates(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	if err := sm.AddFunc("shout", strings.ToUpper); err != nil {
+		t.Fatalf("AddFunc: %v", err)
+	}
+	err := sm.AddFunc("price", func(cents int) (string, error) {
+		if cents < 0 {
+			return "", errors.New("negative price")
+		}
+		return fmt.Sprintf("$%d.%02d", cents/100, cents%100), nil
+	})
+	if err != nil {
+		t.Fatalf("AddFunc: %v", err)
+	}
+
+	if err := sm.ParseString("greeting", `<p>{{shout .Name}} owes {{price .Cents}}</p>`); err != nil {
+		t.Fatalf("ParseString: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	if err := sm.Render(rec, "greeting", map[string]interface{}{"Name": "ada", "Cents": 1250}); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if got := rec.Body.String(); got != "<p>ADA owes $12.50</p>" {
+		t.Errorf("rendered %q", got)
+	}
+
+	// Errors from functions fail the render
+	if err := sm.Render(httptest.NewRecorder(), "greeting", map[string]interface{}{"Name": "ada", "Cents": -1}); err == nil {
+		t.Error("render with a failing function succeeded")
+	}
+}
+
+func TestAddFuncRejectsInvalidFunctions(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	tests := map[string]struct {
+		name string
+		fn   interface{}
+	}{
+		"empty name":          {"", strings.ToUpper},
+		"invalid name":        {"to-upper", strings.ToUpper},
+		"not a function":      {"answer", 42},
+		"nil function":        {"noop", (func() string)(nil)},
+		"no results":          {"noop", func() {}},
+		"second result error": {"pair", func() (string, string) { return "", "" }},
+		"too many results":    {"triple", func() (int, int, error) { return 0, 0, nil }},
+	}
+
+	for name, tt := range tests {
+		if err := sm.AddFunc(tt.name, tt.fn); err == nil {
+			t.Errorf("%s: AddFunc succeeded, want an error", name)
+		}
+	}
+
+	// Nothing invalid reached the func map, so parsing still works
+	if err := sm.ParseString("plain", `<p>ok</p>`); err != nil {
+		t.Errorf("ParseString after rejected functions: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package state
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/magooney-loon/webrender/pkg/component"
+	wsmanager "github.com/magooney-loon/webrender/pkg/websocket"
+)
+
+func TestUnregisterComponent(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	destroyed := false
+	c := component.New("toast-1", "toast", `<div></div>`)
+	c.Lifecycle.OnDestroy = func(*component.Component) error {
+		destroyed = true
+		return nil
+	}
+	if err := sm.RegisterComponent(c); err != nil {
+		t.Fatalf("RegisterComponent: %v", err)
+	}
+	conn := connect(t, sm)
+
+	if err := sm.UnregisterComponent("toast-1"); err != nil {
+		t.Fatalf("UnregisterComponent: %v", err)
+	}
+
+	if !destroyed {
+		t.Error("OnDestroy did not run")
+	}
+	if _, ok := sm.GetComponentRegistry().Get("toast-1"); ok {
+		t.Error("Get found the component after it was unregistered")
+	}
+
+	waitFor(t, "the removal broadcast", func() bool {
+		return len(conn.messages(wsmanager.MessageTypeComponentRemoved)) == 1
+	})
+	var removed wsmanager.ComponentRemoved
+	if err := json.Unmarshal(conn.messages(wsmanager.MessageTypeComponentRemoved)[0].Payload, &removed); err != nil {
+		t.Fatal(err)
+	}
+	if removed.ComponentID != "toast-1" {
+		t.Errorf("removed component_id = %q, want toast-1", removed.ComponentID)
+	}
+
+	if err := sm.UnregisterComponent("toast-1"); err == nil {
+		t.Error("unregistering twice succeeded, want an error")
+	}
+}
@@ -0,0 +1,97 @@
+package state
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/magooney-loon/webrender/pkg/component"
+	wsmanager "github.com/magooney-loon/webrender/pkg/websocket"
+)
+
+// sendStateUpdate runs a client state update through the update handler
+func sendStateUpdate(t *testing.T, sm *StateManager, conn wsmanager.Conn, update wsmanager.StateUpdate) {
+	t.Helper()
+
+	payload, err := json.Marshal(update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm.handleStateUpdate(conn, payload)
+}
+
+// broadcastUpdate waits for a broadcast state update of key with the given
+// type and returns it
+func broadcastUpdate(t *testing.T, conn *fakeConn, key, updateType string) wsmanager.StateUpdate {
+	t.Helper()
+
+	var found wsmanager.StateUpdate
+	waitFor(t, updateType+" of "+key, func() bool {
+		for _, update := range conn.stateUpdates(t) {
+			if update.Key == key && update.Type == updateType {
+				found = update
+				return true
+			}
+		}
+		return false
+	})
+	return found
+}
+
+func TestClientComputeBroadcastsRecomputedValue(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	c := component.New("cart-1", "cart", `<div></div>`)
+	c.State.Set("price", 5)
+	c.State.Set("quantity", 2)
+	c.State.Compute("total", func() interface{} {
+		price, _ := c.State.GetInt("price")
+		quantity, _ := c.State.GetInt("quantity")
+		return price * quantity
+	})
+	if err := sm.RegisterComponent(c); err != nil {
+		t.Fatal(err)
+	}
+	viewer := connect(t, sm)
+
+	// Change an input without recomputing, then ask for the total
+	c.State.Set("quantity", 3)
+	sendStateUpdate(t, sm, newFakeConn(), wsmanager.StateUpdate{ComponentID: "cart-1", Key: "total", Type: "compute"})
+
+	update := broadcastUpdate(t, viewer, "total", "compute")
+	if update.Value != float64(15) {
+		t.Errorf("broadcast total = %v, want 15", update.Value)
+	}
+}
+
+func TestClientComputeWithoutComputedProperty(t *testing.T) {
+	sm := newTestStateManager(t)
+	if err := sm.RegisterComponent(component.New("cart-1", "cart", `<div></div>`)); err != nil {
+		t.Fatal(err)
+	}
+
+	conn := newFakeConn()
+	sendStateUpdate(t, sm, conn, wsmanager.StateUpdate{ComponentID: "cart-1", Key: "total", Type: "compute"})
+
+	errs := actionErrors(t, conn)
+	if len(errs) != 1 || errs[0].Action != "compute:total" || errs[0].Error != "computed property not found" {
+		t.Errorf("action errors = %+v, want one for compute:total", errs)
+	}
+}
+
+func TestClientDeleteRemovesStateKey(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	c := component.New("cart-1", "cart", `<div></div>`)
+	c.State.Set("coupon", "SAVE10")
+	if err := sm.RegisterComponent(c); err != nil {
+		t.Fatal(err)
+	}
+	viewer := connect(t, sm)
+
+	sendStateUpdate(t, sm, newFakeConn(), wsmanager.StateUpdate{ComponentID: "cart-1", Key: "coupon", Type: "delete"})
+
+	if _, ok := c.State.GetAll()["coupon"]; ok {
+		t.Error("coupon still in state after a client delete")
+	}
+	broadcastUpdate(t, viewer, "coupon", "delete")
+}
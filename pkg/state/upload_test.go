@@ -0,0 +1,85 @@
+package state
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/magooney-loon/webrender/pkg/component"
+	wsmanager "github.com/magooney-loon/webrender/pkg/websocket"
+)
+
+// newUploadComponent registers a component whose "avatar" action records
+// the bytes uploaded with it
+func newUploadComponent(t *testing.T, sm *StateManager) chan []byte {
+	t.Helper()
+
+	received := make(chan []byte, 1)
+	comp := component.New("profile-1", "profile", "<div></div>")
+	comp.AddTypedMethod("avatar", func(params map[string]interface{}) error {
+		data, _ := component.Upload(params)
+		received <- data
+		return nil
+	})
+	if err := sm.RegisterComponent(comp); err != nil {
+		t.Fatal(err)
+	}
+	return received
+}
+
+func TestActionReceivesUploadedBytes(t *testing.T) {
+	sm := newTestStateManager(t)
+	received := newUploadComponent(t, sm)
+	conn := connect(t, sm)
+
+	image := []byte{0x89, 'P', 'N', 'G', 0, '\n', 0xff}
+	conn.sendBinary(append([]byte("up-1\n"), image...))
+	conn.send(`{"type":"action","payload":{"component_id":"profile-1","action":"avatar","upload_id":"up-1"}}`)
+
+	var data []byte
+	waitFor(t, "the action", func() bool {
+		select {
+		case data = <-received:
+			return true
+		default:
+			return false
+		}
+	})
+	if !bytes.Equal(data, image) {
+		t.Errorf("method received %v, want %v", data, image)
+	}
+}
+
+func TestActionWithUnknownUploadIsRejected(t *testing.T) {
+	sm := newTestStateManager(t)
+	received := newUploadComponent(t, sm)
+	conn := connect(t, sm)
+
+	conn.send(`{"type":"action","payload":{"component_id":"profile-1","action":"avatar","upload_id":"missing"}}`)
+
+	waitFor(t, "the action error", func() bool {
+		return len(conn.messages(wsmanager.MessageTypeActionError)) == 1
+	})
+	if errs := actionErrors(t, conn); errs[0].Error != "upload not found" {
+		t.Errorf("action error = %q, want \"upload not found\"", errs[0].Error)
+	}
+	if len(received) != 0 {
+		t.Error("method ran without its upload")
+	}
+}
+
+func TestOversizedUploadIsDropped(t *testing.T) {
+	sm := newTestStateManager(t)
+	sm.GetWebSocketManager().MaxUploadSize = 4
+	received := newUploadComponent(t, sm)
+	conn := connect(t, sm)
+
+	conn.sendBinary([]byte("up-1\ntoo large"))
+	conn.send(`{"type":"action","payload":{"component_id":"profile-1","action":"avatar","upload_id":"up-1"}}`)
+
+	waitFor(t, "the action error", func() bool {
+		return len(conn.messages(wsmanager.MessageTypeActionError)) == 1
+	})
+	if len(received) != 0 {
+		t.Error("method ran with an oversized upload")
+	}
+}
@@ -0,0 +1,53 @@
+package state
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/magooney-loon/webrender/pkg/component"
+	wsmanager "github.com/magooney-loon/webrender/pkg/websocket"
+)
+
+func TestWindowRequestAnsweredToRequester(t *testing.T) {
+	sm := newTestStateManager(t)
+
+	c := component.New("rows-1", "rows", `<ul></ul>`)
+	c.SetWindowedSource(func(offset, limit int) ([]interface{}, int) {
+		var items []interface{}
+		for i := offset; i < offset+limit && i < 1000; i++ {
+			items = append(items, i)
+		}
+		return items, 1000
+	})
+	if err := sm.RegisterComponent(c); err != nil {
+		t.Fatalf("RegisterComponent: %v", err)
+	}
+
+	requester := connect(t, sm)
+	other := connect(t, sm)
+	requester.send(`{"type":"window_request","payload":{"component_id":"rows-1","offset":100,"limit":3}}`)
+
+	waitFor(t, "the window update", func() bool {
+		return len(requester.messages(wsmanager.MessageTypeWindowUpdate)) == 1
+	})
+
+	var update wsmanager.WindowUpdate
+	if err := json.Unmarshal(requester.messages(wsmanager.MessageTypeWindowUpdate)[0].Payload, &update); err != nil {
+		t.Fatalf("invalid window update: %v", err)
+	}
+	want := wsmanager.WindowUpdate{
+		ComponentID: "rows-1",
+		Offset:      100,
+		Limit:       3,
+		Items:       []interface{}{float64(100), float64(101), float64(102)},
+		Total:       1000,
+	}
+	if !reflect.DeepEqual(update, want) {
+		t.Errorf("got %+v, want %+v", update, want)
+	}
+
+	if got := other.messages(wsmanager.MessageTypeWindowUpdate); len(got) != 0 {
+		t.Errorf("another client received %d window updates", len(got))
+	}
+}
@@ -11,14 +11,24 @@ const BaseTemplate = `
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
     <link rel="icon" href="/static/logo.svg" type="image/svg+xml">
     <title>{{.Title}}</title>
+    {{- if not .Assets.SelfHosted}}
     <!-- Tailwind CSS -->
-    <script src="https://cdn.tailwindcss.com"></script>
+    <script src="https://cdn.tailwindcss.com"{{if .Nonce}} nonce="{{.Nonce}}"{{end}}></script>
     <!-- Inter font for Vercel-like UI -->
-    <link rel="stylesheet" href="https://fonts.googleapis.com/css2?family=Inter:wght@300;400;500;600;700&display=swap">
+    <link rel="stylesheet" href="https://fonts.googleapis.com/css2?family=Inter:wght@300;400;500;600;700&display=swap"{{if .Nonce}} nonce="{{.Nonce}}"{{end}}>
     <!-- Fira Code for monospace elements -->
-    <link rel="stylesheet" href="https://fonts.googleapis.com/css2?family=Fira+Code:wght@400;500&display=swap">
-    <script>
-        tailwind.config = {
+    <link rel="stylesheet" href="https://fonts.googleapis.com/css2?family=Fira+Code:wght@400;500&display=swap"{{if .Nonce}} nonce="{{.Nonce}}"{{end}}>
+    {{- end}}
+    <!-- Self-hosted assets -->
+    {{- range .Assets.Stylesheets}}
+    <link rel="stylesheet" href="{{.}}"{{if $.Nonce}} nonce="{{$.Nonce}}"{{end}}>
+    {{- end}}
+    {{- range .Assets.Scripts}}
+    <script src="{{.}}"{{if $.Nonce}} nonce="{{$.Nonce}}"{{end}}></script>
+    {{- end}}
+    <script{{if .Nonce}} nonce="{{.Nonce}}"{{end}}>
+        // Only the Tailwind play script reads this; prebuilt CSS ignores it
+        if (typeof tailwind !== 'undefined') tailwind.config = {
             darkMode: 'class',
             theme: {
                 extend: {
@@ -56,13 +66,21 @@ const BaseTemplate = `
             }
         }
     </script>
-    <style>
+    <style{{if .Nonce}} nonce="{{.Nonce}}"{{end}}>
         /* Base app styles */
+        {{- if eq .Theme "light"}}
+        body {
+            background: #fff;
+            color: #111;
+            min-height: 100vh;
+        }
+        {{- else if ne .Theme "none"}}
         body {
             background: radial-gradient(circle at center top, #111, #000);
             min-height: 100vh;
             overflow-x: hidden;
         }
+        {{- end}}
         .component-container {
             transition: all 0.2s ease;
         }
@@ -144,26 +162,29 @@ const BaseTemplate = `
         {{.Styles}}
     </style>
 </head>
-<body class="font-sans text-white leading-relaxed m-0 p-0">
+<body class="font-sans{{if or (eq .Theme "") (eq .Theme "dark")}} text-white{{end}} leading-relaxed m-0 p-0">
     <div id="app" class="max-w-7xl mx-auto p-5">
         {{.Content}}
     </div>
 
     <!-- WebRender Core -->
-    <script>
+    <script{{if .Nonce}} nonce="{{.Nonce}}"{{end}}>
     {{.ClientJS}}
     </script>
     
     <!-- Initialize WebSocket -->
-    <script>
+    <script{{if .Nonce}} nonce="{{.Nonce}}"{{end}}>
         document.addEventListener('DOMContentLoaded', function() {
             // Initialize WebSocket with auto-reconnect
             const wsProtocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-            const wsUrl = wsProtocol + '//' + window.location.host + '/ws';
-            WSManager.init(wsUrl);
+            const wsPath = '{{if .WebSocketPath}}{{.WebSocketPath}}{{else}}/ws{{end}}';
+            const wsUrl = wsProtocol + '//' + window.location.host + wsPath;
+            WSManager.init(wsUrl, {
+                sseUrl: '{{if .SSEPath}}{{.SSEPath}}{{else}}/sse{{end}}'
+            });
             
             // Listen for state updates
-            WSManager.on('state_update', function(data) {
+            function applyStateUpdate(data) {
                 // Find component by ID
                 const component = document.getElementById(data.component_id);
                 if (!component) {
@@ -173,7 +194,7 @@ const BaseTemplate = `
                 
                 // Update component state
                 try {
-                    let state = JSON.parse(component.getAttribute('data-state') || '{}');
+                    let state = WSManager.getState(component);
                     
                     if (data.type === 'delete') {
                         delete state[data.key];
@@ -181,8 +202,8 @@ const BaseTemplate = `
                         state[data.key] = data.value;
                     }
                     
-                    // Update the data attribute
-                    component.setAttribute('data-state', JSON.stringify(state));
+                    // Update the stored state
+                    WSManager.setState(component, state);
                     
                     // Update any bound elements
                     const boundElements = component.querySelectorAll('[data-bind="' + data.key + '"]');
@@ -201,12 +222,46 @@ const BaseTemplate = `
                 } catch (error) {
                     console.error('Error updating component state:', error);
                 }
+            }
+
+            WSManager.on('state_update', applyStateUpdate);
+
+            // Snapshots carry the full state of one component after a refresh
+            WSManager.on('state_snapshot', function(snapshot) {
+                Object.keys(snapshot.state || {}).forEach(function(key) {
+                    applyStateUpdate({
+                        component_id: snapshot.component_id,
+                        key: key,
+                        value: snapshot.state[key],
+                        type: 'update'
+                    });
+                });
+            });
+
+            // Batches carry several changed or deleted keys of one component
+            WSManager.on('state_batch', function(batch) {
+                Object.keys(batch.changes || {}).forEach(function(key) {
+                    applyStateUpdate({
+                        component_id: batch.component_id,
+                        key: key,
+                        value: batch.changes[key],
+                        type: 'update'
+                    });
+                });
+                (batch.deleted || []).forEach(function(key) {
+                    applyStateUpdate({
+                        component_id: batch.component_id,
+                        key: key,
+                        value: '',
+                        type: 'delete'
+                    });
+                });
             });
         });
     </script>
 
     <!-- Custom scripts for the page -->
-    <script>{{.Scripts}}</script>
+    <script{{if .Nonce}} nonce="{{.Nonce}}"{{end}}>{{.Scripts}}</script>
 </body>
 </html>
 `
@@ -218,6 +273,54 @@ type PageData struct {
 	Styles   template.CSS
 	Scripts  template.JS
 	ClientJS template.JS
+
+	// Nonce is added to inline script and style tags when a CSP nonce is in use
+	Nonce string
+
+	// WebSocketPath is the endpoint the client connects to (defaults to /ws)
+	WebSocketPath string
+
+	// SSEPath is the Server-Sent Events fallback endpoint (defaults to /sse)
+	SSEPath string
+
+	// Assets selects CDN or self-hosted CSS, fonts, and scripts
+	Assets Assets
+
+	// Theme selects the base body styling (empty means ThemeDark)
+	Theme string
+}
+
+const (
+	// ThemeDark gives the body a dark radial-gradient background and
+	// white text
+	ThemeDark = "dark"
+	// ThemeLight gives the body a white background and dark text
+	ThemeLight = "light"
+	// ThemeNone leaves body styling entirely to the page's styles
+	ThemeNone = "none"
+)
+
+// ValidTheme reports whether theme is empty or a known theme
+func ValidTheme(theme string) bool {
+	switch theme {
+	case "", ThemeDark, ThemeLight, ThemeNone:
+		return true
+	}
+	return false
+}
+
+// Assets selects where the base template loads CSS, fonts, and scripts
+// The zero value loads Tailwind and fonts from public CDNs.
+type Assets struct {
+	// Omit the CDN tags, e.g. for air-gapped deployments or a strict CSP
+	SelfHosted bool
+
+	// Stylesheet URLs linked in the head, e.g. a prebuilt Tailwind build
+	// and font CSS under /static
+	Stylesheets []string
+
+	// Script URLs loaded in the head, e.g. a vendored Tailwind play script
+	Scripts []string
 }
 
 // GetBaseTemplate returns a parsed base template
@@ -0,0 +1,37 @@
+package template
+
+import (
+	"bytes"
+	"html/template"
+)
+
+// ErrorContentTemplate is the body of the default error pages
+// It is rendered inside the base template.
+const ErrorContentTemplate = `
+<div class="min-h-[70vh] flex items-center justify-center px-4">
+    <div class="max-w-md w-full text-center bg-vercel-gray-900/50 border border-vercel-gray-800 rounded-lg p-8">
+        <p class="font-mono text-sm text-vercel-gray-400">{{.Status}}</p>
+        <h1 class="mt-2 text-2xl font-semibold">{{.StatusText}}</h1>
+        {{if .Message}}<p class="mt-3 text-vercel-gray-400">{{.Message}}</p>{{end}}
+        <a href="/" class="inline-block mt-6 text-vercel-accent-400 hover:underline">Back to home</a>
+    </div>
+</div>
+`
+
+// ErrorContentData contains data for rendering the default error content
+type ErrorContentData struct {
+	Status     int
+	StatusText string
+	Message    string
+}
+
+var errorContentTemplate = template.Must(template.New("error").Parse(ErrorContentTemplate))
+
+// RenderErrorContent renders the default error page body
+func RenderErrorContent(data ErrorContentData) (template.HTML, error) {
+	var buf bytes.Buffer
+	if err := errorContentTemplate.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return template.HTML(buf.String()), nil
+}
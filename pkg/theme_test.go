@@ -0,0 +1,48 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+
+	tmpl "github.com/magooney-loon/webrender/pkg/template"
+)
+
+const darkBackground = "radial-gradient(circle at center top, #111, #000)"
+
+func TestThemeBodyStyles(t *testing.T) {
+	tests := []struct {
+		name     string
+		theme    string
+		wantDark bool
+	}{
+		{"default", "", true},
+		{"dark", tmpl.ThemeDark, true},
+		{"light", tmpl.ThemeLight, false},
+		{"none", tmpl.ThemeNone, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wr := newTestWebRender(t, func(c *Config) { c.Theme = tt.theme })
+			page := renderPage(t, wr)
+
+			if got := strings.Contains(page, darkBackground); got != tt.wantDark {
+				t.Errorf("dark background included = %v, want %v", got, tt.wantDark)
+			}
+			if got := strings.Contains(page, "overflow-x: hidden"); got != tt.wantDark {
+				t.Errorf("overflow-x: hidden included = %v, want %v", got, tt.wantDark)
+			}
+			if got := strings.Contains(page, "text-white"); got != tt.wantDark {
+				t.Errorf("text-white body class included = %v, want %v", got, tt.wantDark)
+			}
+		})
+	}
+}
+
+func TestThemeNoneOmitsBodyRule(t *testing.T) {
+	page := renderPage(t, newTestWebRender(t, func(c *Config) { c.Theme = tmpl.ThemeNone }))
+
+	if strings.Contains(page, "body {") {
+		t.Error("theme none still styles the body")
+	}
+}
@@ -0,0 +1,32 @@
+package pkg
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHTMLTransformsApplyInOrder(t *testing.T) {
+	wr := newTestWebRender(t)
+	wr.AddHTMLTransform(func(page []byte) []byte {
+		return bytes.ReplaceAll(page, []byte("<p>content</p>"), []byte("<p>marker</p>"))
+	})
+	wr.AddHTMLTransform(func(page []byte) []byte {
+		return bytes.ReplaceAll(page, []byte("marker"), []byte("MARKER"))
+	})
+
+	page := renderPage(t, wr)
+	if !strings.Contains(page, "<p>MARKER</p>") {
+		t.Errorf("transforms not applied in order; page does not contain <p>MARKER</p>")
+	}
+	if strings.Contains(page, "<p>content</p>") {
+		t.Error("page still has the untransformed content")
+	}
+}
+
+func TestNoHTMLTransformsLeavesPageAlone(t *testing.T) {
+	page := renderPage(t, newTestWebRender(t))
+	if !strings.Contains(page, "<p>content</p>") {
+		t.Error("page content changed without any transforms")
+	}
+}
@@ -1,15 +1,20 @@
 package pkg
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/magooney-loon/webrender/internal/admin/handlers"
 	"github.com/magooney-loon/webrender/pkg/component"
+	"github.com/magooney-loon/webrender/pkg/logger"
 	"github.com/magooney-loon/webrender/pkg/router"
 	"github.com/magooney-loon/webrender/pkg/state"
 	tmpl "github.com/magooney-loon/webrender/pkg/template"
@@ -27,14 +32,39 @@ type WebRender struct {
 	Router   *router.Router
 	ServeMux *http.ServeMux // Kept for backward compatibility
 
+	// Runtime maintenance mode toggle
+	Maintenance *router.Maintenance
+
 	// Configuration
-	StaticDir string
+	StaticDir     string
+	WebSocketPath string
+	SSEPath       string
 
 	// Client JavaScript content
 	ClientJSContent string
 
 	// Base template data
 	BaseTemplate *template.Template
+
+	// CDN or self-hosted CSS, fonts, and scripts for the base template
+	Assets tmpl.Assets
+
+	// Base template body styling, e.g. tmpl.ThemeNone
+	Theme string
+
+	// HTTP server created by Start, stopped by Shutdown
+	server    *http.Server
+	serverMux sync.Mutex
+
+	// How long clients are warned before Shutdown closes connections
+	shutdownGrace time.Duration
+
+	// Shared by the state manager, WebSocket manager, and registry
+	logger logger.Logger
+
+	// Applied in order to every page rendered with the base template
+	htmlTransforms    []func([]byte) []byte
+	htmlTransformsMux sync.RWMutex
 }
 
 // Config contains configuration options for WebRender
@@ -57,6 +87,40 @@ type Config struct {
 
 	// Base template configuration
 	UseBaseTemplate bool
+
+	// Content-Security-Policy applied with a per-request nonce (disabled when empty)
+	ContentSecurityPolicy string
+
+	// Start in maintenance mode (can be toggled at runtime)
+	MaintenanceMode bool
+
+	// Path of the WebSocket endpoint (defaults to /ws)
+	WebSocketPath string
+
+	// Path of the Server-Sent Events fallback endpoint (defaults to /sse)
+	SSEPath string
+
+	// Annotate rendered components with boundary comments (development only)
+	Debug bool
+
+	// Broadcast queue size and overflow policy (zero values use the defaults)
+	WebSocketOptions websocket.ManagerOptions
+
+	// Time between the shutdown notice sent to clients and the server
+	// stopping (0 shuts down without a notice)
+	ShutdownGrace time.Duration
+
+	// CDN or self-hosted CSS, fonts, and scripts for the base template
+	// (the zero value uses CDNs)
+	Assets tmpl.Assets
+
+	// Base template body styling: tmpl.ThemeDark (the default),
+	// tmpl.ThemeLight, or tmpl.ThemeNone
+	Theme string
+
+	// Logger for all WebRender packages (nil logs everything with the
+	// standard library logger)
+	Logger logger.Logger
 }
 
 // DefaultConfig returns the default configuration
@@ -69,6 +133,8 @@ func DefaultConfig() Config {
 		AutoRegisterDirs:      []string{"pkg/components"},
 		AutoRegisterNamespace: "app",
 		UseBaseTemplate:       true,
+		WebSocketPath:         "/ws",
+		SSEPath:               "/sse",
 	}
 }
 
@@ -81,55 +147,118 @@ func (wr *WebRender) StandardMiddleware() {
 func New(config Config) (*WebRender, error) {
 	// Create instance
 	wr := &WebRender{
-		StaticDir: config.StaticDir,
-		ServeMux:  config.ServeMux,
-		Router:    config.Router,
+		StaticDir:     config.StaticDir,
+		ServeMux:      config.ServeMux,
+		Router:        config.Router,
+		WebSocketPath: config.WebSocketPath,
+		SSEPath:       config.SSEPath,
+		shutdownGrace: config.ShutdownGrace,
+		logger:        config.Logger,
+		Assets:        config.Assets,
+		Theme:         config.Theme,
+	}
+	if wr.logger == nil {
+		wr.logger = logger.Default()
+	}
+	if wr.WebSocketPath == "" {
+		wr.WebSocketPath = "/ws"
+	}
+	if wr.SSEPath == "" {
+		wr.SSEPath = "/sse"
 	}
 
 	// Initialize state manager
-	wr.StateManager = state.NewStateManager()
+	wsOptions := config.WebSocketOptions
+	if wsOptions.Logger == nil {
+		wsOptions.Logger = wr.logger
+	}
+	wr.StateManager = state.NewStateManagerWithOptions(wsOptions)
 
 	// Get reference to component registry and WebSocket manager
 	wr.ComponentRegistry = wr.StateManager.GetComponentRegistry()
 	wr.WebSocketManager = wr.StateManager.GetWebSocketManager()
+	wr.ComponentRegistry.SetDebug(config.Debug)
 
 	// Store reference to base template
 	wr.BaseTemplate = tmpl.GetBaseTemplate()
 
-	// Read client JS content
-	clientJSPath := filepath.Join("pkg", "websocket", "client.js")
-	clientJSContent, err := os.ReadFile(clientJSPath)
-	if err != nil {
-		return nil, err
+	// Store client JS content, embedded in the websocket package
+	wr.ClientJSContent = websocket.ClientJS()
+	if wr.ClientJSContent == "" {
+		return nil, errors.New("websocket client script is empty")
 	}
 
-	// Store client JS content
-	wr.ClientJSContent = string(clientJSContent)
-
 	// Register static file handler with Gorilla Mux
 	wr.Router.RegisterStaticHandler(wr.StaticDir, "/static")
 
+	// Render error pages with the base template unless overridden
+	if wr.Router.ErrorPages == nil {
+		wr.Router.WithErrorPages(router.NewErrorPages())
+	}
+	wr.Router.ErrorPages.SetFallback(wr.renderErrorPage)
+
 	// Apply standard middleware
 	wr.StandardMiddleware()
 
+	// Apply nonce-based CSP if configured
+	if config.ContentSecurityPolicy != "" {
+		if config.Assets.SelfHosted || !config.UseBaseTemplate {
+			wr.Router.UseMiddleware(router.CSPNonceMiddleware(config.ContentSecurityPolicy))
+		} else {
+			// The Tailwind play CDN injects <style> tags without the nonce,
+			// so CDN stylesheets no longer get in through it either
+			styleHosts := []string{"https://fonts.googleapis.com"}
+			if config.EnableAdminPanel {
+				styleHosts = append(styleHosts, "https://cdn.jsdelivr.net")
+			}
+			policy := router.MergeCSPSources(config.ContentSecurityPolicy, map[string][]string{
+				"style-src": styleHosts,
+			})
+			wr.Router.UseMiddleware(router.CSPScriptNonceMiddleware(policy))
+		}
+	}
+
+	// Apply maintenance mode middleware
+	wr.Maintenance = router.NewMaintenance()
+	wr.Maintenance.SetEnabled(config.MaintenanceMode)
+	if wr.WebSocketPath != "/ws" {
+		wr.Maintenance.Exempt(wr.WebSocketPath)
+	}
+	if wr.SSEPath != "/sse" {
+		wr.Maintenance.Exempt(wr.SSEPath)
+	}
+	wr.Router.UseMiddleware(wr.Maintenance.Middleware)
+
 	// Setup WebSocket handler on both ServeMux and Router
-	wr.ServeMux.HandleFunc("/ws", wr.StateManager.HandleWebSocket)
-	wr.Router.Router.HandleFunc("/ws", wr.StateManager.HandleWebSocket).Methods("GET")
+	wr.ServeMux.HandleFunc(wr.WebSocketPath, wr.StateManager.HandleWebSocket)
+	wr.Router.Router.HandleFunc(wr.WebSocketPath, wr.StateManager.HandleWebSocket).Methods("GET")
+
+	// Setup the Server-Sent Events fallback transport
+	wr.ServeMux.HandleFunc(wr.SSEPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			wr.StateManager.HandleSSEMessage(w, r)
+			return
+		}
+		wr.StateManager.HandleSSE(w, r)
+	})
+	wr.Router.Router.HandleFunc(wr.SSEPath, wr.StateManager.HandleSSE).Methods("GET")
+	wr.Router.Router.HandleFunc(wr.SSEPath, wr.StateManager.HandleSSEMessage).Methods("POST")
 
 	// Auto-register components if directories are specified
 	if len(config.AutoRegisterDirs) > 0 {
 		autoReg := component.NewAutoRegistration(wr.ComponentRegistry, config.AutoRegisterNamespace)
 		for _, dir := range config.AutoRegisterDirs {
-			err = autoReg.RegisterDirectory(dir)
+			report, err := autoReg.RegisterDirectory(dir)
 			if err != nil {
-				fmt.Printf("Warning: Auto-registration for directory %s failed: %v\n", dir, err)
+				wr.logger.Warnf("Auto-registration for directory %s failed: %v", dir, err)
 			}
+			wr.logAutoRegistration(dir, report)
 		}
 	}
 
 	// Register admin routes if enabled
 	if config.EnableAdminPanel {
-		handlers.RegisterAdminRoutes(wr.Router.Router, wr.StateManager)
+		handlers.RegisterAdminRoutes(wr.Router.Router, wr.StateManager, wr.Maintenance, wr.WebSocketPath, wr.SSEPath, wr.Assets)
 	}
 
 	return wr, nil
@@ -140,16 +269,33 @@ func (wr *WebRender) RegisterComponent(c *component.Component) error {
 	return wr.StateManager.RegisterComponent(c)
 }
 
+// UnregisterComponent removes a component and tells clients to drop it
+func (wr *WebRender) UnregisterComponent(id string) error {
+	return wr.StateManager.UnregisterComponent(id)
+}
+
 // RenderComponent renders a component with props
 func (wr *WebRender) RenderComponent(id string, props map[string]interface{}) (string, error) {
 	return wr.StateManager.RenderComponent(id, props)
 }
 
+// RenderComponentContext renders a component with props, memoizing identical
+// renders within a request (see component.WithRenderCache)
+func (wr *WebRender) RenderComponentContext(ctx context.Context, id string, props map[string]interface{}) (string, error) {
+	return wr.ComponentRegistry.RenderComponentContext(ctx, id, props)
+}
+
 // ParseTemplate parses a template and registers it with the state manager
 func (wr *WebRender) ParseTemplate(name, content string) error {
 	return wr.StateManager.ParseString(name, content)
 }
 
+// AddTemplateFunc registers a function for templates parsed by ParseTemplate
+// Register functions before parsing the templates that use them.
+func (wr *WebRender) AddTemplateFunc(name string, fn interface{}) error {
+	return wr.StateManager.AddFunc(name, fn)
+}
+
 // RenderTemplate renders a template with data
 func (wr *WebRender) RenderTemplate(w http.ResponseWriter, name string, data interface{}) error {
 	return wr.StateManager.Render(w, name, data)
@@ -173,13 +319,76 @@ func (wr *WebRender) Route(path string, handler func(http.ResponseWriter, *http.
 	return wr.Router.Router.HandleFunc(path, handler)
 }
 
+// RenderOptions customizes the response of a template route
+type RenderOptions struct {
+	// HTTP status code (defaults to 200). A 3xx status redirects without
+	// rendering the page and needs a Location header, except 304 Not
+	// Modified, which is sent with the headers alone.
+	Status int
+
+	// Extra response headers, e.g. Cache-Control
+	Headers map[string]string
+}
+
+// location returns the Location header, matching the name case-insensitively
+func (o RenderOptions) location() string {
+	for name, value := range o.Headers {
+		if http.CanonicalHeaderKey(name) == "Location" {
+			return value
+		}
+	}
+	return ""
+}
+
 // RouteWithTemplate adds a route that automatically renders content using the base template
 func (wr *WebRender) RouteWithTemplate(path string, title string, getContentFn func() (template.HTML, error), getStylesFn func() template.CSS, getScriptsFn func() template.JS) *mux.Route {
+	return wr.routeWithTemplate(path, title, func(*http.Request) (template.HTML, RenderOptions, error) {
+		content, err := getContentFn()
+		return content, RenderOptions{}, err
+	}, getStylesFn, getScriptsFn)
+}
+
+// RouteWithOptions adds a base template route whose content function can
+// also choose the response status and headers, e.g. to set caching headers
+// or redirect based on component state
+func (wr *WebRender) RouteWithOptions(path string, title string, getContentFn func(r *http.Request) (template.HTML, RenderOptions, error), getStylesFn func() template.CSS, getScriptsFn func() template.JS) *mux.Route {
+	return wr.routeWithTemplate(path, title, getContentFn, getStylesFn, getScriptsFn)
+}
+
+// routeWithTemplate adds a base template route whose content depends on the request
+// Each request gets its own render cache for RenderComponentContext, and
+// components render in the request's Accept-Language locale.
+func (wr *WebRender) routeWithTemplate(path string, title string, getContentFn func(r *http.Request) (template.HTML, RenderOptions, error), getStylesFn func() template.CSS, getScriptsFn func() template.JS) *mux.Route {
 	return wr.Router.Router.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+		ctx := component.WithRenderCache(r.Context())
+		if locales := component.ParseAcceptLanguage(r.Header.Get("Accept-Language")); len(locales) > 0 {
+			ctx = component.WithLocale(ctx, locales...)
+		}
+		r = r.WithContext(ctx)
+
 		// Get the content HTML
-		content, err := getContentFn()
+		content, opts, err := getContentFn(r)
 		if err != nil {
-			http.Error(w, "Failed to render content: "+err.Error(), http.StatusInternalServerError)
+			wr.Router.ErrorPages.Render(w, r, http.StatusInternalServerError, "Failed to render content: "+err.Error())
+			return
+		}
+
+		status := opts.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		// Redirects have no page to render
+		if status >= 300 && status < 400 {
+			if status != http.StatusNotModified && opts.location() == "" {
+				wr.Router.ErrorPages.Render(w, r, http.StatusInternalServerError,
+					fmt.Sprintf("Redirect status %d without a Location header", status))
+				return
+			}
+			for name, value := range opts.Headers {
+				w.Header().Set(name, value)
+			}
+			w.WriteHeader(status)
 			return
 		}
 
@@ -195,29 +404,193 @@ func (wr *WebRender) RouteWithTemplate(path string, title string, getContentFn f
 			scripts = getScriptsFn()
 		}
 
+		// Add the assets of the components on the page
+		componentStyles, componentScripts := wr.ComponentRegistry.RenderedAssets(r.Context())
+		styles = template.CSS(joinAsset(string(styles), componentStyles))
+		scripts = template.JS(joinAsset(string(scripts), componentScripts))
+
 		// Render the page with the base template
-		wr.BaseTemplate.Execute(w, tmpl.PageData{
+		page, err := wr.renderPage(tmpl.PageData{
 			Title:    title,
 			Content:  content,
 			Styles:   styles,
 			Scripts:  scripts,
 			ClientJS: wr.GetClientJS(),
+			Nonce:    router.CSPNonce(r),
+
+			WebSocketPath: wr.WebSocketPath,
+			SSEPath:       wr.SSEPath,
+			Assets:        wr.Assets,
+			Theme:         wr.Theme,
 		})
+		if err != nil {
+			wr.Router.ErrorPages.Render(w, r, http.StatusInternalServerError, "Failed to render page: "+err.Error())
+			return
+		}
+
+		// Let the components on the page extend the CSP
+		if sources := wr.ComponentRegistry.RenderedCSPSources(r.Context()); len(sources) > 0 {
+			if policy := w.Header().Get("Content-Security-Policy"); policy != "" {
+				w.Header().Set("Content-Security-Policy", router.MergeCSPSources(policy, sources))
+			}
+		}
+
+		// Apply headers and status before writing the body
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		for name, value := range opts.Headers {
+			w.Header().Set(name, value)
+		}
+		w.WriteHeader(status)
+		w.Write(page)
+	})
+}
+
+// AddHTMLTransform registers a function applied to the final HTML of every
+// page rendered with the base template, e.g. to minify it or inject an
+// analytics snippet
+// Transforms run in registration order, each receiving the previous output.
+func (wr *WebRender) AddHTMLTransform(transform func([]byte) []byte) {
+	wr.htmlTransformsMux.Lock()
+	defer wr.htmlTransformsMux.Unlock()
+
+	wr.htmlTransforms = append(wr.htmlTransforms, transform)
+}
+
+// joinAsset appends component assets to route assets
+func joinAsset(route, components string) string {
+	switch {
+	case components == "":
+		return route
+	case route == "":
+		return components
+	}
+	return route + "\n" + components
+}
+
+// renderPage renders a page with the base template and applies the HTML
+// transforms
+func (wr *WebRender) renderPage(data tmpl.PageData) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := wr.BaseTemplate.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("error executing base template: %w", err)
+	}
+
+	wr.htmlTransformsMux.RLock()
+	transforms := wr.htmlTransforms
+	wr.htmlTransformsMux.RUnlock()
+
+	page := buf.Bytes()
+	for _, transform := range transforms {
+		page = transform(page)
+	}
+	return page, nil
+}
+
+// SetErrorPage sets the template rendered for an HTTP error status
+// The template receives a router.ErrorPageData. Passing nil restores the default page.
+func (wr *WebRender) SetErrorPage(status int, tmpl *template.Template) {
+	wr.Router.ErrorPages.Set(status, tmpl)
+}
+
+// renderErrorPage renders the default error page inside the base template
+func (wr *WebRender) renderErrorPage(w http.ResponseWriter, r *http.Request, data router.ErrorPageData) {
+	content, err := tmpl.RenderErrorContent(tmpl.ErrorContentData{
+		Status:     data.Status,
+		StatusText: data.StatusText,
+		Message:    data.Message,
+	})
+	if err != nil {
+		http.Error(w, data.StatusText, data.Status)
+		return
+	}
+
+	page, err := wr.renderPage(tmpl.PageData{
+		Title:    fmt.Sprintf("%d %s", data.Status, data.StatusText),
+		Content:  content,
+		ClientJS: wr.GetClientJS(),
+		Nonce:    data.Nonce,
+
+		WebSocketPath: wr.WebSocketPath,
+		SSEPath:       wr.SSEPath,
+		Assets:        wr.Assets,
+		Theme:         wr.Theme,
 	})
+	if err != nil {
+		http.Error(w, data.StatusText, data.Status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(data.Status)
+	w.Write(page)
+}
+
+// ComponentSpec names a component to render on a page and its props
+type ComponentSpec struct {
+	ID    string
+	Props map[string]interface{}
+}
+
+// MultiComponentRoute adds a route that renders several components in
+// order on one page, e.g. the widgets of a dashboard
+// Each component's styles and scripts set with WithAssets are included
+// once per component name.
+func (wr *WebRender) MultiComponentRoute(path string, title string, specs []ComponentSpec) *mux.Route {
+	return wr.routeWithTemplate(path, title, func(r *http.Request) (template.HTML, RenderOptions, error) {
+		var content strings.Builder
+		for _, spec := range specs {
+			html, err := wr.RenderComponentContext(r.Context(), spec.ID, spec.Props)
+			if err != nil {
+				return "", RenderOptions{}, fmt.Errorf("component %s: %w", spec.ID, err)
+			}
+			content.WriteString(html)
+		}
+		return template.HTML(content.String()), RenderOptions{}, nil
+	}, nil, nil)
+}
+
+// ComponentRouteAuto adds a route that renders a specific component with
+// the styles and scripts set on the component with WithAssets
+func (wr *WebRender) ComponentRouteAuto(path string, title string, componentID string, props map[string]interface{}) *mux.Route {
+	return wr.ComponentRoute(path, title, componentID, props, nil, nil)
 }
 
 // ComponentRoute adds a route that renders a specific component
+// Assets set with WithAssets are included as well as those from
+// getStylesFn and getScriptsFn.
 func (wr *WebRender) ComponentRoute(path string, title string, componentID string, props map[string]interface{}, getStylesFn func() template.CSS, getScriptsFn func() template.JS) *mux.Route {
-	return wr.RouteWithTemplate(path, title, func() (template.HTML, error) {
-		html, err := wr.RenderComponent(componentID, props)
-		return template.HTML(html), err
+	return wr.routeWithTemplate(path, title, func(r *http.Request) (template.HTML, RenderOptions, error) {
+		html, err := wr.RenderComponentContext(r.Context(), componentID, props)
+		return template.HTML(html), RenderOptions{}, err
 	}, getStylesFn, getScriptsFn)
 }
 
-// AutoRegisterComponents auto-registers components from a directory
-func (wr *WebRender) AutoRegisterComponents(dir string, namespace string) error {
+// AutoRegisterComponents auto-registers components from a directory and
+// reports which registered, which files were skipped, and what failed
+func (wr *WebRender) AutoRegisterComponents(dir string, namespace string) (*component.RegistrationReport, error) {
 	autoReg := component.NewAutoRegistration(wr.ComponentRegistry, namespace)
-	return autoReg.RegisterDirectory(dir)
+	report, err := autoReg.RegisterDirectory(dir)
+	wr.logAutoRegistration(dir, report)
+	return report, err
+}
+
+// logAutoRegistration logs a summary of an auto-registration run, with a
+// warning for each skipped file and failure
+func (wr *WebRender) logAutoRegistration(dir string, report *component.RegistrationReport) {
+	wr.logger.Infof("Auto-registration for %s: %s", dir, report)
+	for _, skipped := range report.Skipped {
+		wr.logger.Warnf("Auto-registration skipped %s: %s", skipped.Path, skipped.Reason)
+	}
+	for _, err := range report.Errors {
+		wr.logger.Warnf("Auto-registration error: %v", err)
+	}
+}
+
+// RegisterInitializers registers a component for each named constructor,
+// with IDs prefixed by namespace, and returns the names and IDs created
+func (wr *WebRender) RegisterInitializers(namespace string, initializers map[string]component.ComponentInitializer) ([]component.RegisteredComponent, error) {
+	autoReg := component.NewAutoRegistration(wr.ComponentRegistry, namespace)
+	return autoReg.RegisterInitializers(initializers)
 }
 
 // ServeHTTP implements the http.Handler interface
@@ -226,9 +599,108 @@ func (wr *WebRender) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	wr.Router.ServeHTTP(w, r)
 }
 
-// Start starts the HTTP server on the specified address
+// DefaultShutdownTimeout bounds the shutdown StartWithContext runs after its
+// context is cancelled
+const DefaultShutdownTimeout = 10 * time.Second
+
+// Start compiles the component templates and starts the HTTP server on
+// the specified address
 func (wr *WebRender) Start(addr string) error {
-	fmt.Printf("Server starting at http://localhost%s\n", addr)
-	fmt.Printf("Admin dashboard at http://localhost%s/_/\n", addr)
-	return http.ListenAndServe(addr, wr)
+	if err := wr.compileComponents(); err != nil {
+		return err
+	}
+	return wr.newServer(addr).ListenAndServe()
+}
+
+// StartWithContext runs the HTTP server until ctx is cancelled, then shuts
+// it down gracefully (see Shutdown) within DefaultShutdownTimeout plus any
+// configured shutdown grace. It returns nil after a clean shutdown.
+func (wr *WebRender) StartWithContext(ctx context.Context, addr string) error {
+	if err := wr.compileComponents(); err != nil {
+		return err
+	}
+	server := wr.newServer(addr)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), DefaultShutdownTimeout+wr.shutdownGrace)
+	defer cancel()
+
+	if err := wr.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+
+	// ListenAndServe returns ErrServerClosed once Shutdown begins
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// compileComponents compiles every registered component template before
+// the server starts, so template errors stop startup
+func (wr *WebRender) compileComponents() error {
+	if err := wr.ComponentRegistry.CompileAll(); err != nil {
+		return fmt.Errorf("failed to compile component templates: %w", err)
+	}
+	return nil
+}
+
+// newServer creates the HTTP server used by Start and StartWithContext
+func (wr *WebRender) newServer(addr string) *http.Server {
+	wr.logger.Infof("Server starting at http://localhost%s", addr)
+	wr.logger.Infof("Admin dashboard at http://localhost%s/_/", addr)
+
+	server := &http.Server{Addr: addr, Handler: wr}
+
+	// Disconnect WebSocket and SSE clients as soon as shutdown begins, since
+	// the server doesn't wait for (or close) hijacked and streaming connections
+	server.RegisterOnShutdown(wr.WebSocketManager.Stop)
+
+	wr.serverMux.Lock()
+	wr.server = server
+	wr.serverMux.Unlock()
+
+	return server
+}
+
+// Shutdown gracefully stops the HTTP server started by Start, then closes
+// the state manager. Start returns http.ErrServerClosed once it begins.
+// With a shutdown grace configured, clients are sent a shutdown notice
+// first and the server keeps running for the grace period (or until ctx
+// is done).
+func (wr *WebRender) Shutdown(ctx context.Context) error {
+	if wr.shutdownGrace > 0 {
+		if err := wr.StateManager.BroadcastShutdownNotice(wr.shutdownGrace); err != nil {
+			wr.logger.Warnf("Error broadcasting shutdown notice: %v", err)
+		}
+
+		timer := time.NewTimer(wr.shutdownGrace)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+
+	wr.serverMux.Lock()
+	server := wr.server
+	wr.serverMux.Unlock()
+
+	if server != nil {
+		if err := server.Shutdown(ctx); err != nil {
+			return fmt.Errorf("shutting down HTTP server: %w", err)
+		}
+	}
+
+	return wr.StateManager.Close(ctx)
 }
@@ -0,0 +1,172 @@
+package pkg
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/magooney-loon/webrender/pkg/component"
+	"github.com/magooney-loon/webrender/pkg/logger"
+	"github.com/magooney-loon/webrender/pkg/router"
+)
+
+// newTestWebRender returns a quiet instance without the admin panel or
+// component auto-registration, shut down with the test
+func newTestWebRender(t *testing.T, configure ...func(*Config)) *WebRender {
+	t.Helper()
+
+	config := Config{
+		ServeMux:        http.NewServeMux(),
+		Router:          router.New(),
+		UseBaseTemplate: true,
+		Logger:          logger.Discard(),
+	}
+	for _, fn := range configure {
+		fn(&config)
+	}
+
+	wr, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		wr.StateManager.Close(ctx)
+	})
+	return wr
+}
+
+func TestRouteWithOptionsRedirects(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       RenderOptions
+		wantStatus int
+		wantBody   bool
+	}{
+		{"redirect", RenderOptions{Status: http.StatusFound, Headers: map[string]string{"location": "/login"}}, http.StatusFound, false},
+		{"redirect without location", RenderOptions{Status: http.StatusFound}, http.StatusInternalServerError, true},
+		{"not modified", RenderOptions{Status: http.StatusNotModified}, http.StatusNotModified, false},
+		{"page", RenderOptions{}, http.StatusOK, true},
+	}
+
+	wr := newTestWebRender(t)
+	for i, tt := range tests {
+		path := "/page" + string(rune('a'+i))
+		opts := tt.opts
+		wr.RouteWithOptions(path, "Page", func(*http.Request) (template.HTML, RenderOptions, error) {
+			return "<p>content</p>", opts, nil
+		}, nil, nil)
+
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			wr.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if hasBody := rec.Body.Len() > 0; hasBody != tt.wantBody {
+				t.Errorf("body present = %v, want %v", hasBody, tt.wantBody)
+			}
+			if tt.wantStatus == http.StatusFound && rec.Header().Get("Location") != "/login" {
+				t.Errorf("Location = %q, want /login", rec.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+// get serves a GET request through the router
+func get(wr *WebRender, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	wr.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestMultiComponentRouteRendersEachComponent(t *testing.T) {
+	wr := newTestWebRender(t)
+
+	clock := component.New("clock-1", "clock", `<div id="{{.ID}}">{{.props.zone}}{{.State.ScriptTag}}</div>`)
+	clock.State.Set("time", "12:00")
+	weather := component.New("weather-1", "weather", `<div id="{{.ID}}">{{.props.city}}{{.State.ScriptTag}}</div>`)
+	weather.State.Set("temp", 21)
+	for _, c := range []*component.Component{clock, weather} {
+		if err := wr.RegisterComponent(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wr.MultiComponentRoute("/dashboard", "Dashboard", []ComponentSpec{
+		{ID: "clock-1", Props: map[string]interface{}{"zone": "UTC"}},
+		{ID: "weather-1", Props: map[string]interface{}{"city": "Oslo"}},
+	})
+	wr.MultiComponentRoute("/broken", "Broken", []ComponentSpec{{ID: "clock-1"}, {ID: "missing-1"}})
+
+	rec := get(wr, "/dashboard")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	page := rec.Body.String()
+
+	clockAt := strings.Index(page, `<div id="clock-1">UTC`)
+	weatherAt := strings.Index(page, `<div id="weather-1">Oslo`)
+	if clockAt < 0 || weatherAt < 0 {
+		t.Fatalf("page is missing a component:\n%s", page)
+	}
+	if clockAt > weatherAt {
+		t.Error("components rendered out of order")
+	}
+	// Each component keeps its own state block so updates route correctly
+	for _, id := range []string{"clock-1-state", "weather-1-state"} {
+		if !strings.Contains(page, `id="`+id+`"`) {
+			t.Errorf("page is missing the %s block", id)
+		}
+	}
+
+	if rec := get(wr, "/broken"); rec.Code != http.StatusInternalServerError {
+		t.Errorf("page with a missing component: status = %d, want 500", rec.Code)
+	}
+}
+
+func TestStartFailsOnBadTemplate(t *testing.T) {
+	wr := newTestWebRender(t)
+
+	c := component.New("broken-1", "broken", `<p>{{.ID}}</p>`)
+	if err := wr.RegisterComponent(c); err != nil {
+		t.Fatal(err)
+	}
+	c.Template = `<p>{{range .ID}}</p>`
+
+	// The server must refuse to start rather than fail on the first request;
+	// the cancelled context stops it straight away if it does start
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := wr.StartWithContext(ctx, "127.0.0.1:0")
+	if err == nil || !strings.Contains(err.Error(), "broken-1") {
+		t.Errorf("StartWithContext error = %v, want a template error for broken-1", err)
+	}
+}
+
+func TestCustomSSEPathServedDuringMaintenance(t *testing.T) {
+	wr := newTestWebRender(t, func(c *Config) {
+		c.SSEPath = "/events"
+		c.MaintenanceMode = true
+	})
+
+	// Posts without a known client reach the SSE handler rather than the
+	// maintenance page
+	rec := httptest.NewRecorder()
+	wr.Router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/events?client=client-1", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "Unknown client") {
+		t.Errorf("POST /events: %d %q, want the SSE handler's unknown client error", rec.Code, rec.Body.String())
+	}
+
+	page := renderPage(t, newTestWebRender(t, func(c *Config) { c.SSEPath = "/events" }))
+	// html/template escapes the slash inside the script's string literal
+	if !strings.Contains(page, `sseUrl: '\/events'`) {
+		t.Error("base template does not point the client at the SSE path")
+	}
+}
@@ -35,6 +35,32 @@ func (b *Broadcaster) BroadcastStateUpdate(componentID, key string, value interf
 	return b.manager.BroadcastStateUpdate(update)
 }
 
+// BroadcastStateBatch sends several state changes of a component to all
+// connected clients in one message
+func (b *Broadcaster) BroadcastStateBatch(componentID string, changes map[string]interface{}) error {
+	if b.manager == nil {
+		return fmt.Errorf("broadcaster has no manager")
+	}
+
+	return b.manager.BroadcastStateBatch(StateBatch{
+		ComponentID: componentID,
+		Changes:     changes,
+	})
+}
+
+// BroadcastStateDeletes tells all connected clients that several state keys
+// of a component were deleted, in one message
+func (b *Broadcaster) BroadcastStateDeletes(componentID string, keys []string) error {
+	if b.manager == nil {
+		return fmt.Errorf("broadcaster has no manager")
+	}
+
+	return b.manager.BroadcastStateBatch(StateBatch{
+		ComponentID: componentID,
+		Deleted:     keys,
+	})
+}
+
 // StateUpdateMessage represents a state update message
 // Kept for backwards compatibility
 type StateUpdateMessage struct {
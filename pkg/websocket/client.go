@@ -0,0 +1,15 @@
+package websocket
+
+import (
+	_ "embed"
+)
+
+// clientJS is the browser client compiled into the binary
+//
+//go:embed client.js
+var clientJS string
+
+// ClientJS returns the browser WebSocket client script (WSManager)
+func ClientJS() string {
+	return clientJS
+}
@@ -1,6 +1,7 @@
 /**
  * WebRender WebSocket Client
- * Handles client-side WebSocket communication with improved reliability
+ * Handles client-side WebSocket communication with improved reliability,
+ * falling back to Server-Sent Events when WebSockets are unavailable
  */
 const WSManager = {
     ws: null,
@@ -13,14 +14,34 @@ const WSManager = {
     handlers: {},
     isConnected: false,
     pendingUpdates: {},
+    stateHashes: {}, // Server state hash per component from the last refresh
+    subscriptions: null, // Component IDs to receive updates for (null = components on the page)
     hadPreviousConnection: false,
     
+    // Transport ('websocket' or 'sse') and Server-Sent Events fallback state
+    transport: 'websocket',
+    sse: null,
+    sseUrl: '/sse',
+    sseClientId: null,
+    sseToken: null,
+    wsOpened: false,
+    wsFailures: 0,
+    maxWebSocketFailures: 2,
+    
     /**
      * Initialize the WebSocket connection
      * @param {string} url - The WebSocket URL to connect to
+     * @param {object} options - Optional settings: sseUrl, transport ('websocket' or 'sse')
      */
-    init(url) {
+    init(url, options = {}) {
         this.url = url;
+        this.sseUrl = options.sseUrl || this.sseUrl;
+        this.transport = options.transport || this.transport;
+        
+        // Use Server-Sent Events where WebSockets aren't supported
+        if (!('WebSocket' in window) && 'EventSource' in window) {
+            this.transport = 'sse';
+        }
         this.messageQueue = this.messageQueue || [];
         this.pendingUpdates = this.pendingUpdates || {};
         this.handlers = this.handlers || {};
@@ -57,6 +78,11 @@ const WSManager = {
      * Connect to the WebSocket server
      */
     connect() {
+        if (this.transport === 'sse') {
+            this.connectSSE();
+            return;
+        }
+        
         if (this.ws && (this.ws.readyState === WebSocket.OPEN || this.ws.readyState === WebSocket.CONNECTING)) {
             return;
         }
@@ -67,63 +93,38 @@ const WSManager = {
             
             this.ws.onopen = () => {
                 console.log('WebSocket connection established');
-                this.isConnected = true;
-                this.reconnectAttempts = 0;
-                this.reconnectTimeout = 1000;
-                
-                // First process any queued messages
-                this.processQueue();
-                
-                // If this was a reconnection (not initial connection),
-                // clear any component data-bind elements to ensure clean state
-                if (this.hadPreviousConnection) {
-                    console.log('Reconnected after disconnection, refreshing all component states');
-                } else {
-                    this.hadPreviousConnection = true;
-                }
-                
-                // Always request state refresh from server to ensure client state is synchronized
-                this.requestStateRefresh();
-                
-                // Trigger any onConnect handlers
-                this.triggerHandlers('connect', {});
+                this.wsOpened = true;
+                this.handleOpen();
             };
             
             this.ws.onmessage = (event) => {
-                try {
-                    const message = JSON.parse(event.data);
-                    
-                    // Handle heartbeat messages internally
-                    if (message.type === 'heartbeat') {
-                        this.handleHeartbeat(message);
-                        return;
-                    }
-                    
-                    // Handle state update messages with DOM updates
-                    if (message.type === 'state_update') {
-                        // Log received message for debugging
-                        console.log('Received state update:', message);
-                        
-                        // Handle the payload
-                        this.handleStateUpdate(message.payload);
-                    }
-                    
-                    // Trigger handlers for this message type
-                    this.triggerHandlers(message.type, message.payload);
-                    
-                    // Also trigger any 'message' handlers
-                    this.triggerHandlers('message', message);
-                } catch (error) {
-                    console.error('Error processing message:', error, event.data);
-                }
+                this.handleMessage(event.data);
             };
             
             this.ws.onclose = (event) => {
                 this.isConnected = false;
                 
-                // Don't attempt to reconnect if this was a clean close
-                if (event.wasClean) {
+                // A WebSocket that never opens is likely blocked by a proxy,
+                // so switch to Server-Sent Events after a few failures
+                if (!this.wsOpened && !event.wasClean && 'EventSource' in window) {
+                    this.wsFailures++;
+                    if (this.wsFailures >= this.maxWebSocketFailures) {
+                        console.log('WebSocket unavailable, falling back to Server-Sent Events');
+                        this.transport = 'sse';
+                        this.reconnectAttempts = 0;
+                        this.reconnectTimeout = 1000;
+                        this.connectSSE();
+                        return;
+                    }
+                }
+                
+                // Don't attempt to reconnect if this was a clean close,
+                // unless the server is restarting (1012 Service Restart)
+                if (event.wasClean && event.code !== 1012) {
                     console.log(`WebSocket connection closed cleanly, code=${event.code}, reason=${event.reason}`);
+                } else if (event.wasClean) {
+                    console.log(`Server restarting, code=${event.code}, reason=${event.reason}`);
+                    this.scheduleReconnect();
                 } else {
                     console.log(`WebSocket connection lost, code=${event.code}`);
                     this.scheduleReconnect();
@@ -145,6 +146,276 @@ const WSManager = {
         }
     },
     
+    /**
+     * Connect to the Server-Sent Events stream
+     */
+    connectSSE() {
+        if (this.sse && this.sse.readyState !== EventSource.CLOSED) {
+            return;
+        }
+        
+        console.log('Connecting to Server-Sent Events stream at', this.sseUrl);
+        this.sse = new EventSource(this.sseUrl);
+        
+        // The server sends the client ID and token used to post messages back
+        this.sse.addEventListener('connected', (event) => {
+            try {
+                const hello = JSON.parse(event.data);
+                this.sseClientId = hello.client_id;
+                this.sseToken = hello.token;
+            } catch (error) {
+                console.error('Invalid SSE connected event:', error, event.data);
+                return;
+            }
+            console.log('Server-Sent Events connection established');
+            this.handleOpen();
+        });
+        
+        this.sse.onmessage = (event) => {
+            this.handleMessage(event.data);
+        };
+        
+        // Server is shutting down; reconnect with backoff
+        this.sse.addEventListener('close', () => {
+            console.log('Server closed the event stream');
+            this.sse.close();
+            this.isConnected = false;
+            this.triggerHandlers('disconnect', { code: 1012, reason: 'server shutting down' });
+            this.scheduleReconnect();
+        });
+        
+        this.sse.onerror = (error) => {
+            if (this.isConnected) {
+                this.isConnected = false;
+                this.triggerHandlers('disconnect', { code: 1006, reason: '' });
+            }
+            this.triggerHandlers('error', error);
+            
+            // EventSource retries on its own unless the server refused the stream
+            if (this.sse.readyState === EventSource.CLOSED) {
+                this.scheduleReconnect();
+            }
+        };
+    },
+    
+    /**
+     * Handle a newly established connection on either transport
+     */
+    handleOpen() {
+        this.isConnected = true;
+        this.reconnectAttempts = 0;
+        this.reconnectTimeout = 1000;
+        
+        // Tell the server which components this page shows
+        this.sendSubscriptions();
+        
+        // First process any queued messages
+        this.processQueue();
+        
+        // If this was a reconnection (not initial connection),
+        // clear any component data-bind elements to ensure clean state
+        if (this.hadPreviousConnection) {
+            console.log('Reconnected after disconnection, refreshing all component states');
+        } else {
+            this.hadPreviousConnection = true;
+        }
+        
+        // Always request state refresh from server to ensure client state is synchronized
+        this.requestStateRefresh();
+        
+        // Trigger any onConnect handlers
+        this.triggerHandlers('connect', {});
+    },
+    
+    /**
+     * Handle a message received on either transport
+     * @param {string} data - The raw JSON message
+     */
+    handleMessage(data) {
+        try {
+            const message = JSON.parse(data);
+            
+            // Handle heartbeat messages internally
+            if (message.type === 'heartbeat') {
+                this.handleHeartbeat(message);
+                return;
+            }
+            
+            // Handle state update messages with DOM updates
+            if (message.type === 'state_update') {
+                // Log received message for debugging
+                console.log('Received state update:', message);
+                
+                // Our state now differs from the last refresh
+                if (message.payload) {
+                    delete this.stateHashes[message.payload.component_id];
+                }
+
+                // Handle the payload
+                this.handleStateUpdate(message.payload);
+            }
+
+            // Apply batched state changes and deletions key by key
+            if (message.type === 'state_batch' && message.payload) {
+                delete this.stateHashes[message.payload.component_id];
+                Object.entries(message.payload.changes || {}).forEach(([key, value]) => {
+                    this.handleStateUpdate({
+                        component_id: message.payload.component_id,
+                        key: key,
+                        value: value,
+                        type: 'update'
+                    });
+                });
+                (message.payload.deleted || []).forEach(key => {
+                    this.handleStateUpdate({
+                        component_id: message.payload.component_id,
+                        key: key,
+                        value: null,
+                        type: 'delete'
+                    });
+                });
+            }
+
+            // Apply a component's full state from a refresh:
+            // { component_id, state: { key: value, ... } }
+            if (message.type === 'state_snapshot' && message.payload) {
+                Object.entries(message.payload.state || {}).forEach(([key, value]) => {
+                    this.handleStateUpdate({
+                        component_id: message.payload.component_id,
+                        key: key,
+                        value: value,
+                        type: 'update'
+                    });
+                });
+            }
+
+            // Surface actions the server rejected
+            if (message.type === 'action_error') {
+                console.warn(`Action ${message.payload.action} failed for ${message.payload.component_id}: ${message.payload.error}`);
+            }
+
+            // Hand windows of large lists to the component that asked for them
+            if (message.type === 'window_update') {
+                const component = document.getElementById(message.payload.component_id);
+                if (component) {
+                    component.dispatchEvent(new CustomEvent('window-update', {
+                        detail: message.payload
+                    }));
+                }
+            }
+
+            // Remember which state we're in sync with for the next reconnect
+            if (message.type === 'state_refresh_ack') {
+                this.stateHashes = message.payload.hashes || {};
+            }
+
+            // Drop components the server unregistered
+            if (message.type === 'component_removed') {
+                const id = message.payload.component_id;
+                delete this.stateHashes[id];
+                delete this.pendingUpdates[id];
+                const component = document.getElementById(id);
+                if (component) {
+                    component.dispatchEvent(new CustomEvent('component-removed', {
+                        detail: { component_id: id }
+                    }));
+                    component.remove();
+                }
+                const stateScript = document.getElementById(`${id}-state`);
+                if (stateScript) {
+                    stateScript.remove();
+                }
+            }
+
+            // The server is about to restart; handlers can show a banner
+            if (message.type === 'shutdown') {
+                console.log(`Server shutting down in ${message.payload.grace_ms}ms`);
+                document.dispatchEvent(new CustomEvent('webrender:shutdown', {
+                    detail: message.payload
+                }));
+            }
+
+            // Swap in freshly rendered component HTML
+            if (message.type === 'render') {
+                delete this.stateHashes[message.payload.component_id];
+                this.handleRender(message.payload);
+            }
+
+            // Follow server-driven redirects
+            if (message.type === 'navigate') {
+                this.handleNavigate(message.payload);
+                return;
+            }
+
+            // Trigger handlers for this message type
+            this.triggerHandlers(message.type, message.payload);
+            
+            // Also trigger any 'message' handlers
+            this.triggerHandlers('message', message);
+        } catch (error) {
+            console.error('Error processing message:', error, data);
+        }
+    },
+    
+    /**
+     * Replace a component's element with HTML rendered by the server
+     * @param {object} payload - The render payload ({component_id, html})
+     */
+    handleRender(payload) {
+        const current = document.getElementById(payload.component_id);
+        if (!current) {
+            return;
+        }
+
+        const fragment = document.createElement('template');
+        fragment.innerHTML = payload.html;
+        const replacement = fragment.content.getElementById(payload.component_id);
+        if (!replacement) {
+            console.error(`Rendered HTML for ${payload.component_id} has no element with that ID`);
+            return;
+        }
+
+        // Carry the state block along so getState keeps working
+        const stateScript = document.getElementById(`${payload.component_id}-state`);
+        const newStateScript = fragment.content.getElementById(`${payload.component_id}-state`);
+        if (stateScript && newStateScript) {
+            stateScript.replaceWith(newStateScript);
+        }
+
+        current.replaceWith(replacement);
+        replacement.dispatchEvent(new CustomEvent('rendered', {
+            detail: { component_id: payload.component_id }
+        }));
+    },
+
+    /**
+     * Navigate to a URL sent by the server
+     * @param {object} payload - The navigate payload ({url, replace})
+     */
+    handleNavigate(payload) {
+        let target;
+        try {
+            target = new URL(payload.url, window.location.href);
+        } catch (error) {
+            console.error('Invalid navigation target:', payload.url);
+            return;
+        }
+
+        // Never follow javascript: or data: URLs
+        if (target.protocol !== 'http:' && target.protocol !== 'https:') {
+            console.error('Refusing navigation to', payload.url);
+            return;
+        }
+
+        this.triggerHandlers('navigate', payload);
+
+        if (payload.replace) {
+            window.location.replace(target.href);
+        } else {
+            window.location.assign(target.href);
+        }
+    },
+
     /**
      * Schedule a reconnection attempt with exponential backoff
      */
@@ -198,6 +469,26 @@ const WSManager = {
             return false;
         }
         
+        // Server-Sent Events are one-way, so post messages back
+        if (this.transport === 'sse') {
+            fetch(`${this.sseUrl}?client=${encodeURIComponent(this.sseClientId)}`, {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                    'X-SSE-Token': this.sseToken
+                },
+                body: JSON.stringify(message)
+            }).then(response => {
+                if (!response.ok) {
+                    throw new Error(`HTTP ${response.status}`);
+                }
+            }).catch(error => {
+                console.error('Error sending message:', error);
+                this.messageQueue.push(message);
+            });
+            return true;
+        }
+        
         try {
             this.ws.send(JSON.stringify(message));
             return true;
@@ -251,14 +542,114 @@ const WSManager = {
      * @param {string} componentId - The component ID
      * @param {string} action - The action name
      * @param {object} params - The action parameters
+     * @param {string} [idempotencyKey] - Key that makes resends of this
+     *   action (e.g. after a reconnect) run once; one is generated if omitted
      */
-    sendAction(componentId, action, params) {
+    sendAction(componentId, action, params, idempotencyKey) {
         const message = {
             type: 'action',
             payload: {
                 component_id: componentId,
                 action: action,
-                params: params
+                params: params,
+                nonce: this.newNonce(),
+                idempotency_key: idempotencyKey || this.newNonce()
+            }
+        };
+        
+        this.sendRaw(message);
+    },
+    
+    /**
+     * Only receive state updates for the given components
+     * By default the components on the page are subscribed on connect.
+     * @param {string[]} componentIds - Component IDs; empty for all components
+     */
+    subscribe(componentIds) {
+        this.subscriptions = componentIds;
+        if (this.isConnected) {
+            this.sendSubscriptions();
+        }
+    },
+    
+    /**
+     * Send the current subscriptions to the server
+     */
+    sendSubscriptions() {
+        const ids = this.subscriptions !== null ? this.subscriptions :
+            Array.from(document.querySelectorAll('[data-component-type][id], [data-state][id]'), el => el.id);
+        
+        this.sendRaw({
+            type: 'subscribe',
+            payload: {
+                component_ids: ids,
+                // Refreshes arrive as one state_snapshot per component
+                capabilities: ['state_snapshot']
+            }
+        });
+    },
+    
+    /**
+     * Generate a single-use nonce for an action
+     * Components can require one on sensitive actions to reject replays.
+     * The server only accepts nonces whose timestamp is recent.
+     * @returns {string} - "<unix milliseconds>-<random hex>"
+     */
+    newNonce() {
+        const bytes = new Uint8Array(16);
+        window.crypto.getRandomValues(bytes);
+        return `${Date.now()}-` + Array.from(bytes, b => b.toString(16).padStart(2, '0')).join('');
+    },
+
+    /**
+     * Send a component action together with binary data (e.g. a File)
+     * The method receives the bytes in params.upload on the server.
+     * Uploads need a WebSocket connection; the SSE fallback can't carry them.
+     * @param {string} componentId - The component ID
+     * @param {string} action - The action name
+     * @param {object} params - The action parameters
+     * @param {Blob|ArrayBuffer} data - The binary data to upload
+     * @returns {boolean} - Whether the upload was sent
+     */
+    sendActionWithUpload(componentId, action, params, data) {
+        if (this.transport !== 'websocket' || !this.isConnected) {
+            console.error('Uploads require an open WebSocket connection');
+            return false;
+        }
+        
+        const uploadId = `upload-${Date.now()}-${Math.random().toString(36).slice(2, 10)}`;
+        
+        // Binary frame: upload ID, newline, then the data
+        this.ws.send(new Blob([`${uploadId}\n`, data]));
+        
+        this.sendRaw({
+            type: 'action',
+            payload: {
+                component_id: componentId,
+                action: action,
+                params: params,
+                upload_id: uploadId,
+                nonce: this.newNonce()
+            }
+        });
+        return true;
+    },
+    
+    /**
+     * Request a window of a component's windowed list, e.g. while scrolling
+     * The reply arrives as a 'window-update' event on the component element
+     * with detail { offset, limit, items, total }.
+     * @param {string} componentId - The component ID
+     * @param {number} offset - Index of the first item
+     * @param {number} limit - Maximum number of items
+     */
+    requestWindow(componentId, offset, limit) {
+        const message = {
+            type: 'window_request',
+            payload: {
+                component_id: componentId,
+                offset: offset,
+                limit: limit
             }
         };
         
@@ -282,6 +673,49 @@ const WSManager = {
         this.sendRaw(response);
     },
     
+    /**
+     * Find the JSON script block holding a component's state, if it has one
+     * @param {HTMLElement} component - The component element
+     * @returns {HTMLScriptElement|null} The <script id="{id}-state"> element
+     */
+    stateScript(component) {
+        const script = document.getElementById(`${component.id}-state`);
+        if (script && script.type === 'application/json') {
+            return script;
+        }
+        return null;
+    },
+    
+    /**
+     * Read a component's state from its JSON script block or data-state attribute
+     * @param {HTMLElement} component - The component element
+     * @returns {Object} The parsed state
+     */
+    getState(component) {
+        const script = this.stateScript(component);
+        const raw = script ? script.textContent : component.getAttribute('data-state');
+        try {
+            return JSON.parse(raw || '{}');
+        } catch (err) {
+            console.warn('Error parsing component state, resetting:', err);
+            return {};
+        }
+    },
+    
+    /**
+     * Store a component's state wherever it was rendered
+     * @param {HTMLElement} component - The component element
+     * @param {Object} state - The state to store
+     */
+    setState(component, state) {
+        const script = this.stateScript(component);
+        if (script) {
+            script.textContent = JSON.stringify(state);
+        } else {
+            component.setAttribute('data-state', JSON.stringify(state));
+        }
+    },
+    
     /**
      * Handle a state update message by updating the DOM
      * @param {Object} payload - The update payload
@@ -309,22 +743,16 @@ const WSManager = {
             return;
         }
         
-        // Update component's data-state attribute
+        // Update component's stored state
         try {
             // Get current state
-            let currentState;
-            try {
-                currentState = JSON.parse(component.getAttribute('data-state') || '{}');
-            } catch (err) {
-                console.warn('Error parsing component state, resetting:', err);
-                currentState = {};
-            }
+            const currentState = this.getState(component);
             
             // Update with new value
             currentState[payload.key] = payload.value;
             
             // Set updated state
-            component.setAttribute('data-state', JSON.stringify(currentState));
+            this.setState(component, currentState);
             
             // Update any DOM elements with data-bind attribute
             const boundElements = component.querySelectorAll(`[data-bind="${payload.key}"]`);
@@ -427,22 +855,16 @@ const WSManager = {
                 console.log(`Found component ${id} in DOM, applying ${Object.keys(this.pendingUpdates[id]).length} pending updates`);
                 const updates = this.pendingUpdates[id];
                 
-                // First update the data-state attribute with all pending updates
-                let currentState;
-                try {
-                    currentState = JSON.parse(component.getAttribute('data-state') || '{}');
-                } catch (err) {
-                    console.warn('Error parsing component state, resetting:', err);
-                    currentState = {};
-                }
+                // First update the stored state with all pending updates
+                const currentState = this.getState(component);
                 
                 // Apply all updates to the state object
                 Object.keys(updates).forEach(key => {
                     currentState[key] = updates[key];
                 });
                 
-                // Set the updated state attribute
-                component.setAttribute('data-state', JSON.stringify(currentState));
+                // Set the updated state
+                this.setState(component, currentState);
                 
                 // Then apply each pending update to DOM elements
                 Object.keys(updates).forEach(key => {
@@ -518,6 +940,10 @@ const WSManager = {
         if (this.ws) {
             this.ws.close(1000, 'Client closed connection');
         }
+        if (this.sse) {
+            this.sse.close();
+            this.isConnected = false;
+        }
     },
 
     /**
@@ -525,15 +951,16 @@ const WSManager = {
      * Called after reconnection to ensure client state is in sync
      */
     requestStateRefresh() {
+        // Send the hashes we're in sync with so unchanged state isn't replayed
         const message = {
             type: 'state_refresh_request',
-            payload: {}
+            payload: { hashes: this.stateHashes }
         };
         
         console.log('Requesting state refresh from server');
         
         // Only send if connected, otherwise queue the request for when connection is established
-        if (this.isConnected && this.ws && this.ws.readyState === WebSocket.OPEN) {
+        if (this.isConnected && (this.transport === 'sse' || (this.ws && this.ws.readyState === WebSocket.OPEN))) {
             this.sendRaw(message);
         } else {
             console.log('Not connected, queueing state refresh request');
@@ -544,9 +971,8 @@ const WSManager = {
             }
             
             // If we're not already attempting to connect, try to connect now
-            if (!this.ws || this.ws.readyState === WebSocket.CLOSED) {
-                this.connect();
-            }
+            // (connect is a no-op while a connection is open or pending)
+            this.connect();
         }
     }
 }; 
\ No newline at end of file
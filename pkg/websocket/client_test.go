@@ -0,0 +1,16 @@
+package websocket
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClientJSIsEmbedded(t *testing.T) {
+	js := ClientJS()
+	if js == "" {
+		t.Fatal("ClientJS returned an empty script")
+	}
+	if !strings.Contains(js, "WSManager") {
+		t.Error("ClientJS does not define WSManager")
+	}
+}
@@ -0,0 +1,39 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestStopSendsCloseFrameBeforeClosing(t *testing.T) {
+	m := newTestManager(t)
+	conn, _ := connect(t, m)
+
+	m.Stop()
+
+	frames := conn.closeFrames(t)
+	if len(frames) != 1 {
+		t.Fatalf("got %d close frames, want 1", len(frames))
+	}
+	if frames[0].Code != websocket.CloseServiceRestart || frames[0].Text != "server shutting down" {
+		t.Errorf("close frame = %d %q, want %d \"server shutting down\"", frames[0].Code, frames[0].Text, websocket.CloseServiceRestart)
+	}
+
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	if conn.closedAfter != 1 {
+		t.Errorf("connection closed after %d control frames, want after the close frame", conn.closedAfter)
+	}
+	if m.Stats().Clients != 0 {
+		t.Errorf("clients = %d after Stop, want 0", m.Stats().Clients)
+	}
+}
+
+func TestStopTwiceIsSafe(t *testing.T) {
+	m := newTestManager(t)
+	connect(t, m)
+
+	m.Stop()
+	m.Stop()
+}
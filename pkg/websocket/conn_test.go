@@ -0,0 +1,153 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/magooney-loon/webrender/pkg/logger"
+)
+
+// fakeConn is an in-memory connection that records what the manager writes
+type fakeConn struct {
+	incoming  chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	mutex    sync.Mutex
+	written  []Message
+	controls []controlFrame
+	// Number of control frames written before Close, or -1
+	closedAfter int
+}
+
+// controlFrame is a control message written to a fakeConn
+type controlFrame struct {
+	messageType int
+	data        []byte
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{
+		incoming:    make(chan []byte, 16),
+		closed:      make(chan struct{}),
+		closedAfter: -1,
+	}
+}
+
+func (c *fakeConn) ReadMessage() (int, []byte, error) {
+	select {
+	case data := <-c.incoming:
+		return websocket.TextMessage, data, nil
+	case <-c.closed:
+		return 0, nil, errors.New("connection closed")
+	}
+}
+
+func (c *fakeConn) WriteMessage(messageType int, data []byte) error {
+	var message Message
+	if err := json.Unmarshal(data, &message); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.written = append(c.written, message)
+	return nil
+}
+
+func (c *fakeConn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.controls = append(c.controls, controlFrame{messageType: messageType, data: data})
+	return nil
+}
+
+func (c *fakeConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *fakeConn) SetWriteDeadline(time.Time) error { return nil }
+
+func (c *fakeConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.mutex.Lock()
+		c.closedAfter = len(c.controls)
+		c.mutex.Unlock()
+		close(c.closed)
+	})
+	return nil
+}
+
+// closeFrames returns the close codes and reasons written so far
+func (c *fakeConn) closeFrames(t *testing.T) []*websocket.CloseError {
+	t.Helper()
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var frames []*websocket.CloseError
+	for _, frame := range c.controls {
+		if frame.messageType != websocket.CloseMessage {
+			continue
+		}
+		if len(frame.data) < 2 {
+			t.Fatalf("close frame without a status code: %q", frame.data)
+		}
+		frames = append(frames, &websocket.CloseError{
+			Code: int(frame.data[0])<<8 | int(frame.data[1]),
+			Text: string(frame.data[2:]),
+		})
+	}
+	return frames
+}
+
+// messages returns the messages of one type written so far
+func (c *fakeConn) messages(msgType MessageType) []Message {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var matched []Message
+	for _, message := range c.written {
+		if message.Type == msgType {
+			matched = append(matched, message)
+		}
+	}
+	return matched
+}
+
+// waitFor polls until cond holds or fails the test after a second
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// newTestManager returns a quiet manager stopped with the test
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+
+	opts := DefaultManagerOptions()
+	opts.Logger = logger.Discard()
+	m := NewManagerWithOptions(opts)
+	t.Cleanup(m.Stop)
+	return m
+}
+
+// connect accepts a fake client and waits until it is registered
+func connect(t *testing.T, m *Manager) (*fakeConn, *Client) {
+	t.Helper()
+
+	conn := newFakeConn()
+	before := m.Stats().Clients
+	client := m.Accept(conn)
+	waitFor(t, "client registration", func() bool {
+		return m.Stats().Clients > before
+	})
+	return conn, client
+}
@@ -0,0 +1,92 @@
+package websocket
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// dial opens a WebSocket connection to a test server for the manager
+func dial(t *testing.T, m *Manager) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	return dialWithHeader(t, m, nil)
+}
+
+// dialWithHeader dials like dial, sending extra request headers
+func dialWithHeader(t *testing.T, m *Manager, header http.Header) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(m.HandleConnection))
+	t.Cleanup(server.Close)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	if conn != nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, resp, err
+}
+
+func TestOnConnectAcceptsConnection(t *testing.T) {
+	m := newTestManager(t)
+
+	var seen string
+	m.OnConnect(func(r *http.Request) error {
+		seen = r.URL.Path
+		return nil
+	})
+
+	if _, _, err := dial(t, m); err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	waitFor(t, "client registration", func() bool {
+		return m.Stats().Clients == 1
+	})
+	if seen != "/" {
+		t.Errorf("OnConnect saw path %q, want %q", seen, "/")
+	}
+}
+
+func TestOnConnectRejectsConnection(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantReason string
+	}{
+		{"rejection", RejectConnection(http.StatusServiceUnavailable, "down for maintenance"), http.StatusServiceUnavailable, "down for maintenance"},
+		{"plain error", errors.New("banned"), http.StatusForbidden, "banned"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t)
+			m.OnConnect(func(r *http.Request) error { return tt.err })
+
+			_, resp, err := dial(t, m)
+			if err == nil {
+				t.Fatal("dial succeeded, want the upgrade to be refused")
+			}
+			if resp == nil {
+				t.Fatalf("dial failed without a response: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if got := strings.TrimSpace(string(body)); got != tt.wantReason {
+				t.Errorf("reason = %q, want %q", got, tt.wantReason)
+			}
+			if clients := m.Stats().Clients; clients != 0 {
+				t.Errorf("%d clients registered, want none", clients)
+			}
+		})
+	}
+}
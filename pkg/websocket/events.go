@@ -0,0 +1,77 @@
+package websocket
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultEventBuffer is the number of connection events kept for a slow
+// subscriber before new events are dropped
+const DefaultEventBuffer = 256
+
+// ConnectionEventType names a connection lifecycle event
+type ConnectionEventType string
+
+const (
+	// ConnectionEventConnect is emitted when a client is registered
+	ConnectionEventConnect ConnectionEventType = "connect"
+	// ConnectionEventDisconnect is emitted when a client is removed
+	ConnectionEventDisconnect ConnectionEventType = "disconnect"
+	// ConnectionEventError is emitted when a client connection fails
+	ConnectionEventError ConnectionEventType = "error"
+)
+
+// ConnectionEvent describes a client connecting, disconnecting, or failing
+type ConnectionEvent struct {
+	Type     ConnectionEventType `json:"type"`
+	ClientID string              `json:"client_id"`
+	Time     time.Time           `json:"time"`
+	Reason   string              `json:"reason,omitempty"`
+}
+
+// eventStream is the connection event channel, created on first use so
+// managers nobody watches don't fill a buffer with stale events
+type eventStream struct {
+	ch    chan ConnectionEvent
+	mutex sync.RWMutex
+}
+
+// Events returns the stream of connection events
+// Events are recorded from the first call on. They are buffered
+// (DefaultEventBuffer) and dropped once the buffer is full, so a slow or
+// departed reader never blocks the manager but misses new events; see
+// ManagerStats.DroppedEvents. The channel is shared, so each event goes to
+// only one reader, and it is never closed.
+func (m *Manager) Events() <-chan ConnectionEvent {
+	m.events.mutex.Lock()
+	defer m.events.mutex.Unlock()
+
+	if m.events.ch == nil {
+		m.events.ch = make(chan ConnectionEvent, DefaultEventBuffer)
+	}
+	return m.events.ch
+}
+
+// emitEvent queues a connection event without blocking
+func (m *Manager) emitEvent(eventType ConnectionEventType, clientID, reason string) {
+	m.events.mutex.RLock()
+	events := m.events.ch
+	m.events.mutex.RUnlock()
+	if events == nil {
+		return
+	}
+
+	event := ConnectionEvent{
+		Type:     eventType,
+		ClientID: clientID,
+		Time:     time.Now(),
+		Reason:   reason,
+	}
+
+	select {
+	case events <- event:
+	default:
+		atomic.AddInt64(&m.droppedEvents, 1)
+	}
+}
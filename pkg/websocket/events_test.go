@@ -0,0 +1,40 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventsRecordedOnlyOnceRequested(t *testing.T) {
+	m := newTestManager(t)
+	connect(t, m)
+
+	events := m.Events()
+	select {
+	case event := <-events:
+		t.Fatalf("got %+v from before Events was called", event)
+	default:
+	}
+
+	_, client := connect(t, m)
+	select {
+	case event := <-events:
+		if event.Type != ConnectionEventConnect || event.ClientID != client.ID {
+			t.Errorf("event = %+v, want connect for %s", event, client.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no connect event")
+	}
+}
+
+func TestEventsDroppedWhenBufferFull(t *testing.T) {
+	m := newTestManager(t)
+	m.Events()
+
+	for i := 0; i < DefaultEventBuffer+3; i++ {
+		m.emitEvent(ConnectionEventConnect, "client", "")
+	}
+	if got := m.Stats().DroppedEvents; got != 3 {
+		t.Errorf("DroppedEvents = %d, want 3", got)
+	}
+}
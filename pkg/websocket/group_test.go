@@ -0,0 +1,42 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestBroadcastToGroupReachesOnlyMatchingClients(t *testing.T) {
+	m := newTestManager(t)
+
+	roles := []string{"admin", "viewer", "admin"}
+	conns := make([]*fakeConn, len(roles))
+	for i, role := range roles {
+		var client *Client
+		conns[i], client = connect(t, m)
+		client.SetMetadata("role", role)
+	}
+
+	payload, _ := json.Marshal(map[string]string{"text": "deploy starting"})
+	err := m.BroadcastToGroup(func(c *Client) bool {
+		return c.Metadata("role") == "admin"
+	}, Message{Type: MessageTypeEvent, Payload: payload})
+	if err != nil {
+		t.Fatalf("BroadcastToGroup: %v", err)
+	}
+
+	for i, role := range roles {
+		if role != "admin" {
+			continue
+		}
+		waitFor(t, "the admin message", func() bool {
+			return len(conns[i].messages(MessageTypeEvent)) == 1
+		})
+	}
+
+	// Give a stray delivery to the viewer time to show up
+	time.Sleep(20 * time.Millisecond)
+	if got := conns[1].messages(MessageTypeEvent); len(got) != 0 {
+		t.Errorf("viewer received %d group messages, want none", len(got))
+	}
+}
@@ -0,0 +1,74 @@
+package websocket
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// DefaultPongWait is how long a client may go without answering a ping
+	// before it is dropped
+	DefaultPongWait = 60 * time.Second
+
+	// DefaultPingPeriod is how often clients are pinged; it must be shorter
+	// than the pong wait
+	DefaultPingPeriod = DefaultPongWait * 9 / 10
+)
+
+// pongConn is implemented by connections that report pong frames, such as
+// *websocket.Conn from gorilla/websocket
+type pongConn interface {
+	SetPongHandler(h func(appData string) error)
+}
+
+// keepAlive pings a client every PingPeriod and pushes its read deadline
+// out by PongWait whenever it answers or sends a message, so the reader
+// fails and the client is unregistered once it stops responding
+// Connections that can't report pongs, like SSE streams, are left alone.
+// It returns functions that stop the pings and extend the deadline.
+func (m *Manager) keepAlive(conn Conn, client *Client) (stop func(), extend func()) {
+	ponger, ok := conn.(pongConn)
+	if !ok || m.PongWait <= 0 || m.PingPeriod <= 0 {
+		return func() {}, func() {}
+	}
+
+	pongWait := m.PongWait
+	extend = func() {
+		client.Conn.SetReadDeadline(time.Now().Add(pongWait))
+	}
+	ponger.SetPongHandler(func(string) error {
+		extend()
+		return nil
+	})
+	extend()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(m.PingPeriod)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				deadline := time.Now().Add(closeWriteWait)
+				if err := client.Conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+					// The reader notices the dead connection on its own
+					m.Logger().Debugf("Error pinging client %s: %v", client.ID, err)
+				}
+			}
+		}
+	}()
+
+	return func() { close(done) }, extend
+}
+
+// isTimeout reports whether a read failed because its deadline passed
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,156 @@
+package websocket
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// timeoutError is returned by pingConn reads past the read deadline
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// pingConn is a fakeConn that honours read deadlines and reports pongs,
+// answering pings only when answer is set
+type pingConn struct {
+	*fakeConn
+	answer bool
+
+	mutex    sync.Mutex
+	deadline time.Time
+	onPong   func(appData string) error
+}
+
+func newPingConn(answer bool) *pingConn {
+	return &pingConn{fakeConn: newFakeConn(), answer: answer}
+}
+
+func (c *pingConn) SetReadDeadline(deadline time.Time) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.deadline = deadline
+	return nil
+}
+
+func (c *pingConn) SetPongHandler(h func(appData string) error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.onPong = h
+}
+
+func (c *pingConn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if err := c.fakeConn.WriteControl(messageType, data, deadline); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	onPong := c.onPong
+	c.mutex.Unlock()
+	if messageType == websocket.PingMessage && c.answer && onPong != nil {
+		return onPong(string(data))
+	}
+	return nil
+}
+
+func (c *pingConn) ReadMessage() (int, []byte, error) {
+	ticker := time.NewTicker(2 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case data := <-c.incoming:
+			return websocket.TextMessage, data, nil
+		case <-c.closed:
+			return c.fakeConn.ReadMessage()
+		case <-ticker.C:
+			c.mutex.Lock()
+			expired := !c.deadline.IsZero() && time.Now().After(c.deadline)
+			c.mutex.Unlock()
+			if expired {
+				return 0, nil, timeoutError{}
+			}
+		}
+	}
+}
+
+// pings returns the number of ping frames written so far
+func (c *pingConn) pings() int {
+	c.fakeConn.mutex.Lock()
+	defer c.fakeConn.mutex.Unlock()
+
+	count := 0
+	for _, frame := range c.controls {
+		if frame.messageType == websocket.PingMessage {
+			count++
+		}
+	}
+	return count
+}
+
+// newPingingManager returns a test manager with short keepalive timings
+func newPingingManager(t *testing.T) *Manager {
+	t.Helper()
+
+	m := newTestManager(t)
+	m.PongWait = 60 * time.Millisecond
+	m.PingPeriod = 20 * time.Millisecond
+	return m
+}
+
+func TestClientThatNeverPongsIsUnregistered(t *testing.T) {
+	m := newPingingManager(t)
+	events := m.Events()
+
+	conn := newPingConn(false)
+	client := m.Accept(conn)
+	waitFor(t, "client registration", func() bool {
+		return m.Stats().Clients == 1
+	})
+
+	waitFor(t, "the silent client to be unregistered", func() bool {
+		return m.Stats().Clients == 0
+	})
+	if conn.pings() == 0 {
+		t.Error("no pings were sent before the client was dropped")
+	}
+
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Type != ConnectionEventDisconnect || event.ClientID != client.ID {
+				continue
+			}
+			if event.Reason != "missed pong deadline" {
+				t.Errorf("disconnect reason = %q, want missed pong deadline", event.Reason)
+			}
+			return
+		case <-deadline:
+			t.Fatal("no disconnect event")
+		}
+	}
+}
+
+func TestClientThatPongsStaysConnected(t *testing.T) {
+	m := newPingingManager(t)
+
+	conn := newPingConn(true)
+	m.Accept(conn)
+	waitFor(t, "client registration", func() bool {
+		return m.Stats().Clients == 1
+	})
+
+	// Outlast several pong waits
+	time.Sleep(4 * m.PongWait)
+	if m.Stats().Clients != 1 {
+		t.Error("a client answering pings was dropped")
+	}
+	if conn.pings() < 3 {
+		t.Errorf("sent %d pings, want one every ping period", conn.pings())
+	}
+}
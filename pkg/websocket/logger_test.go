@@ -0,0 +1,75 @@
+package websocket
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// capturingLogger keeps every message it is given
+type capturingLogger struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+func (l *capturingLogger) record(level, format string, args ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.messages = append(l.messages, level+": "+fmt.Sprintf(format, args...))
+}
+
+func (l *capturingLogger) Debugf(format string, args ...interface{}) {
+	l.record("debug", format, args...)
+}
+
+func (l *capturingLogger) Infof(format string, args ...interface{}) {
+	l.record("info", format, args...)
+}
+
+func (l *capturingLogger) Warnf(format string, args ...interface{}) {
+	l.record("warn", format, args...)
+}
+
+func (l *capturingLogger) Errorf(format string, args ...interface{}) {
+	l.record("error", format, args...)
+}
+
+// logged reports whether a message containing text was logged
+func (l *capturingLogger) logged(text string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for _, message := range l.messages {
+		if strings.Contains(message, text) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSetLoggerCapturesClientEvents(t *testing.T) {
+	m := newTestManager(t)
+	capture := &capturingLogger{}
+	m.SetLogger(capture)
+
+	conn, client := connect(t, m)
+	waitFor(t, "the registration log", func() bool {
+		return capture.logged("info: WebSocket client registered: " + client.ID)
+	})
+
+	conn.Close()
+	waitFor(t, "the unregistration log", func() bool {
+		return capture.logged("info: WebSocket client unregistered: " + client.ID)
+	})
+}
+
+func TestSetLoggerNilRestoresDefault(t *testing.T) {
+	m := newTestManager(t)
+	capture := &capturingLogger{}
+	m.SetLogger(capture)
+	m.SetLogger(nil)
+
+	if m.Logger() == nil || m.Logger() == capture {
+		t.Errorf("Logger() = %v after SetLogger(nil), want the default logger", m.Logger())
+	}
+}
@@ -1,14 +1,17 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/magooney-loon/webrender/internal/durations"
+	"github.com/magooney-loon/webrender/pkg/logger"
 )
 
 // MessageType defines the type of WebSocket message
@@ -17,20 +20,45 @@ type MessageType string
 const (
 	// MessageTypeStateUpdate for state changes
 	MessageTypeStateUpdate MessageType = "state_update"
+	// MessageTypeStateBatch for several state changes of one component
+	MessageTypeStateBatch MessageType = "state_batch"
+	// MessageTypeSubscribe for clients choosing which components they follow
+	MessageTypeSubscribe MessageType = "subscribe"
 	// MessageTypeEvent for component events
 	MessageTypeEvent MessageType = "event"
 	// MessageTypeHeartbeat for connection health checks
 	MessageTypeHeartbeat MessageType = "heartbeat"
 	// MessageTypeStateRefreshRequest for client requesting full state refresh
 	MessageTypeStateRefreshRequest MessageType = "state_refresh_request"
+	// MessageTypeStateSnapshot for the full state of one component
+	MessageTypeStateSnapshot MessageType = "state_snapshot"
+	// MessageTypeStateRefreshAck for reporting state hashes after a refresh
+	MessageTypeStateRefreshAck MessageType = "state_refresh_ack"
 	// MessageTypeAction for component actions
 	MessageTypeAction MessageType = "action"
+	// MessageTypeActionError for reporting rejected actions to the client
+	MessageTypeActionError MessageType = "action_error"
+	// MessageTypeWindowRequest for clients requesting a window of a large list
+	MessageTypeWindowRequest MessageType = "window_request"
+	// MessageTypeWindowUpdate for sending a window of a large list to a client
+	MessageTypeWindowUpdate MessageType = "window_update"
+	// MessageTypeNavigate for server-driven page navigation
+	MessageTypeNavigate MessageType = "navigate"
+	// MessageTypeComponentRemoved for components unregistered at runtime
+	MessageTypeComponentRemoved MessageType = "component_removed"
+	// MessageTypeShutdown for warning clients of an upcoming server shutdown
+	MessageTypeShutdown MessageType = "shutdown"
+	// MessageTypeRender for replacing a component's HTML on the client
+	MessageTypeRender MessageType = "render"
 )
 
 // Message represents a message sent over WebSocket
 type Message struct {
 	Type    MessageType     `json:"type"`
 	Payload json.RawMessage `json:"payload"`
+
+	// When the message was queued for broadcast, for latency stats
+	queuedAt time.Time
 }
 
 // StateUpdate represents a state change that needs to be broadcasted
@@ -46,12 +74,161 @@ type ActionMessage struct {
 	ComponentID string                 `json:"component_id"`
 	Action      string                 `json:"action"`
 	Params      map[string]interface{} `json:"params"`
+
+	// ID of a binary upload sent just before the action, if any
+	UploadID string `json:"upload_id,omitempty"`
+
+	// Single-use value checked for actions that require replay
+	// protection, "<unix milliseconds>-<random>" as client.js sends it
+	Nonce string `json:"nonce,omitempty"`
+
+	// Client-chosen key that makes retries of the action run it only once
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+// ActionError reports a rejected or failed action to the originating client
+type ActionError struct {
+	ComponentID string `json:"component_id"`
+	Action      string `json:"action"`
+	Error       string `json:"error"`
+}
+
+// WindowRequest asks for a window of a component's windowed data source
+type WindowRequest struct {
+	ComponentID string `json:"component_id"`
+	Offset      int    `json:"offset"`
+	Limit       int    `json:"limit"`
+}
+
+// WindowUpdate carries a window of a component's windowed data source
+type WindowUpdate struct {
+	ComponentID string        `json:"component_id"`
+	Offset      int           `json:"offset"`
+	Limit       int           `json:"limit"`
+	Items       []interface{} `json:"items"`
+	Total       int           `json:"total"`
+}
+
+// ConnectionRejection rejects a WebSocket connection with an HTTP status
+// Return it from an OnConnect hook to choose the status; any other error
+// rejects the connection with 403 Forbidden.
+type ConnectionRejection struct {
+	Status int
+	Reason string
+}
+
+// Error implements the error interface
+func (e *ConnectionRejection) Error() string {
+	return e.Reason
+}
+
+// RejectConnection creates a ConnectionRejection with the given status and reason
+func RejectConnection(status int, reason string) error {
+	return &ConnectionRejection{Status: status, Reason: reason}
+}
+
+// closeWriteWait is how long Stop waits for a close frame to be written
+const closeWriteWait = time.Second
+
+// ErrManagerStopped is returned when broadcasting on a stopped manager
+var ErrManagerStopped = errors.New("websocket manager stopped")
+
+// Conn is the subset of a WebSocket connection used by the manager
+// *websocket.Conn from gorilla/websocket satisfies it; other libraries can
+// be adapted and fakes injected through Manager.Accept.
+type Conn interface {
+	ReadMessage() (messageType int, p []byte, err error)
+	WriteMessage(messageType int, data []byte) error
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+	Close() error
+}
+
+// Ensure the gorilla connection satisfies Conn
+var _ Conn = (*websocket.Conn)(nil)
+
+// lockedConn serializes writes to a connection
+// gorilla/websocket allows one concurrent reader and one writer; broadcasts,
+// direct sends, and message handlers all write from different goroutines.
+type lockedConn struct {
+	Conn
+	writeMux sync.Mutex
+}
+
+// WriteMessage writes a message while holding the write lock
+func (c *lockedConn) WriteMessage(messageType int, data []byte) error {
+	c.writeMux.Lock()
+	defer c.writeMux.Unlock()
+	return c.Conn.WriteMessage(messageType, data)
+}
+
+// WriteControl writes a control frame while holding the write lock
+func (c *lockedConn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	c.writeMux.Lock()
+	defer c.writeMux.Unlock()
+	return c.Conn.WriteControl(messageType, data, deadline)
+}
+
+// SetWriteDeadline sets the write deadline while holding the write lock
+func (c *lockedConn) SetWriteDeadline(t time.Time) error {
+	c.writeMux.Lock()
+	defer c.writeMux.Unlock()
+	return c.Conn.SetWriteDeadline(t)
+}
+
+// SetReadLimit passes the read limit through when the connection has one
+func (c *lockedConn) SetReadLimit(limit int64) {
+	if limiter, ok := c.Conn.(interface{ SetReadLimit(int64) }); ok {
+		limiter.SetReadLimit(limit)
+	}
 }
 
 // Client represents a WebSocket client connection
 type Client struct {
-	Conn *websocket.Conn
+	Conn Conn
 	ID   string
+
+	// Arbitrary client attributes (e.g. "role") used to target groups
+	metadata    map[string]string
+	metadataMux sync.RWMutex
+
+	// Count of unparseable messages received from this client
+	malformed int64
+
+	// Components the client receives state updates for (empty means all),
+	// and the protocol features it announced with them
+	subscriptions    map[string]bool
+	capabilities     map[string]bool
+	subscriptionsMux sync.RWMutex
+
+	// Why the reader stopped, reported in the disconnect event
+	closeReason string
+}
+
+// send writes a text message to the client
+// Client.Conn serializes writes, so send is safe from any goroutine.
+func (c *Client) send(data []byte) error {
+	return c.Conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// SetMetadata sets a client attribute
+func (c *Client) SetMetadata(key, value string) {
+	c.metadataMux.Lock()
+	defer c.metadataMux.Unlock()
+
+	if c.metadata == nil {
+		c.metadata = make(map[string]string)
+	}
+	c.metadata[key] = value
+}
+
+// Metadata returns a client attribute, or an empty string if unset
+func (c *Client) Metadata(key string) string {
+	c.metadataMux.RLock()
+	defer c.metadataMux.RUnlock()
+
+	return c.metadata[key]
 }
 
 // Manager manages WebSocket connections
@@ -68,167 +245,502 @@ type Manager struct {
 	register   chan *Client
 	unregister chan *Client
 
+	// What enqueue does when the broadcast queue is full
+	overflowPolicy    OverflowPolicy
+	droppedBroadcasts int64
+
+	// Component instances whose updates only reach joined clients, and
+	// who may join them
+	rooms          map[string]bool
+	roomAuthorizer func(client *Client, componentID string) bool
+	roomsMux       sync.RWMutex
+
+	// Coalesces rapid state updates per component key
+	throttle broadcastThrottle
+
+	// Time from queueing a broadcast to finishing each client write
+	broadcastLatency latencyRecorder
+
+	// Connection events for admin tooling; see Events
+	events        eventStream
+	droppedEvents int64
+
 	// Message handlers registered by type
-	handlers   map[MessageType][]func(conn *websocket.Conn, payload []byte)
+	handlers   map[MessageType][]func(conn Conn, payload []byte)
 	handlerMux sync.RWMutex
 
+	// Message handlers scoped to a component type, and how payload
+	// component IDs map to types
+	componentHandlers    map[string]map[MessageType][]func(conn Conn, payload []byte)
+	resolveComponentType func(componentID string) string
+
+	// Connection admission hooks run before upgrading
+	connectHooks []func(r *http.Request) error
+
+	// Client setup hooks run after upgrading, before registration
+	clientHooks []func(c *Client, r *http.Request)
+
+	// Binary uploads awaiting their actions
+	uploads *uploadStore
+
+	// Maximum size in bytes of a single binary upload
+	MaxUploadSize int64
+
+	// Unparseable messages a client may send before it is disconnected
+	// with a protocol error (0 disables the limit)
+	MaxMalformedMessages int
+	malformedMessages    int64
+	malformedKicks       int64
+
+	// How long a client may go without answering a ping before it is
+	// dropped, and how often clients are pinged (0 disables pinging)
+	PongWait   time.Duration
+	PingPeriod time.Duration
+
 	// Lifecycle
-	isRunning bool
+	isRunning     bool
+	stop          chan struct{} // closed to stop the run loop
+	stopped       chan struct{} // closed once the run loop has exited
+	heartbeatStop chan struct{}
+	lifecycleMux  sync.Mutex
+
+	// Tracks the run loop, heartbeat, and per-client reader goroutines
+	workers sync.WaitGroup
+
+	// Destination for connection lifecycle and error logs
+	logger    logger.Logger
+	loggerMux sync.RWMutex
 }
 
 // NewManager creates a new WebSocket manager
 func NewManager() *Manager {
+	return NewManagerWithOptions(DefaultManagerOptions())
+}
+
+// NewManagerWithOptions creates a new WebSocket manager with the given
+// broadcast queue, logger, and origin policy
+// Invalid options are logged and replaced with the defaults.
+func NewManagerWithOptions(opts ManagerOptions) *Manager {
+	if err := opts.Validate(); err != nil {
+		// Keep the origin policy so a typo can't open the endpoint to every
+		// origin; malformed entries simply never match
+		defaults := DefaultManagerOptions()
+		defaults.Logger = opts.Logger
+		defaults.AllowedOrigins = opts.AllowedOrigins
+		defaults.CheckOriginFunc = opts.CheckOriginFunc
+		opts = defaults.withDefaults()
+		opts.Logger.Warnf("Invalid WebSocket manager options, using defaults: %v", err)
+	}
+	opts = opts.withDefaults()
+
 	m := &Manager{
 		clients: make(map[string]*Client),
 		Upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
-			CheckOrigin: func(r *http.Request) bool {
-				return true // Allow all origins
-			},
+			CheckOrigin:     originChecker(opts),
 		},
-		broadcast:  make(chan Message, 100), // Buffered channel to avoid blocking
+		broadcast:  make(chan Message, opts.BroadcastBuffer),
 		register:   make(chan *Client, 10),
 		unregister: make(chan *Client, 10),
-		handlers:   make(map[MessageType][]func(conn *websocket.Conn, payload []byte)),
+		handlers:   make(map[MessageType][]func(conn Conn, payload []byte)),
+		uploads:    newUploadStore(),
+
+		broadcastLatency: latencyRecorder{
+			recorder: durations.Recorder{MaxSamples: maxLatencySamples},
+		},
+
+		overflowPolicy: opts.OverflowPolicy,
+		logger:         opts.Logger,
+
+		MaxUploadSize:        DefaultMaxUploadSize,
+		MaxMalformedMessages: DefaultMaxMalformedMessages,
+		PongWait:             DefaultPongWait,
+		PingPeriod:           DefaultPingPeriod,
 	}
 
 	// Start the background goroutine
-	m.isRunning = true
-	go m.run()
+	m.Start()
 
 	return m
 }
 
 // Start begins the WebSocket manager background processes
 func (m *Manager) Start() {
+	m.lifecycleMux.Lock()
+	defer m.lifecycleMux.Unlock()
+
 	if !m.isRunning {
 		m.isRunning = true
-		go m.run()
+		m.stop = make(chan struct{})
+		m.stopped = make(chan struct{})
+		m.workers.Add(1)
+		go m.run(m.stop, m.stopped)
 	}
 }
 
 // Stop shuts down the WebSocket manager
+// Broadcasts already queued are delivered before clients are disconnected.
 func (m *Manager) Stop() {
+	m.lifecycleMux.Lock()
+	defer m.lifecycleMux.Unlock()
+
+	if !m.isRunning {
+		return
+	}
 	m.isRunning = false
 
-	// Close all connections
+	// Let the run loop flush queued broadcasts and exit
+	close(m.stop)
+	<-m.stopped
+
+	// Send a close frame to each client before dropping the connection so
+	// browsers see a clean close instead of an abnormal closure
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server shutting down")
+	deadline := time.Now().Add(closeWriteWait)
+
 	m.clientsMux.Lock()
 	for _, client := range m.clients {
+		if err := client.Conn.WriteControl(websocket.CloseMessage, closeMsg, deadline); err != nil {
+			m.Logger().Warnf("Error sending close frame to client %s: %v", client.ID, err)
+		}
 		client.Conn.Close()
+		m.dropUploads(client.Conn)
+		m.emitEvent(ConnectionEventDisconnect, client.ID, "server shutting down")
 	}
 	m.clients = make(map[string]*Client)
 	m.clientsMux.Unlock()
 }
 
+// Wait blocks until the manager's goroutines (run loop, heartbeat, and
+// client readers) have exited after Stop, or until ctx is done
+func (m *Manager) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		m.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stoppedChan returns a channel that is closed once the run loop has exited
+func (m *Manager) stoppedChan() chan struct{} {
+	m.lifecycleMux.Lock()
+	defer m.lifecycleMux.Unlock()
+	return m.stopped
+}
+
+// enqueue queues a message for broadcast
+// When the queue is full, the overflow policy decides whether to wait or
+// drop a message.
+func (m *Manager) enqueue(message Message) error {
+	stopped := m.stoppedChan()
+	message.queuedAt = time.Now()
+
+	if m.overflowPolicy != OverflowBlock {
+		return m.enqueueOrDrop(message, stopped)
+	}
+
+	select {
+	case m.broadcast <- message:
+		return nil
+	case <-stopped:
+		return ErrManagerStopped
+	}
+}
+
 // run processes WebSocket events in a separate goroutine
-func (m *Manager) run() {
-	for m.isRunning {
+func (m *Manager) run(stop, stopped chan struct{}) {
+	defer m.workers.Done()
+	defer close(stopped)
+
+	for {
 		select {
+		case <-stop:
+			m.drainBroadcasts()
+			return
+
 		case client := <-m.register:
 			m.clientsMux.Lock()
 			m.clients[client.ID] = client
 			m.clientsMux.Unlock()
-			log.Printf("WebSocket client registered: %s", client.ID)
+			m.Logger().Infof("WebSocket client registered: %s", client.ID)
+			m.emitEvent(ConnectionEventConnect, client.ID, "")
 
 		case client := <-m.unregister:
 			m.clientsMux.Lock()
 			if _, ok := m.clients[client.ID]; ok {
 				delete(m.clients, client.ID)
 				client.Conn.Close()
-				log.Printf("WebSocket client unregistered: %s", client.ID)
+				m.dropUploads(client.Conn)
+				m.Logger().Infof("WebSocket client unregistered: %s", client.ID)
+				m.emitEvent(ConnectionEventDisconnect, client.ID, client.closeReason)
 			}
 			m.clientsMux.Unlock()
 
 		case message := <-m.broadcast:
-			data, err := json.Marshal(message)
-			if err != nil {
-				log.Printf("Error marshaling message: %v", err)
-				continue
-			}
+			m.deliver(message)
+		}
+	}
+}
 
-			m.clientsMux.RLock()
-			for _, client := range m.clients {
-				err := client.Conn.WriteMessage(websocket.TextMessage, data)
-				if err != nil {
-					log.Printf("Error sending message to client %s: %v", client.ID, err)
-					// Don't remove client here, just log the error
-					// Client will be unregistered in handleMessages if connection is broken
-				}
-			}
-			m.clientsMux.RUnlock()
+// drainBroadcasts delivers any messages still queued when the manager stops
+func (m *Manager) drainBroadcasts() {
+	for {
+		select {
+		case message := <-m.broadcast:
+			m.deliver(message)
+		default:
+			return
+		}
+	}
+}
+
+// deliver writes a broadcast message to every connected client
+func (m *Manager) deliver(message Message) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		m.Logger().Errorf("Error marshaling message: %v", err)
+		return
+	}
+
+	// Component updates only go to clients following that component, or
+	// to the members of its room
+	componentID := componentScope(message)
+	room := componentID != "" && m.IsRoom(componentID)
+
+	m.clientsMux.RLock()
+	for _, client := range m.clients {
+		if !receives(client, componentID, room) {
+			continue
+		}
+
+		err := client.send(data)
+		if err != nil {
+			m.Logger().Warnf("Error sending message to client %s: %v", client.ID, err)
+			// Don't remove client here, just log the error
+			// Client will be unregistered in handleMessages if connection is broken
+			continue
 		}
+
+		if !message.queuedAt.IsZero() {
+			m.broadcastLatency.record(time.Since(message.queuedAt))
+		}
+	}
+	m.clientsMux.RUnlock()
+}
+
+// OnConnect registers a hook that runs before a connection is upgraded
+// Returning an error aborts the upgrade; see ConnectionRejection.
+func (m *Manager) OnConnect(hook func(r *http.Request) error) {
+	m.handlerMux.Lock()
+	defer m.handlerMux.Unlock()
+
+	m.connectHooks = append(m.connectHooks, hook)
+}
+
+// OnClient registers a hook that runs for each upgraded connection before
+// the client is registered, e.g. to tag it with metadata from the request
+func (m *Manager) OnClient(hook func(c *Client, r *http.Request)) {
+	m.handlerMux.Lock()
+	defer m.handlerMux.Unlock()
+
+	m.clientHooks = append(m.clientHooks, hook)
+}
+
+// admitConnection runs the OnConnect hooks and writes the rejection response
+// when one of them fails. It reports whether the connection may proceed.
+func (m *Manager) admitConnection(w http.ResponseWriter, r *http.Request) bool {
+	// Check the origin up front so SSE streams get the same policy
+	if check := m.Upgrader.CheckOrigin; check != nil && !check(r) {
+		m.Logger().Infof("WebSocket connection from %s rejected: origin %q not allowed", r.RemoteAddr, r.Header.Get("Origin"))
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return false
+	}
+
+	m.handlerMux.RLock()
+	hooks := m.connectHooks
+	m.handlerMux.RUnlock()
+
+	for _, hook := range hooks {
+		err := hook(r)
+		if err == nil {
+			continue
+		}
+
+		status := http.StatusForbidden
+		if rejection, ok := err.(*ConnectionRejection); ok && rejection.Status != 0 {
+			status = rejection.Status
+		}
+
+		m.Logger().Infof("WebSocket connection from %s rejected: %v", r.RemoteAddr, err)
+		http.Error(w, err.Error(), status)
+		return false
 	}
+
+	return true
 }
 
 // HandleConnection handles a new WebSocket connection
 func (m *Manager) HandleConnection(w http.ResponseWriter, r *http.Request) {
+	// Run admission hooks before upgrading
+	if !m.admitConnection(w, r) {
+		return
+	}
+
 	// Upgrade the HTTP connection to a WebSocket connection
 	conn, err := m.Upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("Error upgrading connection: %v", err)
+		m.Logger().Errorf("Error upgrading connection: %v", err)
 		return
 	}
 
+	m.accept(conn, r)
+}
+
+// Accept registers an already established connection and starts reading
+// messages from it
+func (m *Manager) Accept(conn Conn) *Client {
+	return m.accept(conn, nil)
+}
+
+// accept creates, sets up, and registers a client for a connection
+// OnClient hooks only run when the upgrade request is known.
+func (m *Manager) accept(conn Conn, r *http.Request) *Client {
 	// Generate a unique client ID
 	clientID := fmt.Sprintf("client-%d", time.Now().UnixNano())
 
-	// Create a new client
+	// Create a new client; handlers see the same write-locked connection
+	raw := conn
+	conn = &lockedConn{Conn: conn}
 	client := &Client{
 		Conn: conn,
 		ID:   clientID,
 	}
 
-	// Register the client
-	m.register <- client
+	// Bound frame size by the upload limit when the connection supports it
+	if limiter, ok := conn.(interface{ SetReadLimit(int64) }); ok {
+		limiter.SetReadLimit(m.MaxUploadSize + maxUploadIDLength + 1)
+	}
+
+	// Run client setup hooks
+	if r != nil {
+		m.handlerMux.RLock()
+		hooks := m.clientHooks
+		m.handlerMux.RUnlock()
+
+		for _, hook := range hooks {
+			hook(client, r)
+		}
+	}
+
+	// Register the client, refusing it if the manager has stopped
+	select {
+	case m.register <- client:
+	case <-m.stoppedChan():
+		conn.Close()
+		return client
+	}
 
 	// Start handling messages from this client
-	go m.handleMessages(client)
+	stopPings, extendDeadline := m.keepAlive(raw, client)
+	m.workers.Add(1)
+	go m.handleMessages(client, stopPings, extendDeadline)
+
+	return client
 }
 
 // handleMessages processes messages from a client
-func (m *Manager) handleMessages(client *Client) {
+// The read deadline set by keepAlive is extended by every message.
+func (m *Manager) handleMessages(client *Client, stopPings, extendDeadline func()) {
+	defer m.workers.Done()
+	defer stopPings()
 	defer func() {
-		m.unregister <- client
+		// Stop has already dropped every client once the run loop exits
+		select {
+		case m.unregister <- client:
+		case <-m.stoppedChan():
+		}
 	}()
 
 	for {
 		messageType, p, err := client.Conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket error: %v", err)
+				m.Logger().Warnf("WebSocket error: %v", err)
+				m.emitEvent(ConnectionEventError, client.ID, err.Error())
+			}
+			if client.closeReason == "" {
+				client.closeReason = err.Error()
+				if isTimeout(err) {
+					client.closeReason = "missed pong deadline"
+				}
 			}
 			break
 		}
+		extendDeadline()
 
 		if messageType == websocket.TextMessage {
 			var message Message
 			if err := json.Unmarshal(p, &message); err != nil {
-				log.Printf("Error unmarshaling message: %v", err)
+				m.Logger().Warnf("Error unmarshaling message from client %s: %v", client.ID, err)
+				if m.recordMalformed(client) {
+					client.closeReason = "too many malformed messages"
+					m.closeProtocolError(client, client.closeReason)
+					break
+				}
 				continue
 			}
 
-			// Process the message based on its type
-			m.handlerMux.RLock()
-			handlers, exists := m.handlers[message.Type]
-			m.handlerMux.RUnlock()
+			m.dispatch(client, message)
+		} else if messageType == websocket.BinaryMessage {
+			m.handleBinary(client, p)
+		}
+	}
+}
 
-			if exists {
-				for _, handler := range handlers {
-					handler(client.Conn, message.Payload)
-				}
-			}
+// dispatch runs the handlers registered for a message's type
+func (m *Manager) dispatch(client *Client, message Message) {
+	// Navigation is server-to-client only; never act on it from a client
+	if message.Type == MessageTypeNavigate {
+		m.Logger().Warnf("Ignoring navigate message from client %s", client.ID)
+		return
+	}
+
+	// Subscriptions are per-client state kept by the manager
+	if message.Type == MessageTypeSubscribe {
+		m.handleSubscribe(client, message.Payload)
+		return
+	}
+
+	m.handlerMux.RLock()
+	handlers, exists := m.handlers[message.Type]
+	m.handlerMux.RUnlock()
+
+	if exists {
+		for _, handler := range handlers {
+			handler(client.Conn, message.Payload)
 		}
 	}
+
+	for _, handler := range m.componentHandlersFor(message) {
+		handler(client.Conn, message.Payload)
+	}
 }
 
 // RegisterHandler registers a handler for a specific message type
-func (m *Manager) RegisterHandler(msgType MessageType, handler func(conn *websocket.Conn, payload []byte)) {
+func (m *Manager) RegisterHandler(msgType MessageType, handler func(conn Conn, payload []byte)) {
 	m.handlerMux.Lock()
 	defer m.handlerMux.Unlock()
 
 	if _, exists := m.handlers[msgType]; !exists {
-		m.handlers[msgType] = []func(conn *websocket.Conn, payload []byte){handler}
+		m.handlers[msgType] = []func(conn Conn, payload []byte){handler}
 	} else {
 		m.handlers[msgType] = append(m.handlers[msgType], handler)
 	}
@@ -254,12 +766,87 @@ func (m *Manager) BroadcastStateUpdate(update StateUpdate) error {
 		return fmt.Errorf("error marshaling state update: %w", err)
 	}
 
-	m.broadcast <- Message{
+	message := Message{
 		Type:    MessageTypeStateUpdate,
 		Payload: payload,
 	}
 
-	return nil
+	// Hold back rapid updates to the same key; see SetBroadcastThrottle
+	return m.enqueueStateUpdate(throttleKey(update.ComponentID, update.Key), message)
+}
+
+// StateRefreshRequest asks for the current state of every component
+// Hashes holds the state hash the client last saw per component ID;
+// components whose state still matches are not replayed.
+type StateRefreshRequest struct {
+	Hashes map[string]string `json:"hashes,omitempty"`
+}
+
+// StateSnapshot carries the full state of one component in a refresh,
+// sent to clients announcing CapabilityStateSnapshot
+// JSON shape: {"component_id": "counter-1", "state": {"count": 3}}
+type StateSnapshot struct {
+	ComponentID string                 `json:"component_id"`
+	State       map[string]interface{} `json:"state"`
+}
+
+// StateRefreshAck ends a state refresh with the current state hashes and
+// the components that were skipped because the client was up to date
+type StateRefreshAck struct {
+	Hashes    map[string]string `json:"hashes"`
+	Unchanged []string          `json:"unchanged"`
+}
+
+// ComponentRemoved tells clients a component no longer exists
+type ComponentRemoved struct {
+	ComponentID string `json:"component_id"`
+}
+
+// ShutdownNotice warns clients that the server is shutting down
+// GraceMS is how long, in milliseconds, before connections are closed.
+type ShutdownNotice struct {
+	GraceMS int64 `json:"grace_ms"`
+}
+
+// RenderUpdate carries freshly rendered component HTML
+type RenderUpdate struct {
+	ComponentID string `json:"component_id"`
+	HTML        string `json:"html"`
+}
+
+// BroadcastRender sends a component's rendered HTML to all connected
+// clients, which swap it into the DOM in place of the current element
+func (m *Manager) BroadcastRender(update RenderUpdate) error {
+	payload, err := json.Marshal(update)
+	if err != nil {
+		return fmt.Errorf("error marshaling render update: %w", err)
+	}
+
+	return m.enqueue(Message{
+		Type:    MessageTypeRender,
+		Payload: payload,
+	})
+}
+
+// StateBatch carries several changed or deleted keys of one component
+type StateBatch struct {
+	ComponentID string                 `json:"component_id"`
+	Changes     map[string]interface{} `json:"changes,omitempty"`
+	Deleted     []string               `json:"deleted,omitempty"`
+}
+
+// BroadcastStateBatch sends several state changes of a component to all
+// connected clients in one message
+func (m *Manager) BroadcastStateBatch(batch StateBatch) error {
+	payload, err := json.Marshal(batch)
+	if err != nil {
+		return fmt.Errorf("error marshaling state batch: %w", err)
+	}
+
+	return m.enqueueStateBatch(batch, Message{
+		Type:    MessageTypeStateBatch,
+		Payload: payload,
+	})
 }
 
 // BroadcastCustomMessage sends a custom message to all connected clients
@@ -269,32 +856,74 @@ func (m *Manager) BroadcastCustomMessage(msgType MessageType, payload interface{
 		return fmt.Errorf("error marshaling custom message: %w", err)
 	}
 
-	m.broadcast <- Message{
+	return m.enqueue(Message{
 		Type:    msgType,
 		Payload: data,
+	})
+}
+
+// SetLogger replaces the manager's logger; nil restores logger.Default
+func (m *Manager) SetLogger(l logger.Logger) {
+	if l == nil {
+		l = logger.Default()
 	}
 
-	return nil
+	m.loggerMux.Lock()
+	defer m.loggerMux.Unlock()
+	m.logger = l
+}
+
+// Logger returns the manager's logger
+func (m *Manager) Logger() logger.Logger {
+	m.loggerMux.RLock()
+	defer m.loggerMux.RUnlock()
+	return m.logger
 }
 
 // StartHeartbeat begins sending periodic heartbeat messages
+// Calling it again replaces the running heartbeat.
 func (m *Manager) StartHeartbeat(interval time.Duration) {
+	m.StopHeartbeat()
+
+	m.lifecycleMux.Lock()
+	stop := make(chan struct{})
+	m.heartbeatStop = stop
+	stopped := m.stopped
+	m.lifecycleMux.Unlock()
+
+	m.workers.Add(1)
 	go func() {
+		defer m.workers.Done()
+
 		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 
-		for range ticker.C {
-			if !m.isRunning {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-stopped:
 				return
+			case <-ticker.C:
+				m.BroadcastCustomMessage(MessageTypeHeartbeat, map[string]interface{}{
+					"timestamp": time.Now().Unix(),
+				})
 			}
-
-			m.BroadcastCustomMessage(MessageTypeHeartbeat, map[string]interface{}{
-				"timestamp": time.Now().Unix(),
-			})
 		}
 	}()
 }
 
+// StopHeartbeat stops the heartbeat started by StartHeartbeat
+func (m *Manager) StopHeartbeat() {
+	m.lifecycleMux.Lock()
+	defer m.lifecycleMux.Unlock()
+
+	if m.heartbeatStop != nil {
+		close(m.heartbeatStop)
+		m.heartbeatStop = nil
+	}
+}
+
 // BroadcastToAll sends a message to all connected clients (legacy method, use broadcast channel instead)
 func (m *Manager) BroadcastToAll(message interface{}) error {
 	// Convert message to proper client format if it's a state update
@@ -315,9 +944,30 @@ func (m *Manager) BroadcastToAll(message interface{}) error {
 	}
 
 	// Use broadcast channel for consistency
-	m.broadcast <- Message{
+	return m.enqueue(Message{
 		Type:    MessageTypeEvent,
 		Payload: jsonMessage,
+	})
+}
+
+// BroadcastToGroup sends a message to every client matched by selector
+func (m *Manager) BroadcastToGroup(selector func(*Client) bool, msg Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("error marshaling group message: %w", err)
+	}
+
+	m.clientsMux.RLock()
+	defer m.clientsMux.RUnlock()
+
+	for _, client := range m.clients {
+		if !selector(client) {
+			continue
+		}
+
+		if err := client.send(data); err != nil {
+			m.Logger().Warnf("Error sending group message to client %s: %v", client.ID, err)
+		}
 	}
 
 	return nil
@@ -342,5 +992,5 @@ func (m *Manager) SendToClient(clientID string, message interface{}) error {
 	}
 
 	// Send message to client
-	return client.Conn.WriteMessage(websocket.TextMessage, jsonMessage)
+	return client.send(jsonMessage)
 }
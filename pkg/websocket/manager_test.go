@@ -0,0 +1,68 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestFakeConnDrivesHandlers(t *testing.T) {
+	m := newTestManager(t)
+
+	// Echo events back on the connection they arrived on
+	received := make(chan string, 1)
+	m.RegisterHandler(MessageTypeEvent, func(conn Conn, payload []byte) {
+		received <- string(payload)
+		reply, _ := json.Marshal(Message{Type: MessageTypeEvent, Payload: payload})
+		conn.WriteMessage(websocket.TextMessage, reply)
+	})
+
+	conn, _ := connect(t, m)
+	conn.incoming <- []byte(`{"type":"event","payload":{"name":"click"}}`)
+
+	select {
+	case payload := <-received:
+		if payload != `{"name":"click"}` {
+			t.Errorf("handler got payload %s", payload)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the handler")
+	}
+
+	waitFor(t, "the handler's reply", func() bool {
+		return len(conn.messages(MessageTypeEvent)) == 1
+	})
+}
+
+func TestFakeConnCloseUnregistersClient(t *testing.T) {
+	m := newTestManager(t)
+
+	conn, _ := connect(t, m)
+	conn.Close()
+
+	waitFor(t, "the client to be unregistered", func() bool {
+		return m.Stats().Clients == 0
+	})
+}
+
+func TestFakeConnSkipsMalformedMessages(t *testing.T) {
+	m := newTestManager(t)
+
+	received := make(chan struct{}, 1)
+	m.RegisterHandler(MessageTypeEvent, func(conn Conn, payload []byte) {
+		received <- struct{}{}
+	})
+
+	conn, _ := connect(t, m)
+	conn.incoming <- []byte(`not json`)
+	conn.incoming <- []byte(`{"type":"event","payload":{}}`)
+
+	waitFor(t, "the valid message to be handled", func() bool {
+		return len(received) == 1
+	})
+	if m.Stats().Clients != 1 {
+		t.Error("a single malformed message disconnected the client")
+	}
+}
@@ -0,0 +1,80 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// NavigatePayload tells a client to navigate to another page
+type NavigatePayload struct {
+	URL string `json:"url"`
+
+	// Replace the current history entry instead of pushing a new one
+	Replace bool `json:"replace"`
+}
+
+// navigateMessage validates a target URL and builds the navigate message
+// Only same-site paths ("/login") and absolute http(s) URLs are allowed.
+func navigateMessage(target string, replace bool) (Message, error) {
+	if !isNavigableURL(target) {
+		return Message{}, fmt.Errorf("invalid navigation target %q", target)
+	}
+
+	payload, err := json.Marshal(NavigatePayload{URL: target, Replace: replace})
+	if err != nil {
+		return Message{}, fmt.Errorf("error marshaling navigate payload: %w", err)
+	}
+
+	return Message{Type: MessageTypeNavigate, Payload: payload}, nil
+}
+
+// isNavigableURL reports whether target is a path or an http(s) URL
+func isNavigableURL(target string) bool {
+	if strings.HasPrefix(target, "/") {
+		// Reject protocol-relative URLs like //evil.example
+		return !strings.HasPrefix(target, "//") && !strings.HasPrefix(target, "/\\")
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Navigate tells one client to load another page
+func (m *Manager) Navigate(clientID string, target string, replace bool) error {
+	msg, err := navigateMessage(target, replace)
+	if err != nil {
+		return err
+	}
+	return m.SendToClient(clientID, msg)
+}
+
+// NavigateConn tells the client on conn to load another page, e.g. from a
+// message handler after a login action
+func (m *Manager) NavigateConn(conn Conn, target string, replace bool) error {
+	msg, err := navigateMessage(target, replace)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("error marshaling navigate message: %w", err)
+	}
+	return conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// NavigateGroup tells every client matched by selector to load another page
+func (m *Manager) NavigateGroup(selector func(*Client) bool, target string, replace bool) error {
+	msg, err := navigateMessage(target, replace)
+	if err != nil {
+		return err
+	}
+	return m.BroadcastToGroup(selector, msg)
+}
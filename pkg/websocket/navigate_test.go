@@ -0,0 +1,72 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNavigateTargetsOneClient(t *testing.T) {
+	m := newTestManager(t)
+	conn, client := connect(t, m)
+	other, _ := connect(t, m)
+
+	if err := m.Navigate(client.ID, "/login?next=%2Fcart", true); err != nil {
+		t.Fatalf("Navigate: %v", err)
+	}
+
+	waitFor(t, "the navigate message", func() bool {
+		return len(conn.messages(MessageTypeNavigate)) == 1
+	})
+	var payload NavigatePayload
+	if err := json.Unmarshal(conn.messages(MessageTypeNavigate)[0].Payload, &payload); err != nil {
+		t.Fatalf("invalid navigate payload: %v", err)
+	}
+	if payload.URL != "/login?next=%2Fcart" || !payload.Replace {
+		t.Errorf("got %+v, want /login?next=%%2Fcart with replace", payload)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if got := other.messages(MessageTypeNavigate); len(got) != 0 {
+		t.Errorf("another client received %d navigate messages", len(got))
+	}
+}
+
+func TestNavigateRejectsUnsafeTargets(t *testing.T) {
+	m := newTestManager(t)
+	_, client := connect(t, m)
+
+	for _, target := range []string{"javascript:alert(1)", "//evil.example", `/\evil.example`, "data:text/html,hi", "relative/path", ""} {
+		if err := m.Navigate(client.ID, target, false); err == nil {
+			t.Errorf("Navigate(%q) succeeded, want an error", target)
+		}
+	}
+	for _, target := range []string{"/", "/dashboard", "https://example.com/docs"} {
+		if err := m.Navigate(client.ID, target, false); err != nil {
+			t.Errorf("Navigate(%q): %v", target, err)
+		}
+	}
+}
+
+func TestClientsCannotSendNavigate(t *testing.T) {
+	m := newTestManager(t)
+
+	handled := make(chan struct{}, 1)
+	m.RegisterHandler(MessageTypeNavigate, func(conn Conn, payload []byte) {
+		handled <- struct{}{}
+	})
+	m.RegisterHandler(MessageTypeEvent, func(conn Conn, payload []byte) {
+		handled <- struct{}{}
+	})
+
+	conn, _ := connect(t, m)
+	conn.incoming <- []byte(`{"type":"navigate","payload":{"url":"/admin"}}`)
+	// Messages are handled in order, so the event arrives after the navigate
+	conn.incoming <- []byte(`{"type":"event","payload":{}}`)
+
+	waitFor(t, "the event", func() bool { return len(handled) > 0 })
+	time.Sleep(20 * time.Millisecond)
+	if n := len(handled); n != 1 {
+		t.Errorf("%d handlers ran, want only the event handler", n)
+	}
+}
@@ -0,0 +1,126 @@
+package websocket
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+
+	"github.com/magooney-loon/webrender/pkg/logger"
+)
+
+// DefaultBroadcastBuffer is the default number of queued broadcasts
+const DefaultBroadcastBuffer = 100
+
+// OverflowPolicy decides what happens when the broadcast queue is full
+type OverflowPolicy string
+
+const (
+	// OverflowBlock makes the broadcaster wait for room in the queue
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest discards the oldest queued broadcast to make room
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowDropNewest discards the broadcast being queued
+	OverflowDropNewest OverflowPolicy = "drop-newest"
+)
+
+// ManagerOptions configures a Manager
+type ManagerOptions struct {
+	// Number of broadcasts queued before OverflowPolicy applies
+	// (0 uses DefaultBroadcastBuffer)
+	BroadcastBuffer int
+
+	// What to do when the broadcast queue is full (empty means block)
+	OverflowPolicy OverflowPolicy
+
+	// Where the manager logs (nil uses logger.Default)
+	Logger logger.Logger
+
+	// Origins (e.g. "https://example.com") allowed to connect; requests
+	// from other origins get a 403. Empty allows every origin.
+	AllowedOrigins []string
+
+	// Custom origin check, used instead of AllowedOrigins when set
+	CheckOriginFunc func(r *http.Request) bool
+}
+
+// DefaultManagerOptions returns the options used by NewManager
+func DefaultManagerOptions() ManagerOptions {
+	return ManagerOptions{
+		BroadcastBuffer: DefaultBroadcastBuffer,
+		OverflowPolicy:  OverflowBlock,
+	}
+}
+
+// Validate reports invalid option values
+func (o ManagerOptions) Validate() error {
+	if o.BroadcastBuffer < 0 {
+		return fmt.Errorf("broadcast buffer must not be negative, got %d", o.BroadcastBuffer)
+	}
+
+	for _, origin := range o.AllowedOrigins {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("allowed origin %q must be an http(s) origin like https://example.com", origin)
+		}
+	}
+
+	switch o.OverflowPolicy {
+	case "", OverflowBlock, OverflowDropOldest, OverflowDropNewest:
+		return nil
+	}
+	return fmt.Errorf("unknown overflow policy %q", o.OverflowPolicy)
+}
+
+// withDefaults fills in zero values
+func (o ManagerOptions) withDefaults() ManagerOptions {
+	if o.BroadcastBuffer == 0 {
+		o.BroadcastBuffer = DefaultBroadcastBuffer
+	}
+	if o.OverflowPolicy == "" {
+		o.OverflowPolicy = OverflowBlock
+	}
+	if o.Logger == nil {
+		o.Logger = logger.Default()
+	}
+	return o
+}
+
+// enqueueOrDrop queues a message without blocking, applying the overflow
+// policy when the queue is full
+func (m *Manager) enqueueOrDrop(message Message, stopped chan struct{}) error {
+	for {
+		// Nothing is delivered after the run loop exits
+		select {
+		case <-stopped:
+			return ErrManagerStopped
+		default:
+		}
+
+		select {
+		case m.broadcast <- message:
+			return nil
+		default:
+		}
+
+		if m.overflowPolicy == OverflowDropNewest {
+			m.recordDrop()
+			return nil
+		}
+
+		// Drop the oldest queued message and try again; the run loop may
+		// have freed a slot in the meantime, in which case nothing is dropped
+		select {
+		case <-m.broadcast:
+			m.recordDrop()
+		default:
+		}
+	}
+}
+
+// recordDrop counts a broadcast discarded by the overflow policy
+func (m *Manager) recordDrop() {
+	if n := atomic.AddInt64(&m.droppedBroadcasts, 1); n == 1 || n%1000 == 0 {
+		m.Logger().Warnf("Broadcast queue full (%s): %d messages dropped", m.overflowPolicy, n)
+	}
+}
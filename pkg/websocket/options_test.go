@@ -0,0 +1,168 @@
+package websocket
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/magooney-loon/webrender/pkg/logger"
+)
+
+// gatedConn is a fakeConn whose writes wait until the gate opens, stalling
+// the run loop so the broadcast queue fills up
+type gatedConn struct {
+	*fakeConn
+	writing  chan struct{}
+	gate     chan struct{}
+	openOnce sync.Once
+}
+
+func newGatedConn() *gatedConn {
+	return &gatedConn{
+		fakeConn: newFakeConn(),
+		writing:  make(chan struct{}, 1),
+		gate:     make(chan struct{}),
+	}
+}
+
+func (c *gatedConn) WriteMessage(messageType int, data []byte) error {
+	select {
+	case c.writing <- struct{}{}:
+	default:
+	}
+	<-c.gate
+	return c.fakeConn.WriteMessage(messageType, data)
+}
+
+// open lets writes through
+func (c *gatedConn) open() {
+	c.openOnce.Do(func() { close(c.gate) })
+}
+
+// numbers decodes the "n" field of the event messages delivered so far
+func (c *gatedConn) numbers(t *testing.T) []int {
+	t.Helper()
+
+	var numbers []int
+	for _, message := range c.messages(MessageTypeEvent) {
+		var payload struct{ N int }
+		if err := json.Unmarshal(message.Payload, &payload); err != nil {
+			t.Fatal(err)
+		}
+		numbers = append(numbers, payload.N)
+	}
+	return numbers
+}
+
+// saturate returns a manager with a two-message queue that is full: event
+// 0 is stuck being written to the client while events 1 and 2 are queued
+func saturate(t *testing.T, policy OverflowPolicy) (*Manager, *gatedConn) {
+	t.Helper()
+
+	opts := DefaultManagerOptions()
+	opts.Logger = logger.Discard()
+	opts.BroadcastBuffer = 2
+	opts.OverflowPolicy = policy
+	m := NewManagerWithOptions(opts)
+	t.Cleanup(m.Stop)
+
+	conn := newGatedConn()
+	t.Cleanup(conn.open)
+	before := m.Stats().Clients
+	m.Accept(conn)
+	waitFor(t, "client registration", func() bool {
+		return m.Stats().Clients > before
+	})
+
+	broadcast(t, m, 0)
+	select {
+	case <-conn.writing:
+	case <-time.After(time.Second):
+		t.Fatal("run loop never wrote the first broadcast")
+	}
+	broadcast(t, m, 1)
+	broadcast(t, m, 2)
+	return m, conn
+}
+
+// broadcast queues event n for every client
+func broadcast(t *testing.T, m *Manager, n int) {
+	t.Helper()
+	if err := m.BroadcastCustomMessage(MessageTypeEvent, map[string]int{"n": n}); err != nil {
+		t.Fatalf("broadcast %d: %v", n, err)
+	}
+}
+
+// returnsPromptly reports whether fn returns within 50ms
+func returnsPromptly(fn func()) (chan struct{}, bool) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+		return done, true
+	case <-time.After(50 * time.Millisecond):
+		return done, false
+	}
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		policy      OverflowPolicy
+		blocks      bool
+		wantDropped int64
+		want        []int
+	}{
+		{OverflowBlock, true, 0, []int{0, 1, 2, 3}},
+		{OverflowDropNewest, false, 1, []int{0, 1, 2}},
+		{OverflowDropOldest, false, 1, []int{0, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			m, conn := saturate(t, tt.policy)
+
+			var err error
+			done, prompt := returnsPromptly(func() {
+				err = m.BroadcastCustomMessage(MessageTypeEvent, map[string]int{"n": 3})
+			})
+			if prompt == tt.blocks {
+				t.Errorf("broadcast into a full queue returned promptly = %v, want %v", prompt, !tt.blocks)
+			}
+
+			conn.open()
+			<-done
+			if err != nil {
+				t.Fatalf("broadcast 3: %v", err)
+			}
+			waitFor(t, "delivery", func() bool {
+				return len(conn.messages(MessageTypeEvent)) >= len(tt.want)
+			})
+
+			if got := conn.numbers(t); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("delivered %v, want %v", got, tt.want)
+			}
+			if got := m.Stats().DroppedBroadcasts; got != tt.wantDropped {
+				t.Errorf("dropped broadcasts = %d, want %d", got, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestManagerOptionsValidate(t *testing.T) {
+	opts := DefaultManagerOptions()
+	opts.OverflowPolicy = "drop-everything"
+	if err := opts.Validate(); err == nil {
+		t.Error("unknown overflow policy accepted")
+	}
+
+	opts = DefaultManagerOptions()
+	opts.BroadcastBuffer = -1
+	if err := opts.Validate(); err == nil {
+		t.Error("negative broadcast buffer accepted")
+	}
+}
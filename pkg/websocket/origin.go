@@ -0,0 +1,56 @@
+package websocket
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// originChecker builds the upgrader's CheckOrigin from the options
+// CheckOriginFunc wins over AllowedOrigins; with neither set every origin
+// is allowed, as before these options existed.
+func originChecker(opts ManagerOptions) func(r *http.Request) bool {
+	if opts.CheckOriginFunc != nil {
+		return opts.CheckOriginFunc
+	}
+
+	if len(opts.AllowedOrigins) == 0 {
+		return func(r *http.Request) bool {
+			return true // Allow all origins
+		}
+	}
+
+	allowed := make(map[string]bool, len(opts.AllowedOrigins))
+	for _, origin := range opts.AllowedOrigins {
+		allowed[normalizeOrigin(origin)] = true
+	}
+
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			// Non-browser clients don't send an Origin header
+			return true
+		}
+		return allowed[normalizeOrigin(origin)]
+	}
+}
+
+// normalizeOrigin lowercases an origin and drops default ports and any
+// trailing slash so equivalent origins compare equal
+func normalizeOrigin(origin string) string {
+	u, err := url.Parse(strings.TrimSpace(origin))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return strings.ToLower(strings.TrimSpace(origin))
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" {
+		host += ":" + port
+	}
+	return scheme + "://" + host
+}
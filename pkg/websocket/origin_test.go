@@ -0,0 +1,99 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/magooney-loon/webrender/pkg/logger"
+)
+
+func TestOriginChecker(t *testing.T) {
+	opts := ManagerOptions{AllowedOrigins: []string{"https://example.com", "http://localhost:8080"}}
+	check := originChecker(opts)
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://example.com", true},
+		{"HTTPS://Example.com", true},
+		{"https://example.com:443", true},
+		{"https://example.com/", true},
+		{"http://localhost:8080", true},
+		{"", true},
+		{"http://example.com", false},
+		{"https://evil.example.com", false},
+		{"https://example.com.evil.com", false},
+		{"http://localhost:9090", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if got := check(r); got != tt.want {
+			t.Errorf("origin %q allowed = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+
+	// Without a policy every origin is allowed
+	r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	r.Header.Set("Origin", "https://anywhere.test")
+	if !originChecker(ManagerOptions{})(r) {
+		t.Error("default policy rejected an origin")
+	}
+
+	// A custom check wins over the allow-list
+	opts.CheckOriginFunc = func(*http.Request) bool { return false }
+	r.Header.Set("Origin", "https://example.com")
+	if originChecker(opts)(r) {
+		t.Error("CheckOriginFunc was ignored")
+	}
+}
+
+func TestUpgradeRejectsDisallowedOrigin(t *testing.T) {
+	opts := DefaultManagerOptions()
+	opts.Logger = logger.Discard()
+	opts.AllowedOrigins = []string{"https://example.com"}
+	m := NewManagerWithOptions(opts)
+	t.Cleanup(m.Stop)
+
+	_, resp, err := dialWithHeader(t, m, http.Header{"Origin": {"https://evil.test"}})
+	if err == nil {
+		t.Fatal("dial from a disallowed origin succeeded")
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("dial from a disallowed origin: response %v, err %v; want a 403", resp, err)
+	}
+	resp.Body.Close()
+
+	if _, _, err := dialWithHeader(t, m, http.Header{"Origin": {"https://example.com"}}); err != nil {
+		t.Fatalf("dial from an allowed origin: %v", err)
+	}
+	waitFor(t, "client registration", func() bool {
+		return m.Stats().Clients == 1
+	})
+}
+
+func TestInvalidAllowedOriginsKeepThePolicy(t *testing.T) {
+	opts := DefaultManagerOptions()
+	opts.AllowedOrigins = []string{"example.com"}
+	if err := opts.Validate(); err == nil {
+		t.Error("origin without a scheme accepted")
+	}
+
+	// Invalid options fall back to the defaults but never to allow-all
+	opts.Logger = logger.Discard()
+	m := NewManagerWithOptions(opts)
+	t.Cleanup(m.Stop)
+
+	_, resp, err := dialWithHeader(t, m, http.Header{"Origin": {"https://evil.test"}})
+	if err == nil {
+		t.Fatal("dial succeeded with an invalid allow-list")
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,109 @@
+package websocket
+
+import (
+	"sort"
+)
+
+// SetRoom makes a component instance a room: its updates only reach
+// clients that joined it, rather than every client not filtering
+// subscriptions
+// Clients join by subscribing to the component, which the client script
+// does for the components on its page, and leave when they disconnect or
+// subscribe elsewhere. Restrict who may join with SetRoomAuthorizer.
+func (m *Manager) SetRoom(componentID string, enabled bool) {
+	m.roomsMux.Lock()
+	defer m.roomsMux.Unlock()
+
+	if !enabled {
+		delete(m.rooms, componentID)
+		return
+	}
+	if m.rooms == nil {
+		m.rooms = make(map[string]bool)
+	}
+	m.rooms[componentID] = true
+}
+
+// SetRoomAuthorizer sets the check a client must pass to join a room by
+// subscribing to it; rooms it is refused are dropped from the
+// subscription. Without an authorizer any client may join.
+// Server-side calls to Client.SetSubscriptions are not checked.
+func (m *Manager) SetRoomAuthorizer(authorize func(client *Client, componentID string) bool) {
+	m.roomsMux.Lock()
+	defer m.roomsMux.Unlock()
+
+	m.roomAuthorizer = authorize
+}
+
+// authorizedSubscriptions removes the rooms a client may not join from
+// the component IDs it asked to subscribe to
+func (m *Manager) authorizedSubscriptions(client *Client, componentIDs []string) []string {
+	m.roomsMux.RLock()
+	authorize := m.roomAuthorizer
+	m.roomsMux.RUnlock()
+
+	if authorize == nil {
+		return componentIDs
+	}
+
+	allowed := make([]string, 0, len(componentIDs))
+	for _, id := range componentIDs {
+		if m.IsRoom(id) && !authorize(client, id) {
+			m.Logger().Warnf("Client %s refused to join room %s", client.ID, id)
+			continue
+		}
+		allowed = append(allowed, id)
+	}
+	return allowed
+}
+
+// Receives reports whether updates about a component reach a client, by
+// the same rules as broadcasts: rooms need the client to have joined,
+// other components a matching (or empty) subscription
+func (m *Manager) Receives(client *Client, componentID string) bool {
+	return receives(client, componentID, m.IsRoom(componentID))
+}
+
+// IsRoom reports whether a component instance is a room
+func (m *Manager) IsRoom(componentID string) bool {
+	m.roomsMux.RLock()
+	defer m.roomsMux.RUnlock()
+
+	return m.rooms[componentID]
+}
+
+// RoomMembers returns the IDs of the connected clients in a component's
+// room, sorted
+func (m *Manager) RoomMembers(componentID string) []string {
+	m.clientsMux.RLock()
+	defer m.clientsMux.RUnlock()
+
+	var members []string
+	for _, client := range m.clients {
+		if client.joined(componentID) {
+			members = append(members, client.ID)
+		}
+	}
+	sort.Strings(members)
+	return members
+}
+
+// joined reports whether the client explicitly subscribed to a component
+func (c *Client) joined(componentID string) bool {
+	c.subscriptionsMux.RLock()
+	defer c.subscriptionsMux.RUnlock()
+
+	return c.subscriptions[componentID]
+}
+
+// receives reports whether a broadcast about a component goes to the client
+func receives(client *Client, componentID string, room bool) bool {
+	switch {
+	case componentID == "":
+		return true
+	case room:
+		return client.joined(componentID)
+	default:
+		return client.IsSubscribed(componentID)
+	}
+}
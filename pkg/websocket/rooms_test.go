@@ -0,0 +1,112 @@
+package websocket
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRoomUpdatesReachOnlyJoinedClients(t *testing.T) {
+	m := newTestManager(t)
+	m.SetRoom("doc-42", true)
+
+	alice, aliceClient := connect(t, m)
+	aliceClient.SetSubscriptions([]string{"doc-42"})
+	bob, bobClient := connect(t, m)
+	bobClient.SetSubscriptions([]string{"doc-42", "menu-1"})
+	// A client without subscriptions receives every ordinary update, but
+	// has not joined the room
+	outsider, _ := connect(t, m)
+
+	if err := m.BroadcastStateUpdate(StateUpdate{ComponentID: "doc-42", Key: "title", Value: "Draft", Type: "update"}); err != nil {
+		t.Fatalf("BroadcastStateUpdate: %v", err)
+	}
+
+	for name, conn := range map[string]*fakeConn{"alice": alice, "bob": bob} {
+		waitFor(t, name+"'s room update", func() bool {
+			return len(conn.messages(MessageTypeStateUpdate)) == 1
+		})
+	}
+
+	// Give a stray delivery to the outsider time to show up
+	time.Sleep(20 * time.Millisecond)
+	if got := outsider.messages(MessageTypeStateUpdate); len(got) != 0 {
+		t.Errorf("outsider received %d room updates, want none", len(got))
+	}
+
+	// Components that are not rooms still reach unfiltered clients
+	if err := m.BroadcastStateUpdate(StateUpdate{ComponentID: "menu-1", Key: "open", Value: true, Type: "update"}); err != nil {
+		t.Fatalf("BroadcastStateUpdate: %v", err)
+	}
+	waitFor(t, "the outsider's ordinary update", func() bool {
+		return len(outsider.messages(MessageTypeStateUpdate)) == 1
+	})
+}
+
+func TestRoomMembersFollowSubscriptionsAndDisconnects(t *testing.T) {
+	m := newTestManager(t)
+	m.SetRoom("doc-42", true)
+	if !m.IsRoom("doc-42") {
+		t.Fatal("doc-42 is not a room after SetRoom")
+	}
+
+	alice, aliceClient := connect(t, m)
+	aliceClient.SetSubscriptions([]string{"doc-42"})
+	_, bobClient := connect(t, m)
+	bobClient.SetSubscriptions([]string{"doc-42"})
+	connect(t, m)
+
+	want := []string{aliceClient.ID, bobClient.ID}
+	if want[0] > want[1] {
+		want[0], want[1] = want[1], want[0]
+	}
+	if got := m.RoomMembers("doc-42"); !reflect.DeepEqual(got, want) {
+		t.Errorf("RoomMembers = %v, want %v", got, want)
+	}
+
+	// Subscribing elsewhere leaves the room
+	bobClient.SetSubscriptions([]string{"menu-1"})
+	if got := m.RoomMembers("doc-42"); !reflect.DeepEqual(got, []string{aliceClient.ID}) {
+		t.Errorf("RoomMembers after bob left = %v, want only alice", got)
+	}
+
+	// So does disconnecting
+	alice.Close()
+	waitFor(t, "alice to leave the room", func() bool {
+		return len(m.RoomMembers("doc-42")) == 0
+	})
+
+	m.SetRoom("doc-42", false)
+	if m.IsRoom("doc-42") {
+		t.Error("doc-42 is still a room after disabling it")
+	}
+}
+
+func TestRoomAuthorizerRefusesJoins(t *testing.T) {
+	m := newTestManager(t)
+	m.SetRoom("doc-42", true)
+	m.SetRoomAuthorizer(func(client *Client, componentID string) bool {
+		return client.Metadata("role") == "editor"
+	})
+
+	editor, editorClient := connect(t, m)
+	editorClient.SetMetadata("role", "editor")
+	intruder, intruderClient := connect(t, m)
+
+	for _, conn := range []*fakeConn{editor, intruder} {
+		conn.incoming <- []byte(`{"type":"subscribe","payload":{"component_ids":["doc-42","menu-1"]}}`)
+	}
+	waitFor(t, "the subscriptions", func() bool {
+		return editorClient.joined("menu-1") && intruderClient.joined("menu-1")
+	})
+
+	if got := m.RoomMembers("doc-42"); !reflect.DeepEqual(got, []string{editorClient.ID}) {
+		t.Errorf("RoomMembers = %v, want only the editor", got)
+	}
+	if m.Receives(intruderClient, "doc-42") {
+		t.Error("refused client receives the room's updates")
+	}
+	if !m.Receives(intruderClient, "menu-1") {
+		t.Error("refused client lost its other subscriptions")
+	}
+}
@@ -0,0 +1,65 @@
+package websocket
+
+import "encoding/json"
+
+// componentTarget is the part of a payload naming its component
+type componentTarget struct {
+	ComponentID string `json:"component_id"`
+}
+
+// SetComponentTypeResolver sets how component IDs in message payloads map
+// to component types for RegisterComponentHandler
+// Resolving to "" runs no scoped handlers, e.g. for disabled components.
+func (m *Manager) SetComponentTypeResolver(resolve func(componentID string) string) {
+	m.handlerMux.Lock()
+	defer m.handlerMux.Unlock()
+
+	m.resolveComponentType = resolve
+}
+
+// RegisterComponentHandler registers a handler for messages of msgType
+// whose payload targets a component of componentType
+// Scoped handlers run after the handlers registered with RegisterHandler,
+// whatever those decided, and get the raw payload. The state manager's
+// resolver skips unknown and disabled components, but action checks such
+// as nonces, idempotency keys, and uploads are not applied, so don't use
+// scoped handlers to perform nonce-protected actions.
+func (m *Manager) RegisterComponentHandler(componentType string, msgType MessageType, handler func(conn Conn, payload []byte)) {
+	m.handlerMux.Lock()
+	defer m.handlerMux.Unlock()
+
+	if m.componentHandlers == nil {
+		m.componentHandlers = make(map[string]map[MessageType][]func(conn Conn, payload []byte))
+	}
+	if m.componentHandlers[componentType] == nil {
+		m.componentHandlers[componentType] = make(map[MessageType][]func(conn Conn, payload []byte))
+	}
+	m.componentHandlers[componentType][msgType] = append(m.componentHandlers[componentType][msgType], handler)
+}
+
+// componentHandlersFor returns the scoped handlers for a message, if any
+func (m *Manager) componentHandlersFor(message Message) []func(conn Conn, payload []byte) {
+	m.handlerMux.RLock()
+	resolve := m.resolveComponentType
+	hasHandlers := len(m.componentHandlers) > 0
+	m.handlerMux.RUnlock()
+
+	if resolve == nil || !hasHandlers {
+		return nil
+	}
+
+	var target componentTarget
+	if err := json.Unmarshal(message.Payload, &target); err != nil || target.ComponentID == "" {
+		return nil
+	}
+
+	componentType := resolve(target.ComponentID)
+	if componentType == "" {
+		return nil
+	}
+
+	m.handlerMux.RLock()
+	defer m.handlerMux.RUnlock()
+
+	return m.componentHandlers[componentType][message.Type]
+}
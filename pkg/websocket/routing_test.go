@@ -0,0 +1,76 @@
+package websocket
+
+import (
+	"encoding/json"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestComponentHandlersScopedToType(t *testing.T) {
+	m := newTestManager(t)
+	m.SetComponentTypeResolver(func(componentID string) string {
+		return map[string]string{"chart-1": "chart", "chart-2": "chart", "table-1": "table"}[componentID]
+	})
+
+	var mutex sync.Mutex
+	received := make(map[string][]string)
+	record := func(handler string) func(conn Conn, payload []byte) {
+		return func(conn Conn, payload []byte) {
+			var target componentTarget
+			json.Unmarshal(payload, &target)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			received[handler] = append(received[handler], target.ComponentID)
+		}
+	}
+	m.RegisterHandler("zoom", record("global"))
+	m.RegisterComponentHandler("chart", "zoom", record("chart"))
+	m.RegisterComponentHandler("table", "zoom", record("table"))
+	m.RegisterComponentHandler("chart", "pan", record("chart pan"))
+
+	conn, _ := connect(t, m)
+	// Messages from one client are handled in order
+	for _, id := range []string{"chart-1", "table-1", "unknown-1", "chart-2"} {
+		conn.incoming <- []byte(`{"type":"zoom","payload":{"component_id":"` + id + `"}}`)
+	}
+	conn.incoming <- []byte(`{"type":"zoom","payload":{}}`)
+
+	waitFor(t, "the global handler", func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(received["global"]) == 5
+	})
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	want := map[string][]string{
+		"global": {"chart-1", "table-1", "unknown-1", "chart-2", ""},
+		"chart":  {"chart-1", "chart-2"},
+		"table":  {"table-1"},
+	}
+	if !reflect.DeepEqual(received, want) {
+		t.Errorf("handlers received %v, want %v", received, want)
+	}
+}
+
+func TestComponentHandlersNeedAResolver(t *testing.T) {
+	m := newTestManager(t)
+
+	scoped := make(chan struct{}, 1)
+	global := make(chan struct{}, 2)
+	m.RegisterComponentHandler("chart", "zoom", func(Conn, []byte) { scoped <- struct{}{} })
+	m.RegisterHandler("zoom", func(Conn, []byte) { global <- struct{}{} })
+
+	conn, _ := connect(t, m)
+	// Scoped handlers run after the global ones, so once the second
+	// message is handled the first has been fully dispatched
+	conn.incoming <- []byte(`{"type":"zoom","payload":{"component_id":"chart-1"}}`)
+	conn.incoming <- []byte(`{"type":"zoom","payload":{"component_id":"chart-1"}}`)
+
+	waitFor(t, "the global handler", func() bool { return len(global) == 2 })
+	if len(scoped) != 0 {
+		t.Error("scoped handler ran without a component type resolver")
+	}
+}
@@ -0,0 +1,250 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// sseHeartbeatInterval is how often an SSE comment is sent to keep proxies
+// from closing idle streams
+const sseHeartbeatInterval = 15 * time.Second
+
+// SSETokenHeader carries the token of an SSE stream on messages posted
+// back for it
+const SSETokenHeader = "X-SSE-Token"
+
+// errSSEClosed is returned when writing to or reading from a closed stream
+var errSSEClosed = errors.New("sse: stream closed")
+
+// sseConn adapts a Server-Sent Events stream to Conn so SSE clients share
+// the WebSocket broadcast plumbing. Streams are server-to-client only;
+// client messages arrive through HandleSSEMessage.
+type sseConn struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+
+	// Secret the stream's client must send with every posted message
+	token string
+
+	closed    bool
+	done      chan struct{}
+	closeOnce sync.Once
+	mutex     sync.Mutex
+}
+
+// newSSEConn wraps a response writer that supports flushing, with a new
+// random token
+func newSSEConn(w http.ResponseWriter, flusher http.Flusher) (*sseConn, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("error generating SSE token: %w", err)
+	}
+
+	return &sseConn{
+		w:       w,
+		flusher: flusher,
+		token:   hex.EncodeToString(token),
+		done:    make(chan struct{}),
+	}, nil
+}
+
+// sseConnOf returns the SSE stream behind a client, if it has one
+func sseConnOf(client *Client) (*sseConn, bool) {
+	conn := client.Conn
+	if locked, ok := conn.(*lockedConn); ok {
+		conn = locked.Conn
+	}
+	sse, ok := conn.(*sseConn)
+	return sse, ok
+}
+
+// writeEvent writes a single event to the stream and flushes it
+func (c *sseConn) writeEvent(event string, data []byte) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return errSSEClosed
+	}
+
+	if event != "" {
+		if _, err := fmt.Fprintf(c.w, "event: %s\n", event); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(c.w, "data: %s\n\n", data); err != nil {
+		return err
+	}
+	c.flusher.Flush()
+	return nil
+}
+
+// writeComment writes an SSE comment line, ignored by EventSource
+func (c *sseConn) writeComment(comment string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return errSSEClosed
+	}
+
+	if _, err := fmt.Fprintf(c.w, ": %s\n\n", comment); err != nil {
+		return err
+	}
+	c.flusher.Flush()
+	return nil
+}
+
+// ReadMessage blocks until the stream is closed
+func (c *sseConn) ReadMessage() (int, []byte, error) {
+	<-c.done
+	return 0, nil, errSSEClosed
+}
+
+// WriteMessage sends a message as an unnamed SSE event
+// Messages are single-line JSON, so they fit in one data field.
+func (c *sseConn) WriteMessage(messageType int, data []byte) error {
+	return c.writeEvent("", data)
+}
+
+// WriteControl translates WebSocket control frames for the stream
+// Close frames become a "close" event; pings become comments.
+func (c *sseConn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	switch messageType {
+	case websocket.CloseMessage:
+		return c.writeEvent("close", []byte(`{}`))
+	case websocket.PingMessage:
+		return c.writeComment("ping")
+	}
+	return nil
+}
+
+// SetReadDeadline is a no-op; streams have no reads
+func (c *sseConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+// SetWriteDeadline is a no-op; writes are bounded by the HTTP server
+func (c *sseConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// Close ends the stream
+// It waits for any in-flight write so the handler can return safely.
+func (c *sseConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.mutex.Lock()
+		c.closed = true
+		c.mutex.Unlock()
+		close(c.done)
+	})
+	return nil
+}
+
+// HandleSSE streams messages to a client over Server-Sent Events
+// It is a fallback for clients that can't open a WebSocket. The first
+// event ("connected") carries the client ID and the token used to post
+// messages back.
+func (m *Manager) HandleSSE(w http.ResponseWriter, r *http.Request) {
+	// Run admission hooks before streaming
+	if !m.admitConnection(w, r) {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	conn, err := newSSEConn(w, flusher)
+	if err != nil {
+		m.Logger().Errorf("Error opening SSE stream: %v", err)
+		http.Error(w, "Failed to open stream", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	client := m.accept(conn, r)
+
+	// Tell the client its ID and token so it can post messages
+	data, err := json.Marshal(map[string]string{"client_id": client.ID, "token": conn.token})
+	if err != nil {
+		m.Logger().Errorf("Error marshaling SSE client ID: %v", err)
+		conn.Close()
+		return
+	}
+	if err := conn.writeEvent("connected", data); err != nil {
+		conn.Close()
+		return
+	}
+
+	ticker := time.NewTicker(sseHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			conn.Close()
+			return
+		case <-conn.done:
+			return
+		case <-ticker.C:
+			if err := conn.writeComment("heartbeat"); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// HandleSSEMessage accepts a message posted by an SSE client
+// The client is identified by the "client" query parameter and must send
+// its stream's token in the SSETokenHeader header; WebSocket clients can't
+// be posted for. The message is dispatched to the registered handlers as
+// if it arrived over WebSocket.
+func (m *Manager) HandleSSEMessage(w http.ResponseWriter, r *http.Request) {
+	if !m.admitConnection(w, r) {
+		return
+	}
+
+	m.clientsMux.RLock()
+	client, exists := m.clients[r.URL.Query().Get("client")]
+	m.clientsMux.RUnlock()
+
+	// Unknown clients and bad tokens get the same answer
+	if exists {
+		sse, ok := sseConnOf(client)
+		exists = ok && subtle.ConstantTimeCompare([]byte(r.Header.Get(SSETokenHeader)), []byte(sse.token)) == 1
+	}
+	if !exists {
+		http.Error(w, "Unknown client", http.StatusNotFound)
+		return
+	}
+
+	var message Message
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&message); err != nil {
+		if m.recordMalformed(client) {
+			m.closeProtocolError(client, "too many malformed messages")
+		}
+		http.Error(w, "Invalid message", http.StatusBadRequest)
+		return
+	}
+
+	m.dispatch(client, message)
+	w.WriteHeader(http.StatusNoContent)
+}
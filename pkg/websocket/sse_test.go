@@ -0,0 +1,195 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sseEvent is one event read from a text/event-stream
+type sseEvent struct {
+	name string
+	data string
+}
+
+// openSSE starts a stream from the manager and returns its events
+func openSSE(t *testing.T, m *Manager) (*http.Response, <-chan sseEvent) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(m.HandleSSE))
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	events := make(chan sseEvent, 16)
+	go func() {
+		defer close(events)
+		var event sseEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event.name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				event.data = strings.TrimPrefix(line, "data: ")
+			case line == "" && event.data != "":
+				events <- event
+				event = sseEvent{}
+			}
+		}
+	}()
+	return resp, events
+}
+
+// nextEvent returns the next event from the stream or fails the test
+func nextEvent(t *testing.T, events <-chan sseEvent) sseEvent {
+	t.Helper()
+
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("stream ended")
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for an SSE event")
+	}
+	return sseEvent{}
+}
+
+func TestSSEClientReceivesStateUpdates(t *testing.T) {
+	m := newTestManager(t)
+	resp, events := openSSE(t, m)
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	connected := nextEvent(t, events)
+	var hello struct {
+		ClientID string `json:"client_id"`
+	}
+	if connected.name != "connected" || json.Unmarshal([]byte(connected.data), &hello) != nil || hello.ClientID == "" {
+		t.Fatalf("first event = %+v, want a connected event with the client ID", connected)
+	}
+
+	err := m.BroadcastStateUpdate(StateUpdate{ComponentID: "counter-1", Key: "count", Value: 4, Type: "update"})
+	if err != nil {
+		t.Fatalf("BroadcastStateUpdate: %v", err)
+	}
+
+	event := nextEvent(t, events)
+	if event.name != "" {
+		t.Errorf("state update sent as %q event, want an unnamed one", event.name)
+	}
+	var message struct {
+		Type    MessageType `json:"type"`
+		Payload StateUpdate `json:"payload"`
+	}
+	if err := json.Unmarshal([]byte(event.data), &message); err != nil {
+		t.Fatalf("event data is not a message: %v\n%s", err, event.data)
+	}
+	if message.Type != MessageTypeStateUpdate || message.Payload.ComponentID != "counter-1" ||
+		message.Payload.Key != "count" || message.Payload.Value != float64(4) {
+		t.Errorf("got %+v, want the counter-1 count update", message)
+	}
+}
+
+// sseHello is the connected event that opens a stream
+type sseHello struct {
+	ClientID string `json:"client_id"`
+	Token    string `json:"token"`
+}
+
+// openSSEClient opens a stream and returns its connected event
+func openSSEClient(t *testing.T, m *Manager) sseHello {
+	t.Helper()
+
+	_, events := openSSE(t, m)
+	var hello sseHello
+	if err := json.Unmarshal([]byte(nextEvent(t, events).data), &hello); err != nil {
+		t.Fatalf("invalid connected event: %v", err)
+	}
+	if hello.ClientID == "" || hello.Token == "" {
+		t.Fatalf("connected event %+v lacks the client ID or token", hello)
+	}
+	return hello
+}
+
+// postSSE posts an event message for a client with a token
+func postSSE(m *Manager, clientID, token string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/sse?client="+clientID,
+		strings.NewReader(`{"type":"event","payload":{"name":"click"}}`))
+	if token != "" {
+		req.Header.Set(SSETokenHeader, token)
+	}
+	m.HandleSSEMessage(rec, req)
+	return rec
+}
+
+func TestSSEClientPostsMessages(t *testing.T) {
+	m := newTestManager(t)
+	hello := openSSEClient(t, m)
+
+	received := make(chan string, 1)
+	m.RegisterHandler(MessageTypeEvent, func(conn Conn, payload []byte) {
+		received <- string(payload)
+	})
+
+	rec := postSSE(m, hello.ClientID, hello.Token)
+
+	select {
+	case payload := <-received:
+		if payload != `{"name":"click"}` {
+			t.Errorf("handler got payload %s", payload)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("handler not called; response %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestSSEMessagesRequireTheStreamToken(t *testing.T) {
+	m := newTestManager(t)
+	hello := openSSEClient(t, m)
+	other := openSSEClient(t, m)
+	_, wsClient := connect(t, m)
+
+	handled := make(chan struct{}, 8)
+	m.RegisterHandler(MessageTypeEvent, func(conn Conn, payload []byte) {
+		handled <- struct{}{}
+	})
+
+	tests := []struct {
+		name     string
+		clientID string
+		token    string
+	}{
+		{"no token", hello.ClientID, ""},
+		{"wrong token", hello.ClientID, "not-the-token"},
+		{"another stream's token", hello.ClientID, other.Token},
+		{"websocket client", wsClient.ID, hello.Token},
+		{"unknown client", "client-1", hello.Token},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := postSSE(m, tt.clientID, tt.token); rec.Code != http.StatusNotFound {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+			}
+		})
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if len(handled) != 0 {
+		t.Errorf("%d rejected messages reached the handlers", len(handled))
+	}
+}
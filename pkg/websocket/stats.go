@@ -0,0 +1,115 @@
+package websocket
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/magooney-loon/webrender/internal/durations"
+)
+
+// DefaultMaxMalformedMessages is how many unparseable messages a client may
+// send before it is disconnected
+const DefaultMaxMalformedMessages = 10
+
+// maxLatencySamples bounds the number of latencies kept for percentiles
+const maxLatencySamples = 1024
+
+// ManagerStats summarizes connection health
+type ManagerStats struct {
+	Clients           int   `json:"clients"`
+	MalformedMessages int64 `json:"malformed_messages"`
+	MalformedKicks    int64 `json:"malformed_disconnects"`
+	DroppedBroadcasts int64 `json:"dropped_broadcasts"`
+	DroppedEvents     int64 `json:"dropped_events"`
+
+	// Time from queueing a broadcast to finishing the write to a client
+	BroadcastLatency LatencyStats `json:"broadcast_latency"`
+}
+
+// LatencyStats describes a latency distribution
+// Percentiles are computed over the most recent samples.
+type LatencyStats struct {
+	Count int64         `json:"count"`
+	Max   time.Duration `json:"max"`
+	P50   time.Duration `json:"p50"`
+	P95   time.Duration `json:"p95"`
+	P99   time.Duration `json:"p99"`
+}
+
+// latencyRecorder keeps recent latency samples, safe for concurrent use
+type latencyRecorder struct {
+	recorder durations.Recorder
+	mutex    sync.Mutex
+}
+
+// record adds a latency sample
+func (l *latencyRecorder) record(v time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.recorder.Record(v)
+}
+
+// stats returns the count, maximum, and percentiles of the samples
+func (l *latencyRecorder) stats() LatencyStats {
+	l.mutex.Lock()
+	summary := l.recorder.Summary()
+	l.mutex.Unlock()
+
+	return LatencyStats{
+		Count: summary.Count,
+		Max:   summary.Max,
+		P50:   summary.P50,
+		P95:   summary.P95,
+		P99:   summary.P99,
+	}
+}
+
+// Stats returns the number of connected clients, malformed message counts,
+// broadcasts dropped by the overflow policy, connection events dropped for
+// a slow Events reader, and broadcast delivery latency
+func (m *Manager) Stats() ManagerStats {
+	m.clientsMux.RLock()
+	clients := len(m.clients)
+	m.clientsMux.RUnlock()
+
+	return ManagerStats{
+		Clients:           clients,
+		MalformedMessages: atomic.LoadInt64(&m.malformedMessages),
+		MalformedKicks:    atomic.LoadInt64(&m.malformedKicks),
+		DroppedBroadcasts: atomic.LoadInt64(&m.droppedBroadcasts),
+		DroppedEvents:     atomic.LoadInt64(&m.droppedEvents),
+		BroadcastLatency:  m.broadcastLatency.stats(),
+	}
+}
+
+// MalformedMessages returns how many unparseable messages the client has sent
+func (c *Client) MalformedMessages() int64 {
+	return atomic.LoadInt64(&c.malformed)
+}
+
+// recordMalformed counts an unparseable message from a client and reports
+// whether the client has exceeded MaxMalformedMessages and should be dropped
+func (m *Manager) recordMalformed(client *Client) bool {
+	atomic.AddInt64(&m.malformedMessages, 1)
+	count := atomic.AddInt64(&client.malformed, 1)
+
+	if m.MaxMalformedMessages <= 0 || count < int64(m.MaxMalformedMessages) {
+		return false
+	}
+
+	atomic.AddInt64(&m.malformedKicks, 1)
+	m.Logger().Warnf("Disconnecting client %s after %d malformed messages", client.ID, count)
+	return true
+}
+
+// closeProtocolError closes a client connection with a protocol error code
+func (m *Manager) closeProtocolError(client *Client, reason string) {
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseProtocolError, reason)
+	if err := client.Conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(closeWriteWait)); err != nil {
+		m.Logger().Warnf("Error sending close frame to client %s: %v", client.ID, err)
+	}
+	client.Conn.Close()
+}
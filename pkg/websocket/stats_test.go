@@ -0,0 +1,115 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestMalformedMessagesDisconnectAtThreshold(t *testing.T) {
+	m := newTestManager(t)
+	m.MaxMalformedMessages = 3
+	conn, client := connect(t, m)
+
+	for i := 0; i < 2; i++ {
+		conn.incoming <- []byte(`{not json`)
+	}
+	waitFor(t, "the malformed messages to be counted", func() bool {
+		return client.MalformedMessages() == 2
+	})
+	if m.Stats().Clients != 1 {
+		t.Fatal("client disconnected below the threshold")
+	}
+
+	conn.incoming <- []byte(`{not json`)
+	waitFor(t, "the client to be disconnected", func() bool {
+		return m.Stats().Clients == 0
+	})
+
+	frames := conn.closeFrames(t)
+	if len(frames) != 1 || frames[0].Code != websocket.CloseProtocolError {
+		t.Errorf("close frames = %v, want one protocol error", frames)
+	}
+
+	stats := m.Stats()
+	if stats.MalformedMessages != 3 || stats.MalformedKicks != 1 {
+		t.Errorf("stats: %d malformed messages, %d disconnects; want 3 and 1", stats.MalformedMessages, stats.MalformedKicks)
+	}
+}
+
+func TestMalformedMessagesUnlimitedWhenThresholdDisabled(t *testing.T) {
+	m := newTestManager(t)
+	m.MaxMalformedMessages = 0
+	conn, client := connect(t, m)
+
+	for i := 0; i < DefaultMaxMalformedMessages+5; i++ {
+		conn.incoming <- []byte(`{not json`)
+	}
+	waitFor(t, "the malformed messages to be counted", func() bool {
+		return client.MalformedMessages() == DefaultMaxMalformedMessages+5
+	})
+	if m.Stats().Clients != 1 {
+		t.Error("client disconnected with the threshold disabled")
+	}
+}
+
+// slowConn is a fakeConn whose writes take a fixed time
+type slowConn struct {
+	*fakeConn
+	delay time.Duration
+}
+
+func (c *slowConn) WriteMessage(messageType int, data []byte) error {
+	time.Sleep(c.delay)
+	return c.fakeConn.WriteMessage(messageType, data)
+}
+
+func TestBroadcastLatencyRecordsWrites(t *testing.T) {
+	m := newTestManager(t)
+
+	const delay = 5 * time.Millisecond
+	conn := &slowConn{fakeConn: newFakeConn(), delay: delay}
+	m.Accept(conn)
+	waitFor(t, "client registration", func() bool {
+		return m.Stats().Clients == 1
+	})
+
+	for i := 0; i < 3; i++ {
+		if err := m.BroadcastCustomMessage(MessageTypeEvent, map[string]int{"n": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, "the broadcasts to be written", func() bool {
+		return m.Stats().BroadcastLatency.Count == 3
+	})
+
+	latency := m.Stats().BroadcastLatency
+	if latency.P50 < delay || latency.Max < delay {
+		t.Errorf("latency p50=%v max=%v, want at least the %v write time", latency.P50, latency.Max, delay)
+	}
+	if latency.P50 > latency.P95 || latency.P95 > latency.P99 || latency.P99 > latency.Max {
+		t.Errorf("percentiles out of order: %+v", latency)
+	}
+}
+
+func TestLatencyPercentiles(t *testing.T) {
+	var l latencyRecorder
+	if stats := l.stats(); stats != (LatencyStats{}) {
+		t.Errorf("empty recorder stats = %+v, want zero", stats)
+	}
+
+	for i := 100; i >= 1; i-- {
+		l.record(time.Duration(i) * time.Millisecond)
+	}
+	want := LatencyStats{
+		Count: 100,
+		Max:   100 * time.Millisecond,
+		P50:   50 * time.Millisecond,
+		P95:   95 * time.Millisecond,
+		P99:   99 * time.Millisecond,
+	}
+	if got := l.stats(); got != want {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+}
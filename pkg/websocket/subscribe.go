@@ -0,0 +1,105 @@
+package websocket
+
+import (
+	"encoding/json"
+)
+
+// CapabilityStateSnapshot marks clients that accept state_snapshot
+// messages in place of one state_update per key
+const CapabilityStateSnapshot = "state_snapshot"
+
+// Subscription lists the components a client wants state updates for
+// An empty list subscribes to every component.
+type Subscription struct {
+	ComponentIDs []string `json:"component_ids"`
+
+	// Optional protocol features the client supports, e.g.
+	// CapabilityStateSnapshot; older clients send none
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// SetSubscriptions replaces the set of components the client receives
+// updates for; no IDs means all components
+func (c *Client) SetSubscriptions(componentIDs []string) {
+	subscriptions := make(map[string]bool, len(componentIDs))
+	for _, id := range componentIDs {
+		subscriptions[id] = true
+	}
+
+	c.subscriptionsMux.Lock()
+	c.subscriptions = subscriptions
+	c.subscriptionsMux.Unlock()
+}
+
+// IsSubscribed reports whether the client receives updates for a component
+func (c *Client) IsSubscribed(componentID string) bool {
+	c.subscriptionsMux.RLock()
+	defer c.subscriptionsMux.RUnlock()
+
+	return len(c.subscriptions) == 0 || c.subscriptions[componentID]
+}
+
+// handleSubscribe updates a client's subscriptions from a subscribe message
+func (m *Manager) handleSubscribe(client *Client, payload []byte) {
+	var sub Subscription
+	if err := json.Unmarshal(payload, &sub); err != nil {
+		m.Logger().Warnf("Error unmarshaling subscription from client %s: %v", client.ID, err)
+		return
+	}
+
+	client.SetSubscriptions(m.authorizedSubscriptions(client, sub.ComponentIDs))
+	client.setCapabilities(sub.Capabilities)
+}
+
+// setCapabilities replaces the protocol features the client supports
+func (c *Client) setCapabilities(capabilities []string) {
+	set := make(map[string]bool, len(capabilities))
+	for _, capability := range capabilities {
+		set[capability] = true
+	}
+
+	c.subscriptionsMux.Lock()
+	c.capabilities = set
+	c.subscriptionsMux.Unlock()
+}
+
+// HasCapability reports whether the client announced a protocol feature
+// in its subscribe message
+func (c *Client) HasCapability(capability string) bool {
+	c.subscriptionsMux.RLock()
+	defer c.subscriptionsMux.RUnlock()
+
+	return c.capabilities[capability]
+}
+
+// ClientForConn returns the connected client using a connection, e.g. to
+// check its capabilities from a message handler
+func (m *Manager) ClientForConn(conn Conn) (*Client, bool) {
+	m.clientsMux.RLock()
+	defer m.clientsMux.RUnlock()
+
+	for _, client := range m.clients {
+		if client.Conn == conn {
+			return client, true
+		}
+	}
+	return nil, false
+}
+
+// componentScope returns the component a broadcast is about, or "" for
+// messages that go to every client
+func componentScope(message Message) string {
+	switch message.Type {
+	case MessageTypeStateUpdate, MessageTypeStateBatch, MessageTypeRender:
+	default:
+		return ""
+	}
+
+	var scoped struct {
+		ComponentID string `json:"component_id"`
+	}
+	if err := json.Unmarshal(message.Payload, &scoped); err != nil {
+		return ""
+	}
+	return scoped.ComponentID
+}
@@ -0,0 +1,197 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+)
+
+// broadcastThrottle coalesces state updates to the same component key
+// Throttled updates, their flushes, and batches take a turn for each key
+// they carry under mutex, then queue outside it in turn order, so they
+// reach clients in the order they were decided without a full queue
+// holding up the throttle.
+type broadcastThrottle struct {
+	window time.Duration
+	keys   map[string]*throttledKey
+	mutex  sync.Mutex
+
+	// Turns taken and finished per key, and the signal that one finished
+	sequences map[string]*keySequence
+	finished  *sync.Cond
+}
+
+// keySequence orders the messages queued for one component key
+type keySequence struct {
+	issued uint64
+	queued uint64
+}
+
+// turn is a message's place in the sequence of one key
+type turn struct {
+	key string
+	n   uint64
+}
+
+// throttledKey tracks the open window of one component key
+type throttledKey struct {
+	// Latest update held back during the window, if any
+	pending *Message
+}
+
+// throttleKey identifies a component's state key in the throttle
+func throttleKey(componentID, key string) string {
+	return componentID + "\x00" + key
+}
+
+// SetBroadcastThrottle coalesces state updates to the same component key
+// sent within window: the first goes out at once, and at the end of the
+// window only the latest of the rest is sent, so the final value always
+// arrives. Batches aren't delayed; they replace held updates to the keys
+// they carry. A window of 0 disables throttling.
+func (m *Manager) SetBroadcastThrottle(window time.Duration) {
+	m.throttle.mutex.Lock()
+	defer m.throttle.mutex.Unlock()
+
+	m.throttle.window = window
+}
+
+// enqueueStateUpdate queues a state update, or holds it back for the end
+// of its key's window when throttling
+func (m *Manager) enqueueStateUpdate(key string, message Message) error {
+	t := &m.throttle
+	t.mutex.Lock()
+
+	if entry, open := t.keys[key]; open {
+		entry.pending = &message
+		t.mutex.Unlock()
+		return nil
+	}
+
+	if t.window > 0 {
+		if t.keys == nil {
+			t.keys = make(map[string]*throttledKey)
+		}
+		t.keys[key] = &throttledKey{}
+		time.AfterFunc(t.window, func() { m.flushThrottled(key) })
+	}
+	turns := t.take(key)
+	t.mutex.Unlock()
+
+	return m.enqueueInTurn(turns, message)
+}
+
+// enqueueStateBatch queues a batch, dropping updates held back for the
+// keys it carries so a later flush can't overwrite its newer values
+func (m *Manager) enqueueStateBatch(batch StateBatch, message Message) error {
+	keys := make([]string, 0, len(batch.Changes)+len(batch.Deleted))
+	for key := range batch.Changes {
+		keys = append(keys, throttleKey(batch.ComponentID, key))
+	}
+	for _, key := range batch.Deleted {
+		// A key both changed and deleted takes a single turn
+		if _, changed := batch.Changes[key]; !changed {
+			keys = append(keys, throttleKey(batch.ComponentID, key))
+		}
+	}
+
+	t := &m.throttle
+	t.mutex.Lock()
+	for _, key := range keys {
+		if entry := t.keys[key]; entry != nil {
+			entry.pending = nil
+		}
+	}
+	turns := t.take(keys...)
+	t.mutex.Unlock()
+
+	return m.enqueueInTurn(turns, message)
+}
+
+// flushThrottled sends the update held back for a key, keeping the window
+// open for another period, or closes the window if nothing was held
+func (m *Manager) flushThrottled(key string) {
+	t := &m.throttle
+	t.mutex.Lock()
+
+	entry := t.keys[key]
+	if entry == nil || entry.pending == nil {
+		delete(t.keys, key)
+		t.mutex.Unlock()
+		return
+	}
+
+	message := *entry.pending
+	entry.pending = nil
+	if t.window <= 0 {
+		// Throttling was turned off; the next update goes out directly
+		delete(t.keys, key)
+	} else {
+		time.AfterFunc(t.window, func() { m.flushThrottled(key) })
+	}
+	turns := t.take(key)
+	t.mutex.Unlock()
+
+	if err := m.enqueueInTurn(turns, message); err != nil {
+		m.Logger().Warnf("Error sending throttled state update: %v", err)
+	}
+}
+
+// take reserves the next turn on each key; the caller holds mutex
+func (t *broadcastThrottle) take(keys ...string) []turn {
+	if t.sequences == nil {
+		t.sequences = make(map[string]*keySequence)
+		t.finished = sync.NewCond(&t.mutex)
+	}
+
+	turns := make([]turn, len(keys))
+	for i, key := range keys {
+		seq := t.sequences[key]
+		if seq == nil {
+			seq = &keySequence{}
+			t.sequences[key] = seq
+		}
+		turns[i] = turn{key: key, n: seq.issued}
+		seq.issued++
+	}
+	return turns
+}
+
+// due reports whether every earlier turn on the keys has been queued; the
+// caller holds mutex
+func (t *broadcastThrottle) due(turns []turn) bool {
+	for _, tn := range turns {
+		if t.sequences[tn.key].queued != tn.n {
+			return false
+		}
+	}
+	return true
+}
+
+// enqueueInTurn waits for the message's turns, queues it without holding
+// the throttle's mutex, and passes the turns on. Turns are taken in one
+// global order, so the earliest outstanding message is always due.
+func (m *Manager) enqueueInTurn(turns []turn, message Message) error {
+	t := &m.throttle
+	if len(turns) > 0 {
+		t.mutex.Lock()
+		for !t.due(turns) {
+			t.finished.Wait()
+		}
+		t.mutex.Unlock()
+	}
+
+	err := m.enqueue(message)
+
+	if len(turns) > 0 {
+		t.mutex.Lock()
+		for _, tn := range turns {
+			seq := t.sequences[tn.key]
+			if seq.queued++; seq.queued == seq.issued {
+				delete(t.sequences, tn.key)
+			}
+		}
+		t.mutex.Unlock()
+		t.finished.Broadcast()
+	}
+	return err
+}
@@ -0,0 +1,134 @@
+package websocket
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// keyValues returns the values a client received for one state key, in
+// order, from both single updates and batches
+func keyValues(t *testing.T, conn *fakeConn, key string) []interface{} {
+	t.Helper()
+
+	conn.mutex.Lock()
+	written := append([]Message(nil), conn.written...)
+	conn.mutex.Unlock()
+
+	var values []interface{}
+	for _, message := range written {
+		switch message.Type {
+		case MessageTypeStateUpdate:
+			var update StateUpdate
+			if err := json.Unmarshal(message.Payload, &update); err != nil {
+				t.Fatal(err)
+			}
+			if update.Key == key {
+				values = append(values, update.Value)
+			}
+		case MessageTypeStateBatch:
+			var batch StateBatch
+			if err := json.Unmarshal(message.Payload, &batch); err != nil {
+				t.Fatal(err)
+			}
+			if value, ok := batch.Changes[key]; ok {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+func TestBroadcastThrottleCoalescesUpdates(t *testing.T) {
+	m := newTestManager(t)
+	conn, _ := connect(t, m)
+	m.SetBroadcastThrottle(50 * time.Millisecond)
+
+	for i := 0; i < 10; i++ {
+		m.BroadcastStateUpdate(StateUpdate{ComponentID: "c", Key: "k", Value: i, Type: "update"})
+	}
+	waitFor(t, "final value", func() bool { return len(keyValues(t, conn, "k")) >= 2 })
+	time.Sleep(120 * time.Millisecond)
+
+	got := keyValues(t, conn, "k")
+	if len(got) != 2 || got[0] != float64(0) || got[1] != float64(9) {
+		t.Errorf("received %v, want the first and last values [0 9]", got)
+	}
+}
+
+func TestBroadcastThrottleBatchReplacesPendingUpdate(t *testing.T) {
+	m := newTestManager(t)
+	conn, _ := connect(t, m)
+	m.SetBroadcastThrottle(50 * time.Millisecond)
+
+	m.BroadcastStateUpdate(StateUpdate{ComponentID: "c", Key: "x", Value: 1, Type: "update"})
+	m.BroadcastStateUpdate(StateUpdate{ComponentID: "c", Key: "x", Value: 2, Type: "update"})
+	m.BroadcastStateBatch(StateBatch{ComponentID: "c", Changes: map[string]interface{}{"x": 3}})
+	time.Sleep(150 * time.Millisecond)
+
+	got := keyValues(t, conn, "x")
+	if len(got) != 2 || got[0] != float64(1) || got[1] != float64(3) {
+		t.Errorf("received %v, want [1 3] with the held value dropped", got)
+	}
+}
+
+func TestBroadcastThrottleDisabledSendsEveryUpdate(t *testing.T) {
+	m := newTestManager(t)
+	conn, _ := connect(t, m)
+
+	for i := 0; i < 5; i++ {
+		m.BroadcastStateUpdate(StateUpdate{ComponentID: "c", Key: "k", Value: i, Type: "update"})
+	}
+	waitFor(t, "all updates", func() bool { return len(keyValues(t, conn, "k")) == 5 })
+}
+
+func TestBroadcastThrottleDoesNotWaitOnAFullQueue(t *testing.T) {
+	m, conn := saturate(t, OverflowBlock)
+	m.SetBroadcastThrottle(100 * time.Millisecond)
+
+	// The first update opens the window and blocks on the full queue
+	first, prompt := returnsPromptly(func() {
+		m.BroadcastStateUpdate(StateUpdate{ComponentID: "c", Key: "k", Value: 1, Type: "update"})
+	})
+	if prompt {
+		t.Fatal("update into a full blocking queue returned promptly")
+	}
+
+	// Holding back the next one and changing the window don't need the queue
+	if _, prompt := returnsPromptly(func() {
+		m.BroadcastStateUpdate(StateUpdate{ComponentID: "c", Key: "k", Value: 2, Type: "update"})
+	}); !prompt {
+		t.Error("throttled update waited for the full queue")
+	}
+	if _, prompt := returnsPromptly(func() { m.SetBroadcastThrottle(0) }); !prompt {
+		t.Error("SetBroadcastThrottle waited for the full queue")
+	}
+
+	conn.open()
+	<-first
+	waitFor(t, "the held update", func() bool { return len(keyValues(t, conn.fakeConn, "k")) == 2 })
+	if got := keyValues(t, conn.fakeConn, "k"); got[0] != float64(1) || got[1] != float64(2) {
+		t.Errorf("received %v, want [1 2] in order", got)
+	}
+}
+
+func TestBroadcastThrottleOrdersUpdatesAndBatches(t *testing.T) {
+	m := newTestManager(t)
+	conn, _ := connect(t, m)
+
+	// Batches that change and delete the same key take one turn on it
+	const n = 50
+	for i := 0; i < n; i++ {
+		if i%2 == 0 {
+			m.BroadcastStateUpdate(StateUpdate{ComponentID: "c", Key: "k", Value: i, Type: "update"})
+		} else {
+			m.BroadcastStateBatch(StateBatch{ComponentID: "c", Changes: map[string]interface{}{"k": i}, Deleted: []string{"k"}})
+		}
+	}
+	waitFor(t, "all updates", func() bool { return len(keyValues(t, conn, "k")) == n })
+	for i, value := range keyValues(t, conn, "k") {
+		if value != float64(i) {
+			t.Fatalf("value %d = %v, want updates in send order", i, value)
+		}
+	}
+}
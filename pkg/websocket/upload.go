@@ -0,0 +1,84 @@
+package websocket
+
+import (
+	"bytes"
+	"sync"
+)
+
+const (
+	// DefaultMaxUploadSize is the default limit for a single binary upload
+	DefaultMaxUploadSize = 10 << 20 // 10 MiB
+
+	// maxUploadIDLength bounds the upload ID header of a binary frame
+	maxUploadIDLength = 64
+
+	// maxPendingUploads bounds uploads a client may send ahead of their actions
+	maxPendingUploads = 8
+)
+
+// uploadStore holds binary uploads until the action referencing them arrives
+type uploadStore struct {
+	uploads map[Conn]map[string][]byte
+	mutex   sync.Mutex
+}
+
+// newUploadStore creates an empty upload store
+func newUploadStore() *uploadStore {
+	return &uploadStore{
+		uploads: make(map[Conn]map[string][]byte),
+	}
+}
+
+// handleBinary stores a binary frame of the form "<upload id>\n<data>"
+// Uploads precede the action that uses them on the same connection.
+func (m *Manager) handleBinary(client *Client, frame []byte) {
+	sep := bytes.IndexByte(frame, '\n')
+	if sep <= 0 || sep > maxUploadIDLength {
+		m.Logger().Warnf("Ignoring binary frame without upload ID from client %s", client.ID)
+		return
+	}
+
+	uploadID := string(frame[:sep])
+	data := frame[sep+1:]
+
+	if int64(len(data)) > m.MaxUploadSize {
+		m.Logger().Warnf("Ignoring upload %s from client %s: %d bytes exceeds limit of %d", uploadID, client.ID, len(data), m.MaxUploadSize)
+		return
+	}
+
+	m.uploads.mutex.Lock()
+	defer m.uploads.mutex.Unlock()
+
+	pending := m.uploads.uploads[client.Conn]
+	if pending == nil {
+		pending = make(map[string][]byte)
+		m.uploads.uploads[client.Conn] = pending
+	}
+	if len(pending) >= maxPendingUploads {
+		m.Logger().Warnf("Ignoring upload %s from client %s: too many pending uploads", uploadID, client.ID)
+		return
+	}
+
+	pending[uploadID] = data
+}
+
+// TakeUpload returns and forgets the upload sent on conn with the given ID
+func (m *Manager) TakeUpload(conn Conn, uploadID string) ([]byte, bool) {
+	m.uploads.mutex.Lock()
+	defer m.uploads.mutex.Unlock()
+
+	pending := m.uploads.uploads[conn]
+	data, ok := pending[uploadID]
+	if ok {
+		delete(pending, uploadID)
+	}
+	return data, ok
+}
+
+// dropUploads discards pending uploads for a closed connection
+func (m *Manager) dropUploads(conn Conn) {
+	m.uploads.mutex.Lock()
+	defer m.uploads.mutex.Unlock()
+
+	delete(m.uploads.uploads, conn)
+}